@@ -207,23 +207,9 @@ func (api *API) makeRequestWithAuthTypeAndHeaders(ctx context.Context, method, u
 			reqBody = bytes.NewReader(jsonBody)
 		}
 		if i > 0 {
-			// expect the backoff introduced here on errored requests to dominate the effect of rate limiting
-			// don't need a random component here as the rate limiter should do something similar
-			// nb time duration could truncate an arbitrary float. Since our inputs are all ints, we should be ok
-			sleepDuration := time.Duration(math.Pow(2, float64(i-1)) * float64(api.retryPolicy.MinRetryDelay))
-
-			if sleepDuration > api.retryPolicy.MaxRetryDelay {
-				sleepDuration = api.retryPolicy.MaxRetryDelay
-			}
-			// useful to do some simple logging here, maybe introduce levels later
-			api.logger.Printf("Sleeping %s before retry attempt number %d for request %s %s", sleepDuration.String(), i, method, uri)
-
-			select {
-			case <-time.After(sleepDuration):
-			case <-ctx.Done():
-				return nil, errors.Wrap(ctx.Err(), "operation aborted during backoff")
+			if err := api.retryBackoff(ctx, i, method, uri); err != nil {
+				return nil, err
 			}
-
 		}
 		err = api.rateLimiter.Wait(context.Background())
 		if err != nil {
@@ -285,6 +271,91 @@ func (api *API) makeRequestWithAuthTypeAndHeaders(ctx context.Context, method, u
 	return respBody, nil
 }
 
+// retryBackoff waits before retry attempt i of a request, doubling the delay
+// with every attempt up to the retry policy's maximum.
+func (api *API) retryBackoff(ctx context.Context, i int, method, uri string) error {
+	// expect the backoff introduced here on errored requests to dominate the effect of rate limiting
+	// don't need a random component here as the rate limiter should do something similar
+	// nb time duration could truncate an arbitrary float. Since our inputs are all ints, we should be ok
+	sleepDuration := time.Duration(math.Pow(2, float64(i-1)) * float64(api.retryPolicy.MinRetryDelay))
+
+	if sleepDuration > api.retryPolicy.MaxRetryDelay {
+		sleepDuration = api.retryPolicy.MaxRetryDelay
+	}
+	// useful to do some simple logging here, maybe introduce levels later
+	api.logger.Printf("Sleeping %s before retry attempt number %d for request %s %s", sleepDuration.String(), i, method, uri)
+
+	select {
+	case <-time.After(sleepDuration):
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "operation aborted during backoff")
+	}
+}
+
+// makeStreamingRequestContext makes a HTTP request whose body is read from
+// reqBody and returns the response body unread. Like other requests it is
+// retried on 429 and 5xx responses, but only when reqBody is nil: a
+// streamed request body can't be replayed. The caller must close the
+// returned body.
+func (api *API) makeStreamingRequestContext(ctx context.Context, method, uri string, reqBody io.Reader, headers http.Header) (io.ReadCloser, error) {
+	maxRetries := api.retryPolicy.MaxRetries
+	if reqBody != nil {
+		maxRetries = 0
+	}
+
+	var resp *http.Response
+	var respErr error
+	var respBody []byte
+	for i := 0; i <= maxRetries; i++ {
+		if i > 0 {
+			if err := api.retryBackoff(ctx, i, method, uri); err != nil {
+				return nil, err
+			}
+		}
+		if err := api.rateLimiter.Wait(ctx); err != nil {
+			return nil, errors.Wrap(err, "Error caused by request rate limiting")
+		}
+		resp, respErr = api.request(ctx, method, uri, reqBody, api.authType, headers)
+		if respErr != nil {
+			api.logger.Printf("Error performing request: %s %s : %s \n", method, uri, respErr.Error())
+			continue
+		}
+		if resp.StatusCode < http.StatusBadRequest {
+			return resp.Body, nil
+		}
+
+		var err error
+		respBody, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			respErr = errors.Wrap(err, "could not read response body")
+			continue
+		}
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < http.StatusInternalServerError {
+			break
+		}
+		api.logger.Printf("Request: %s %s got an error response %d: %s\n", method, uri, resp.StatusCode,
+			strings.Replace(strings.Replace(string(respBody), "\n", "", -1), "\t", "", -1))
+	}
+	if respErr != nil {
+		return nil, respErr
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, errors.Errorf("HTTP status %d: service failure", resp.StatusCode)
+	}
+
+	errBody := &Response{}
+	if err := json.Unmarshal(respBody, &errBody); err != nil {
+		return nil, errors.Wrap(err, errUnmarshalErrorBody)
+	}
+	return nil, &APIRequestError{
+		StatusCode: resp.StatusCode,
+		Errors:     errBody.Errors,
+	}
+}
+
 // request makes a HTTP request to the given API endpoint, returning the raw
 // *http.Response, or an error if one occurred. The caller is responsible for
 // closing the response body.
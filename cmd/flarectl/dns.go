@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/cloudflare/cloudflare-go"
+	"github.com/cloudflare/cloudflare-go/ddns"
 	"github.com/urfave/cli/v2"
 )
 
@@ -196,3 +201,53 @@ func dnsDelete(c *cli.Context) error {
 
 	return nil
 }
+
+func dnsDDNS(c *cli.Context) error {
+	if err := checkFlags(c, "zone", "name"); err != nil {
+		return err
+	}
+	zone := c.String("zone")
+	name := c.String("name")
+	if name != zone && !strings.HasSuffix(name, "."+zone) {
+		name = name + "." + zone
+	}
+
+	zoneID, err := api.ZoneIDByName(zone)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	u := ddns.NewUpdater(api, zoneID, name)
+	u.TTL = c.Int("ttl")
+	u.Proxied = c.Bool("proxy")
+	u.Logger = log.New(os.Stderr, "", log.LstdFlags)
+	if !c.Bool("no-ipv4") {
+		u.IPv4 = ddnsSource(c.String("interface"), c.String("ipv4-url"), false)
+	}
+	if c.Bool("ipv6") {
+		u.IPv6 = ddnsSource(c.String("interface"), c.String("ipv6-url"), true)
+	}
+	if c.String("state") != "" {
+		u.Store = ddns.FileStore{Path: c.String("state")}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	err = u.Run(ctx, c.Duration("interval"), func(err error) {
+		fmt.Fprintln(os.Stderr, "Error updating DNS record: ", err)
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return nil
+}
+
+func ddnsSource(iface, url string, ipv6 bool) ddns.Source {
+	if iface != "" {
+		return ddns.InterfaceSource{Name: iface, IPv6: ipv6}
+	}
+	return ddns.HTTPSource{URL: url}
+}
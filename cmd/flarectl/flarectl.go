@@ -2,8 +2,10 @@ package main
 
 import (
 	"os"
+	"time"
 
 	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/cloudflare/cloudflare-go/ddns"
 	"github.com/urfave/cli/v2"
 )
 
@@ -203,6 +205,15 @@ func main() {
 							Name:  "prefixes",
 							Usage: "a list of host/path prefixes to purge",
 						},
+						&cli.StringFlag{
+							Name:  "input",
+							Usage: "read items to purge from a file, one per line, or from stdin with \"-\"",
+						},
+						&cli.StringFlag{
+							Name:  "input-type",
+							Usage: "kind of item read from --input ( files | tags | hosts | prefixes )",
+							Value: "files",
+						},
 					},
 				},
 				{
@@ -245,6 +256,41 @@ func main() {
 							Name:  "zone",
 							Usage: "zone name",
 						},
+						&cli.StringFlag{
+							Name:  "format",
+							Usage: "export format ( bind | octodns | terraform | json )",
+							Value: "bind",
+						},
+					},
+				},
+				{
+					Name:   "snapshot",
+					Action: zoneSnapshot,
+					Usage:  "Write a snapshot of a zone's configuration as JSON",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "zone",
+							Usage: "zone name",
+						},
+						&cli.StringFlag{
+							Name:  "output",
+							Usage: "file to write the snapshot to (default: stdout)",
+						},
+					},
+				},
+				{
+					Name:   "drift",
+					Action: zoneDrift,
+					Usage:  "Compare a zone's configuration with a snapshot",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "snapshot",
+							Usage: "snapshot file written by \"zone snapshot\"",
+						},
+						&cli.StringFlag{
+							Name:  "against",
+							Usage: "second snapshot file to compare with instead of the live zone",
+						},
 					},
 				},
 			},
@@ -387,6 +433,61 @@ func main() {
 						},
 					},
 				},
+				{
+					Name:   "ddns",
+					Action: dnsDDNS,
+					Usage:  "Keep a DNS record pointed at this host's public IP address",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "zone",
+							Usage: "zone name",
+						},
+						&cli.StringFlag{
+							Name:  "name",
+							Usage: "record name",
+						},
+						&cli.DurationFlag{
+							Name:  "interval",
+							Usage: "how often to check the public IP address",
+							Value: 5 * time.Minute,
+						},
+						&cli.BoolFlag{
+							Name:  "ipv6",
+							Usage: "also update the AAAA record",
+						},
+						&cli.BoolFlag{
+							Name:  "no-ipv4",
+							Usage: "do not update the A record",
+						},
+						&cli.StringFlag{
+							Name:  "interface",
+							Usage: "read addresses from a local network interface instead of an HTTP echo service",
+						},
+						&cli.StringFlag{
+							Name:  "ipv4-url",
+							Usage: "HTTP echo service returning the public IPv4 address",
+							Value: ddns.IPifyIPv4URL,
+						},
+						&cli.StringFlag{
+							Name:  "ipv6-url",
+							Usage: "HTTP echo service returning the public IPv6 address",
+							Value: ddns.IPifyIPv6URL,
+						},
+						&cli.StringFlag{
+							Name:  "state",
+							Usage: "file to persist the last published addresses in",
+						},
+						&cli.IntFlag{
+							Name:  "ttl",
+							Usage: "TTL (1 = automatic)",
+							Value: 1,
+						},
+						&cli.BoolFlag{
+							Name:  "proxy",
+							Usage: "proxy through Cloudflare (orange cloud)",
+						},
+					},
+				},
 				{
 					Name:    "delete",
 					Aliases: []string{"d"},
@@ -686,6 +787,133 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:    "workers",
+			Aliases: []string{"w"},
+			Usage:   "Workers information",
+			Before:  initializeAPI,
+			Subcommands: []*cli.Command{
+				{
+					Name:    "tail",
+					Aliases: []string{"t"},
+					Action:  workersTail,
+					Usage:   "Stream live logs of a Worker",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "script",
+							Usage: "Worker script name",
+						},
+						&cli.StringSliceFlag{
+							Name:  "status",
+							Usage: "only show events with this outcome ( ok | error | canceled )",
+						},
+						&cli.StringSliceFlag{
+							Name:  "method",
+							Usage: "only show requests with this HTTP method",
+						},
+						&cli.StringFlag{
+							Name:  "search",
+							Usage: "only show events containing this text",
+						},
+						&cli.StringSliceFlag{
+							Name:  "ip",
+							Usage: "only show requests from this client IP, or \"self\"",
+						},
+						&cli.Float64Flag{
+							Name:  "sampling-rate",
+							Usage: "fraction of events to show, between 0 and 1",
+						},
+						&cli.StringFlag{
+							Name:  "format",
+							Usage: "output format ( pretty | json )",
+							Value: "pretty",
+						},
+					},
+				},
+				{
+					Name:   "sync-secrets",
+					Action: workersSyncSecrets,
+					Usage:  "Set Worker secrets from a .env or JSON file",
+					Flags: []cli.Flag{
+						&cli.StringSliceFlag{
+							Name:  "script",
+							Usage: "Worker script name, may be repeated",
+						},
+						&cli.StringFlag{
+							Name:  "env-file",
+							Usage: ".env file of secrets",
+						},
+						&cli.StringFlag{
+							Name:  "json-file",
+							Usage: "JSON file of secrets",
+						},
+						&cli.BoolFlag{
+							Name:  "delete",
+							Usage: "delete secrets missing from the file",
+						},
+						&cli.BoolFlag{
+							Name:  "skip-existing",
+							Usage: "only set secrets the script doesn't have yet",
+						},
+						&cli.BoolFlag{
+							Name:  "dry-run",
+							Usage: "show the changes without making them",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:   "kv",
+			Usage:  "Workers KV information",
+			Before: initializeAPI,
+			Subcommands: []*cli.Command{
+				{
+					Name:   "backup",
+					Action: kvBackup,
+					Usage:  "Back up a namespace to a directory, .tar or .ndjson file",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "namespace-id",
+							Usage: "namespace ID",
+						},
+						&cli.StringFlag{
+							Name:  "output",
+							Usage: "directory, .tar or .ndjson file to write",
+						},
+						&cli.StringFlag{
+							Name:  "prefix",
+							Usage: "only back up keys starting with this prefix",
+						},
+						&cli.IntFlag{
+							Name:  "concurrency",
+							Usage: "number of values to read at once",
+							Value: 10,
+						},
+					},
+				},
+				{
+					Name:   "restore",
+					Action: kvRestore,
+					Usage:  "Restore a backup into a namespace",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "namespace-id",
+							Usage: "namespace ID",
+						},
+						&cli.StringFlag{
+							Name:  "input",
+							Usage: "directory, .tar or .ndjson file to read",
+						},
+						&cli.IntFlag{
+							Name:  "concurrency",
+							Usage: "number of bulk writes in flight at once",
+							Value: 4,
+						},
+					},
+				},
+			},
+		},
 		{
 			Name:    "origin-ca-root-cert",
 			Aliases: []string{"ocrc"},
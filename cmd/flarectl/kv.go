@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/cloudflare/cloudflare-go/kvbackup"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+func kvBackup(c *cli.Context) error {
+	if err := checkFlags(c, "namespace-id", "output"); err != nil {
+		return err
+	}
+	if api.AccountID == "" {
+		err := errors.New("--account-id is required to back up a KV namespace")
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	w, err := kvbackup.Create(c.String("output"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error creating backup: ", err)
+		return err
+	}
+	n, err := kvbackup.Backup(ctx, api, c.String("namespace-id"), w, kvbackup.BackupOptions{
+		Prefix:      c.String("prefix"),
+		Concurrency: c.Int("concurrency"),
+	})
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error backing up KV namespace: ", err)
+		return err
+	}
+
+	fmt.Printf("Backed up %d keys to %s\n", n, c.String("output"))
+	return nil
+}
+
+func kvRestore(c *cli.Context) error {
+	if err := checkFlags(c, "namespace-id", "input"); err != nil {
+		return err
+	}
+	if api.AccountID == "" {
+		err := errors.New("--account-id is required to restore a KV namespace")
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	r, err := kvbackup.Open(c.String("input"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error opening backup: ", err)
+		return err
+	}
+	defer r.Close()
+
+	res, err := kvbackup.Restore(ctx, api, c.String("namespace-id"), r, cloudflare.WorkersKVBulkOptions{
+		Concurrency: c.Int("concurrency"),
+	})
+	for _, f := range res.Failed {
+		fmt.Fprintf(os.Stderr, "Failed to restore %q: %s\n", f.Key, f.Err)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error restoring KV namespace: ", err)
+		return err
+	}
+
+	fmt.Printf("Restored %d keys, skipped %d expired, %d failed\n", res.Succeeded, res.Expired, len(res.Failed))
+	if len(res.Failed) > 0 {
+		return errors.Errorf("%d keys failed to restore", len(res.Failed))
+	}
+	return nil
+}
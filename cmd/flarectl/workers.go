@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+func workersTail(c *cli.Context) error {
+	if err := checkFlags(c, "script"); err != nil {
+		return err
+	}
+	if api.AccountID == "" {
+		err := errors.New("--account-id is required to tail a Worker")
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+
+	format := c.String("format")
+	if format != "pretty" && format != "json" {
+		err := errors.Errorf("unknown format %q", format)
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	session, err := api.TailWorker(ctx, c.String("script"), cloudflare.WorkerTailFilter{
+		SamplingRate: c.Float64("sampling-rate"),
+		Status:       c.StringSlice("status"),
+		Method:       c.StringSlice("method"),
+		Query:        c.String("search"),
+		ClientIP:     c.StringSlice("ip"),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error tailing Worker: ", err)
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Tailing %s until interrupted; the session expires at %s\n", c.String("script"), session.Tail.ExpiresAt.Local().Format(time.RFC3339))
+
+	enc := json.NewEncoder(os.Stdout)
+	for event := range session.Events {
+		if format == "json" {
+			if err := enc.Encode(event); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			continue
+		}
+		printWorkerTailEvent(event)
+	}
+
+	if err := session.Wait(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error tailing Worker: ", err)
+		return err
+	}
+	return nil
+}
+
+func printWorkerTailEvent(event cloudflare.WorkerTailEvent) {
+	at := event.EventTimestamp.Local().Format(time.RFC3339)
+	switch {
+	case event.Request != nil:
+		status := "-"
+		if event.Request.Response != nil {
+			status = fmt.Sprint(event.Request.Response.Status)
+		}
+		fmt.Printf("%s %s %s %s (%s)\n", at, event.Request.Method, event.Request.URL, status, event.Outcome)
+	case event.Scheduled != nil:
+		fmt.Printf("%s scheduled %q (%s)\n", at, event.Scheduled.Cron, event.Outcome)
+	default:
+		fmt.Printf("%s (%s)\n", at, event.Outcome)
+	}
+
+	for _, l := range event.Logs {
+		parts := make([]string, 0, len(l.Message))
+		for _, m := range l.Message {
+			if s, ok := m.(string); ok {
+				parts = append(parts, s)
+				continue
+			}
+			b, _ := json.Marshal(m)
+			parts = append(parts, string(b))
+		}
+		fmt.Printf("  (%s) %s\n", l.Level, strings.Join(parts, " "))
+	}
+	for _, x := range event.Exceptions {
+		fmt.Printf("  %s: %s\n", x.Name, x.Message)
+	}
+}
+
+func workersSyncSecrets(c *cli.Context) error {
+	if len(c.StringSlice("script")) == 0 {
+		cli.ShowSubcommandHelp(c) //nolint
+		err := errors.New("error: the required flag \"script\" was empty or not provided")
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	if api.AccountID == "" {
+		err := errors.New("--account-id is required to sync Worker secrets")
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+
+	var (
+		secrets cloudflare.WorkersSecretMap
+		err     error
+	)
+	switch {
+	case c.String("env-file") != "" && c.String("json-file") == "":
+		secrets, err = readSecretsFile(c.String("env-file"), cloudflare.WorkersSecretsFromDotEnv)
+	case c.String("json-file") != "" && c.String("env-file") == "":
+		secrets, err = readSecretsFile(c.String("json-file"), cloudflare.WorkersSecretsFromJSON)
+	default:
+		err = errors.New("exactly one of --env-file and --json-file is required")
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading secrets: ", err)
+		return err
+	}
+
+	opts := cloudflare.WorkersSecretsSyncOptions{
+		Delete:       c.Bool("delete"),
+		SkipExisting: c.Bool("skip-existing"),
+		DryRun:       c.Bool("dry-run"),
+	}
+	failed := 0
+	for _, script := range c.StringSlice("script") {
+		res, err := api.SyncWorkersSecrets(context.Background(), script, secrets, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error syncing secrets of %s: %s\n", script, err)
+			failed++
+			continue
+		}
+
+		fmt.Println(script)
+		for _, name := range res.Created {
+			fmt.Printf("  + %s\n", name)
+		}
+		for _, name := range res.Overwritten {
+			fmt.Printf("  ~ %s\n", name)
+		}
+		for _, name := range res.Deleted {
+			fmt.Printf("  - %s\n", name)
+		}
+		for name, err := range res.Failed {
+			fmt.Fprintf(os.Stderr, "  ! %s: %s\n", name, err)
+		}
+		if len(res.Failed) > 0 {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return errors.Errorf("failed to sync secrets of %d scripts", failed)
+	}
+	return nil
+}
+
+func readSecretsFile(path string, parse func(io.Reader) (cloudflare.WorkersSecretMap, error)) (cloudflare.WorkersSecretMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f)
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
 
 	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
 )
 
@@ -213,51 +217,115 @@ func zoneCachePurge(c *cli.Context) error {
 		return err
 	}
 
-	var resp cloudflare.PurgeCacheResponse
-
 	// Purge everything
 	if c.Bool("everything") {
-		resp, err = api.PurgeEverything(context.Background(), zoneID)
+		resp, err := api.PurgeEverything(context.Background(), zoneID)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error purging all from zone %q: %s\n", zoneName, err)
 			return err
 		}
-	} else {
-		var (
-			files    = c.StringSlice("files")
-			tags     = c.StringSlice("tags")
-			hosts    = c.StringSlice("hosts")
-			prefixes = c.StringSlice("prefixes")
-		)
-
-		if len(files) == 0 && len(tags) == 0 && len(hosts) == 0 && len(prefixes) == 0 {
-			fmt.Fprintln(os.Stderr, "You must provide at least one of the --files, --tags, --prefixes or --hosts flags")
-			return nil
+
+		output := make([][]string, 0, 1)
+		output = append(output, formatCacheResponse(resp))
+
+		writeTable(c, output, "ID")
+
+		return nil
+	}
+
+	batch := cloudflare.PurgeCacheBatchRequest{
+		Tags:     c.StringSlice("tags"),
+		Hosts:    c.StringSlice("hosts"),
+		Prefixes: c.StringSlice("prefixes"),
+	}
+	for _, f := range c.StringSlice("files") {
+		batch.Files = append(batch.Files, cloudflare.PurgeCacheFile{URL: f})
+	}
+
+	if input := c.String("input"); input != "" {
+		if err := readPurgeInput(&batch, input, c.String("input-type")); err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading purge input: ", err)
+			return err
 		}
+	}
+
+	if len(batch.Files) == 0 && len(batch.Tags) == 0 && len(batch.Hosts) == 0 && len(batch.Prefixes) == 0 {
+		fmt.Fprintln(os.Stderr, "You must provide at least one of the --files, --tags, --prefixes, --hosts or --input flags")
+		return nil
+	}
 
-		// Purge selectively
-		purgeReq := cloudflare.PurgeCacheRequest{
-			Files:    c.StringSlice("files"),
-			Tags:     c.StringSlice("tags"),
-			Hosts:    c.StringSlice("hosts"),
-			Prefixes: c.StringSlice("prefixes"),
+	// Purge selectively
+	results, err := api.PurgeCacheBatch(context.Background(), zoneID, batch, cloudflare.PurgeCacheBatchOptions{})
+
+	output := make([][]string, 0, len(results))
+	for _, r := range results {
+		status := "ok"
+		if r.Err != nil {
+			status = r.Err.Error()
 		}
+		output = append(output, []string{r.ID, strconv.Itoa(purgeChunkLen(r.Chunk)), status})
+	}
+	writeTable(c, output, "ID", "Items", "Status")
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error purging the cache from zone %q: %s\n", zoneName, err)
+		return err
+	}
+
+	return nil
+}
 
-		resp, err = api.PurgeCache(context.Background(), zoneID, purgeReq)
+// readPurgeInput adds the items listed in path, or stdin if path is "-", to
+// the batch.
+func readPurgeInput(batch *cloudflare.PurgeCacheBatchRequest, path, kind string) error {
+	r := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error purging the cache from zone %q: %s\n", zoneName, err)
 			return err
 		}
+		defer f.Close()
+		r = f
 	}
 
-	output := make([][]string, 0, 1)
-	output = append(output, formatCacheResponse(resp))
+	if kind == "files" {
+		files, err := cloudflare.ReadPurgeCacheFiles(r)
+		if err != nil {
+			return err
+		}
+		batch.Files = append(batch.Files, files...)
+		return nil
+	}
 
-	writeTable(c, output, "ID")
+	var items []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			items = append(items, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
 
+	switch kind {
+	case "tags":
+		batch.Tags = append(batch.Tags, items...)
+	case "hosts":
+		batch.Hosts = append(batch.Hosts, items...)
+	case "prefixes":
+		batch.Prefixes = append(batch.Prefixes, items...)
+	default:
+		return errors.Errorf("unknown input type %q", kind)
+	}
 	return nil
 }
 
+func purgeChunkLen(chunk cloudflare.PurgeCacheBatchRequest) int {
+	return len(chunk.Files) + len(chunk.Tags) + len(chunk.Hosts) + len(chunk.Prefixes)
+}
+
 func zoneRecords(c *cli.Context) error {
 	var zone string
 	if c.NArg() > 0 {
@@ -358,12 +426,120 @@ func zoneExport(c *cli.Context) error {
 		return err
 	}
 
-	res, err := api.ZoneExport(context.Background(), zoneID)
+	format := cloudflare.DNSExportFormat(c.String("format"))
+	if format == cloudflare.DNSExportFormatBIND {
+		res, err := api.ZoneExport(context.Background(), zoneID)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		fmt.Print(res)
+
+		return nil
+	}
+
+	records, err := api.DNSRecords(context.Background(), zoneID, cloudflare.DNSRecord{})
 	if err != nil {
 		fmt.Println(err)
 		return err
 	}
-	fmt.Print(res)
 
+	err = cloudflare.ExportDNSRecords(os.Stdout, format, zoneID, zone, records)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error exporting DNS records: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func zoneSnapshot(c *cli.Context) error {
+	var zone string
+	if c.NArg() > 0 {
+		zone = c.Args().First()
+	} else if c.String("zone") != "" {
+		zone = c.String("zone")
+	} else {
+		cli.ShowSubcommandHelp(c) //nolint
+		return nil
+	}
+
+	zoneID, err := api.ZoneIDByName(zone)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+
+	snapshot, err := api.ZoneSnapshot(context.Background(), zoneID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error taking zone snapshot: ", err)
+		return err
+	}
+
+	out, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+
+	if c.String("output") == "" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	return ioutil.WriteFile(c.String("output"), out, 0600)
+}
+
+func readZoneSnapshot(path string) (*cloudflare.ZoneSnapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot cloudflare.ZoneSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, errors.Wrapf(err, "could not parse snapshot %s", path)
+	}
+	return &snapshot, nil
+}
+
+func zoneDrift(c *cli.Context) error {
+	if err := checkFlags(c, "snapshot"); err != nil {
+		return err
+	}
+
+	before, err := readZoneSnapshot(c.String("snapshot"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+
+	var changes []cloudflare.ZoneSnapshotChange
+	if c.String("against") != "" {
+		after, err := readZoneSnapshot(c.String("against"))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return err
+		}
+		changes, err = cloudflare.DiffZoneSnapshots(before, after)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return err
+		}
+	} else {
+		changes, err = api.ZoneDrift(context.Background(), before)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error checking zone drift: ", err)
+			return err
+		}
+	}
+
+	output := make([][]string, 0, len(changes))
+	for _, change := range changes {
+		output = append(output, []string{change.Change, change.Resource, change.ID})
+	}
+	writeTable(c, output, "Change", "Resource", "ID")
+
+	if len(changes) > 0 {
+		return cli.Exit("", 1)
+	}
 	return nil
 }
@@ -0,0 +1,223 @@
+// Package ddns keeps A and AAAA records on Cloudflare pointed at the current
+// public address of a host whose IP changes over time.
+package ddns
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
+)
+
+// Updater publishes the addresses reported by its sources to a single DNS
+// record name. At least one of IPv4 and IPv6 must be set; a nil source
+// disables that record type. The name is kept to one record per type: when
+// an address is published, other records of its type for the name are
+// deleted.
+type Updater struct {
+	API    *cloudflare.API
+	ZoneID string
+	// Name is the fully qualified record name, e.g. "home.example.com".
+	Name string
+
+	IPv4 Source
+	IPv6 Source
+
+	// TTL and Proxied are applied to records when they are written. A TTL
+	// of 1 means automatic.
+	TTL     int
+	Proxied bool
+
+	// Store persists the last published addresses. NewUpdater sets it to a
+	// MemoryStore, which only keeps state for the lifetime of the Updater.
+	Store StateStore
+
+	// Logger receives progress messages. If nil, nothing is logged.
+	Logger cloudflare.Logger
+}
+
+// NewUpdater returns an Updater for the record name in the zone, keeping
+// its state in memory. Set IPv4 and/or IPv6 before calling Update.
+func NewUpdater(api *cloudflare.API, zoneID, name string) *Updater {
+	return &Updater{
+		API:    api,
+		ZoneID: zoneID,
+		Name:   name,
+		Store:  &MemoryStore{},
+	}
+}
+
+// Change describes the outcome of an update for one record type.
+type Change struct {
+	Type     string
+	Previous string
+	Current  string
+	Updated  bool
+}
+
+// Update looks up the current addresses and writes any that differ from the
+// last known state. Records are only touched when the address has changed,
+// so it is safe to call on a short interval.
+//
+// IPv4 and IPv6 are synced independently: if one fails, the other is still
+// synced and its state saved, and the returned error describes the failure.
+func (u *Updater) Update(ctx context.Context) ([]Change, error) {
+	if u.IPv4 == nil && u.IPv6 == nil {
+		return nil, errors.New("ddns: no IPv4 or IPv6 source configured")
+	}
+	if u.Store == nil {
+		return nil, errors.New("ddns: no state store configured")
+	}
+
+	state, err := u.Store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	var failures []string
+	if u.IPv4 != nil {
+		c, err := u.sync(ctx, "A", u.IPv4, state.IPv4)
+		if err != nil {
+			failures = append(failures, err.Error())
+		} else {
+			state.IPv4 = c.Current
+			changes = append(changes, c)
+		}
+	}
+	if u.IPv6 != nil {
+		c, err := u.sync(ctx, "AAAA", u.IPv6, state.IPv6)
+		if err != nil {
+			failures = append(failures, err.Error())
+		} else {
+			state.IPv6 = c.Current
+			changes = append(changes, c)
+		}
+	}
+
+	for _, c := range changes {
+		if c.Updated {
+			state.UpdatedAt = time.Now().UTC()
+			break
+		}
+	}
+
+	if len(changes) > 0 {
+		if err := u.Store.Save(state); err != nil {
+			return changes, err
+		}
+	}
+
+	if len(failures) > 0 {
+		return changes, errors.New(strings.Join(failures, "; "))
+	}
+	return changes, nil
+}
+
+// sync brings the record of type rtype in line with the address reported by
+// src.
+func (u *Updater) sync(ctx context.Context, rtype string, src Source, last string) (Change, error) {
+	change := Change{Type: rtype, Previous: last}
+
+	ip, err := src.PublicIP(ctx)
+	if err != nil {
+		return change, errors.Wrapf(err, "could not determine public %s address", rtype)
+	}
+	if (rtype == "AAAA") != (ip.To4() == nil) {
+		return change, errors.Errorf("source returned %s for a %s record", ip, rtype)
+	}
+	change.Current = ip.String()
+
+	if change.Current == last {
+		return change, nil
+	}
+
+	records, err := u.API.DNSRecords(ctx, u.ZoneID, cloudflare.DNSRecord{Name: u.Name, Type: rtype})
+	if err != nil {
+		return change, err
+	}
+
+	proxied := u.Proxied
+	rr := cloudflare.DNSRecord{
+		Name:    u.Name,
+		Type:    rtype,
+		Content: change.Current,
+		TTL:     u.TTL,
+		Proxied: &proxied,
+	}
+
+	if len(records) == 0 {
+		u.logf("creating %s record %s -> %s", rtype, u.Name, change.Current)
+		if _, err := u.API.CreateDNSRecord(ctx, u.ZoneID, rr); err != nil {
+			return change, err
+		}
+		change.Updated = true
+		return change, nil
+	}
+
+	// Keep the record that already has the address or else update the
+	// first one. The name must resolve to this host alone, and updating
+	// several records to the same address would duplicate them, so any
+	// others are deleted.
+	keep := -1
+	for i, r := range records {
+		if net.ParseIP(r.Content).Equal(ip) {
+			keep = i
+			break
+		}
+	}
+	if keep < 0 {
+		keep = 0
+		u.logf("updating %s record %s from %s to %s", rtype, u.Name, records[0].Content, change.Current)
+		if err := u.API.UpdateDNSRecord(ctx, u.ZoneID, records[0].ID, rr); err != nil {
+			return change, err
+		}
+		change.Updated = true
+	}
+
+	for i, r := range records {
+		if i == keep {
+			continue
+		}
+		u.logf("deleting extra %s record %s -> %s", rtype, u.Name, r.Content)
+		if err := u.API.DeleteDNSRecord(ctx, u.ZoneID, r.ID); err != nil {
+			return change, errors.Wrapf(err, "could not delete extra %s record %s -> %s", rtype, u.Name, r.Content)
+		}
+		change.Updated = true
+	}
+
+	return change, nil
+}
+
+// Run calls Update immediately and then every interval until ctx is
+// cancelled. Errors from individual updates are passed to onError, if set,
+// and do not stop the loop.
+func (u *Updater) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	if interval <= 0 {
+		return errors.New("ddns: interval must be positive")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := u.Update(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (u *Updater) logf(format string, v ...interface{}) {
+	if u.Logger != nil {
+		u.Logger.Printf(format, v...)
+	}
+}
@@ -0,0 +1,238 @@
+package ddns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func staticSource(addr string) Source {
+	return SourceFunc(func(context.Context) (net.IP, error) {
+		return net.ParseIP(addr), nil
+	})
+}
+
+func newTestAPI(t *testing.T, mux *http.ServeMux) *cloudflare.API {
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	api, err := cloudflare.New("deadbeef", "cloudflare@example.org",
+		cloudflare.BaseURL(server.URL),
+		cloudflare.UsingRateLimit(100000),
+		cloudflare.UsingRetryPolicy(0, 0, 0))
+	require.NoError(t, err)
+	return api
+}
+
+func TestUpdater_UpdatesOnlyOnChange(t *testing.T) {
+	mux := http.NewServeMux()
+	var listCalls, patchCalls int
+	mux.HandleFunc("/zones/023e105f4ecef8ad9ca31a8372d0c353/dns_records", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "home.example.com", r.URL.Query().Get("name"))
+		assert.Equal(t, "A", r.URL.Query().Get("type"))
+		listCalls++
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{
+			"success": true,
+			"errors": [],
+			"messages": [],
+			"result": [{"id": "372e67954025e0ba6aaa6d586b9e0b59", "type": "A", "name": "home.example.com", "content": "198.51.100.1"}],
+			"result_info": {"page": 1, "per_page": 100, "count": 1, "total_count": 1, "total_pages": 1}
+		}`)
+	})
+	mux.HandleFunc("/zones/023e105f4ecef8ad9ca31a8372d0c353/dns_records/372e67954025e0ba6aaa6d586b9e0b59", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		var rr cloudflare.DNSRecord
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&rr))
+		assert.Equal(t, "198.51.100.2", rr.Content)
+		patchCalls++
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": {}}`)
+	})
+
+	u := NewUpdater(newTestAPI(t, mux), "023e105f4ecef8ad9ca31a8372d0c353", "home.example.com")
+	u.IPv4 = staticSource("198.51.100.2")
+	u.TTL = 1
+	u.Store = FileStore{Path: filepath.Join(t.TempDir(), "state.json")}
+
+	changes, err := u.Update(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Type: "A", Current: "198.51.100.2", Updated: true}}, changes)
+	assert.Equal(t, 1, listCalls)
+	assert.Equal(t, 1, patchCalls)
+
+	// A second run with the same address must not touch the API.
+	changes, err = u.Update(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Type: "A", Previous: "198.51.100.2", Current: "198.51.100.2"}}, changes)
+	assert.Equal(t, 1, listCalls)
+	assert.Equal(t, 1, patchCalls)
+
+	st, err := u.Store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "198.51.100.2", st.IPv4)
+	assert.False(t, st.UpdatedAt.IsZero())
+}
+
+func TestUpdater_CreatesMissingRecord(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/zones/023e105f4ecef8ad9ca31a8372d0c353/dns_records", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [], "result_info": {"page": 1, "total_pages": 0}}`)
+		case http.MethodPost:
+			var rr cloudflare.DNSRecord
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&rr))
+			assert.Equal(t, "AAAA", rr.Type)
+			assert.Equal(t, "2001:db8::1", rr.Content)
+			fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": {"id": "372e67954025e0ba6aaa6d586b9e0b59"}}`)
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	})
+
+	u := NewUpdater(newTestAPI(t, mux), "023e105f4ecef8ad9ca31a8372d0c353", "home.example.com")
+	u.IPv6 = staticSource("2001:db8::1")
+
+	changes, err := u.Update(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Type: "AAAA", Current: "2001:db8::1", Updated: true}}, changes)
+}
+
+func TestUpdater_ExtraRecords(t *testing.T) {
+	for name, tc := range map[string]struct {
+		records string
+		patched []string
+		deleted []string
+	}{
+		"none has the address": {
+			records: `{"id": "r1", "type": "A", "name": "home.example.com", "content": "198.51.100.1"},
+				{"id": "r2", "type": "A", "name": "home.example.com", "content": "198.51.100.3"}`,
+			patched: []string{"r1"},
+			deleted: []string{"r2"},
+		},
+		"one has the address": {
+			records: `{"id": "r1", "type": "A", "name": "home.example.com", "content": "198.51.100.1"},
+				{"id": "r2", "type": "A", "name": "home.example.com", "content": "198.51.100.2"}`,
+			deleted: []string{"r1"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var patched, deleted []string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/zones/023e105f4ecef8ad9ca31a8372d0c353/dns_records", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("content-type", "application/json")
+				fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": [%s], "result_info": {"page": 1, "total_pages": 1}}`, tc.records)
+			})
+			for _, id := range []string{"r1", "r2"} {
+				id := id
+				mux.HandleFunc("/zones/023e105f4ecef8ad9ca31a8372d0c353/dns_records/"+id, func(w http.ResponseWriter, r *http.Request) {
+					switch r.Method {
+					case http.MethodPatch:
+						patched = append(patched, id)
+					case http.MethodDelete:
+						deleted = append(deleted, id)
+					default:
+						t.Errorf("unexpected method %s", r.Method)
+					}
+					w.Header().Set("content-type", "application/json")
+					fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": {"id": %q}}`, id)
+				})
+			}
+
+			u := NewUpdater(newTestAPI(t, mux), "023e105f4ecef8ad9ca31a8372d0c353", "home.example.com")
+			u.IPv4 = staticSource("198.51.100.2")
+
+			changes, err := u.Update(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, []Change{{Type: "A", Current: "198.51.100.2", Updated: true}}, changes)
+			assert.Equal(t, tc.patched, patched)
+			assert.Equal(t, tc.deleted, deleted)
+
+			st, err := u.Store.Load()
+			require.NoError(t, err)
+			assert.Equal(t, "198.51.100.2", st.IPv4)
+		})
+	}
+}
+
+func TestUpdater_RejectsWrongFamily(t *testing.T) {
+	u := NewUpdater(newTestAPI(t, http.NewServeMux()), "023e105f4ecef8ad9ca31a8372d0c353", "home.example.com")
+	u.IPv4 = staticSource("2001:db8::1")
+
+	_, err := u.Update(context.Background())
+	assert.Error(t, err)
+}
+
+func TestUpdater_FamiliesAreIndependent(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/zones/023e105f4ecef8ad9ca31a8372d0c353/dns_records", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [], "result_info": {"page": 1, "total_pages": 0}}`)
+		case http.MethodPost:
+			fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": {"id": "372e67954025e0ba6aaa6d586b9e0b59"}}`)
+		}
+	})
+
+	u := NewUpdater(newTestAPI(t, mux), "023e105f4ecef8ad9ca31a8372d0c353", "home.example.com")
+	u.IPv4 = SourceFunc(func(context.Context) (net.IP, error) {
+		return nil, errors.New("no route to host")
+	})
+	u.IPv6 = staticSource("2001:db8::1")
+
+	changes, err := u.Update(context.Background())
+	assert.EqualError(t, err, "could not determine public A address: no route to host")
+	assert.Equal(t, []Change{{Type: "AAAA", Current: "2001:db8::1", Updated: true}}, changes)
+
+	st, err := u.Store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "", st.IPv4)
+	assert.Equal(t, "2001:db8::1", st.IPv6)
+}
+
+func TestHTTPSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "fl=123\nh=www.cloudflare.com\nip=203.0.113.7\nts=1634567890.123\n")
+	}))
+	defer server.Close()
+
+	ip, err := HTTPSource{URL: server.URL}.PublicIP(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.7", ip.String())
+}
+
+func Test_parseEchoResponse(t *testing.T) {
+	ip, err := parseEchoResponse(" 2001:db8::5\n")
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::5", ip.String())
+
+	_, err = parseEchoResponse("<html>nope</html>")
+	assert.Error(t, err)
+}
+
+func TestFileStore_MissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	st, err := FileStore{Path: path}.Load()
+	require.NoError(t, err)
+	assert.Equal(t, State{}, st)
+
+	require.NoError(t, FileStore{Path: path}.Save(State{IPv6: "2001:db8::1"}))
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"ipv6": "2001:db8::1"`)
+}
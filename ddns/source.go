@@ -0,0 +1,131 @@
+package ddns
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Source looks up the current public IP address of the host.
+type Source interface {
+	PublicIP(ctx context.Context) (net.IP, error)
+}
+
+// SourceFunc adapts an ordinary function into a Source.
+type SourceFunc func(ctx context.Context) (net.IP, error)
+
+// PublicIP calls f(ctx).
+func (f SourceFunc) PublicIP(ctx context.Context) (net.IP, error) {
+	return f(ctx)
+}
+
+// Well known HTTP echo services that return the caller's address as plain
+// text.
+const (
+	CloudflareTraceURL = "https://www.cloudflare.com/cdn-cgi/trace"
+	IPifyIPv4URL       = "https://api.ipify.org"
+	IPifyIPv6URL       = "https://api6.ipify.org"
+)
+
+// HTTPSource fetches the public IP address from an HTTP echo service. The
+// response body may either be the bare address or a key=value document
+// containing an "ip=" line, such as the one served by CloudflareTraceURL.
+type HTTPSource struct {
+	URL    string
+	Client *http.Client
+}
+
+// PublicIP implements Source.
+func (s HTTPSource) PublicIP(ctx context.Context) (net.IP, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "HTTP request creation failed")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "HTTP request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("%s returned HTTP status %d", s.URL, resp.StatusCode)
+	}
+
+	// Echo services return a handful of bytes; anything larger is not what
+	// we're looking for.
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read response body")
+	}
+
+	return parseEchoResponse(string(body))
+}
+
+// parseEchoResponse extracts an address from an echo service response.
+func parseEchoResponse(body string) (net.IP, error) {
+	body = strings.TrimSpace(body)
+	if ip := net.ParseIP(body); ip != nil {
+		return ip, nil
+	}
+
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "ip=") {
+			if ip := net.ParseIP(strings.TrimSpace(strings.TrimPrefix(line, "ip="))); ip != nil {
+				return ip, nil
+			}
+		}
+	}
+
+	return nil, errors.Errorf("no IP address found in response %q", body)
+}
+
+// InterfaceSource returns the first global unicast address of the given
+// family configured on a local network interface. It is useful when the
+// host is directly connected and has no NAT in front of it.
+type InterfaceSource struct {
+	Name string
+	IPv6 bool
+}
+
+// PublicIP implements Source.
+func (s InterfaceSource) PublicIP(ctx context.Context) (net.IP, error) {
+	iface, err := net.InterfaceByName(s.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not find interface %q", s.Name)
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not list addresses of interface %q", s.Name)
+	}
+
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || !ipnet.IP.IsGlobalUnicast() {
+			continue
+		}
+		if (ipnet.IP.To4() == nil) == s.IPv6 {
+			return ipnet.IP, nil
+		}
+	}
+
+	return nil, errors.Errorf("interface %q has no global %s address", s.Name, family(s.IPv6))
+}
+
+func family(ipv6 bool) string {
+	if ipv6 {
+		return "IPv6"
+	}
+	return "IPv4"
+}
@@ -0,0 +1,97 @@
+package ddns
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// State is the last known set of addresses published for a record name.
+type State struct {
+	IPv4      string    `json:"ipv4,omitempty"`
+	IPv6      string    `json:"ipv6,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StateStore persists State between runs so that an updater restarting does
+// not have to touch the API when nothing has changed.
+type StateStore interface {
+	Load() (State, error)
+	Save(State) error
+}
+
+// FileStore keeps State as a JSON document on the local filesystem. A
+// missing file is treated as an empty state.
+type FileStore struct {
+	Path string
+}
+
+// Load implements StateStore.
+func (s FileStore) Load() (State, error) {
+	var st State
+
+	b, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return st, nil
+	}
+	if err != nil {
+		return st, errors.Wrap(err, "could not read state file")
+	}
+
+	if err := json.Unmarshal(b, &st); err != nil {
+		return st, errors.Wrap(err, "could not parse state file")
+	}
+
+	return st, nil
+}
+
+// Save implements StateStore. The file is written to a temporary name and
+// renamed into place so a crash never leaves a truncated document behind.
+func (s FileStore) Save(st State) error {
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "could not marshal state")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "could not create state file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "could not write state file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "could not write state file")
+	}
+
+	return errors.Wrap(os.Rename(tmp.Name(), s.Path), "could not write state file")
+}
+
+// MemoryStore keeps State in memory. It is the store NewUpdater configures.
+type MemoryStore struct {
+	mu    sync.Mutex
+	state State
+}
+
+// Load implements StateStore.
+func (s *MemoryStore) Load() (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state, nil
+}
+
+// Save implements StateStore.
+func (s *MemoryStore) Save(st State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = st
+	return nil
+}
@@ -0,0 +1,428 @@
+package cloudflare
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DNSExportFormat is the output format of a DNS record export.
+type DNSExportFormat string
+
+// Supported DNS export formats. DNSExportFormatBIND is produced by the API
+// itself via ZoneExport; the others are rendered locally from DNSRecords.
+const (
+	DNSExportFormatBIND      DNSExportFormat = "bind"
+	DNSExportFormatOctoDNS   DNSExportFormat = "octodns"
+	DNSExportFormatTerraform DNSExportFormat = "terraform"
+	DNSExportFormatJSON      DNSExportFormat = "json"
+)
+
+// ExportDNSRecords writes records in the given format. zoneName is used to
+// make record names relative for octoDNS and zoneID is written into the
+// Terraform resources; either may be empty for formats that don't need them.
+//
+// DNSExportFormatBIND is not supported here, use ZoneExport instead.
+func ExportDNSRecords(w io.Writer, format DNSExportFormat, zoneID, zoneName string, records []DNSRecord) error {
+	switch format {
+	case DNSExportFormatOctoDNS:
+		return ExportDNSRecordsOctoDNS(w, zoneName, records)
+	case DNSExportFormatTerraform:
+		return ExportDNSRecordsTerraform(w, zoneID, records)
+	case DNSExportFormatJSON:
+		return ExportDNSRecordsJSON(w, records)
+	case DNSExportFormatBIND:
+		return errors.New("BIND exports are generated by the API, use ZoneExport")
+	default:
+		return errors.Errorf("unknown DNS export format %q", format)
+	}
+}
+
+// sortedDNSRecords returns a copy of records in a stable order so exports
+// diff cleanly between runs.
+func sortedDNSRecords(records []DNSRecord) []DNSRecord {
+	sorted := make([]DNSRecord, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if pa, pb := dnsRecordPriority(a), dnsRecordPriority(b); pa != pb {
+			return pa < pb
+		}
+		return a.Content < b.Content
+	})
+	return sorted
+}
+
+func dnsRecordPriority(r DNSRecord) int {
+	if r.Priority == nil {
+		return -1
+	}
+	return int(*r.Priority)
+}
+
+// dnsRecordData returns the structured data of SRV, LOC, CAA and similar
+// records, or nil if the record has none.
+func dnsRecordData(r DNSRecord) map[string]interface{} {
+	data, ok := r.Data.(map[string]interface{})
+	if !ok || len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+// exportedDNSRecord is the canonical JSON representation of a DNS record. It
+// deliberately leaves out identifiers and timestamps that change between
+// otherwise identical zones.
+type exportedDNSRecord struct {
+	Name     string                 `json:"name"`
+	Type     string                 `json:"type"`
+	Content  string                 `json:"content,omitempty"`
+	TTL      int                    `json:"ttl"`
+	Proxied  bool                   `json:"proxied"`
+	Priority *uint16                `json:"priority,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+// ExportDNSRecordsJSON writes records as an indented JSON array sorted by
+// name, type, priority and content.
+func ExportDNSRecordsJSON(w io.Writer, records []DNSRecord) error {
+	out := make([]exportedDNSRecord, 0, len(records))
+	for _, r := range sortedDNSRecords(records) {
+		out = append(out, exportedDNSRecord{
+			Name:     r.Name,
+			Type:     r.Type,
+			Content:  r.Content,
+			TTL:      r.TTL,
+			Proxied:  r.Proxied != nil && *r.Proxied,
+			Priority: r.Priority,
+			Data:     dnsRecordData(r),
+		})
+	}
+
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshalling DNS records to JSON")
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// ExportDNSRecordsOctoDNS writes records as an octoDNS zone file. Record
+// names are made relative to zoneName and records sharing a name and type
+// are merged into a single multi-value entry, which gets the lowest TTL of
+// its records. Automatic TTLs are omitted so octoDNS falls back to its own
+// default, unless another record of the entry has an explicit TTL.
+func ExportDNSRecordsOctoDNS(w io.Writer, zoneName string, records []DNSRecord) error {
+	type rrset struct {
+		typ     string
+		ttl     int
+		proxied bool
+		values  []interface{}
+	}
+
+	zoneName = strings.TrimSuffix(strings.ToLower(zoneName), ".")
+	var names []string
+	sets := make(map[string][]*rrset)
+
+	for _, r := range sortedDNSRecords(records) {
+		name := strings.ToLower(strings.TrimSuffix(r.Name, "."))
+		switch {
+		case name == zoneName:
+			name = ""
+		case zoneName != "" && strings.HasSuffix(name, "."+zoneName):
+			name = strings.TrimSuffix(name, "."+zoneName)
+		}
+
+		if _, ok := sets[name]; !ok {
+			names = append(names, name)
+		}
+
+		var set *rrset
+		for _, s := range sets[name] {
+			if s.typ == r.Type {
+				set = s
+			}
+		}
+		if set == nil {
+			set = &rrset{typ: r.Type, ttl: r.TTL, proxied: r.Proxied != nil && *r.Proxied}
+			sets[name] = append(sets[name], set)
+		}
+		if r.TTL > 1 && (set.ttl <= 1 || r.TTL < set.ttl) {
+			set.ttl = r.TTL
+		}
+		set.values = append(set.values, octoDNSValue(r))
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("---\n")
+	for _, name := range names {
+		fmt.Fprintf(bw, "%s:\n", yamlString(name))
+		for _, s := range sets[name] {
+			fmt.Fprintf(bw, "- type: %s\n", s.typ)
+			if s.ttl > 1 {
+				fmt.Fprintf(bw, "  ttl: %d\n", s.ttl)
+			}
+			if s.proxied {
+				bw.WriteString("  octodns:\n    cloudflare:\n      proxied: true\n")
+			}
+
+			if len(s.values) == 1 && isOctoDNSSingleValue(s.typ) {
+				writeYAMLValue(bw, "  ", "value", s.values[0])
+				continue
+			}
+			bw.WriteString("  values:\n")
+			for _, v := range s.values {
+				writeYAMLListItem(bw, "  ", v)
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// isOctoDNSSingleValue reports whether octoDNS expects the record type to
+// carry a single "value" rather than a list of "values".
+func isOctoDNSSingleValue(typ string) bool {
+	switch typ {
+	case "CNAME", "ALIAS", "PTR":
+		return true
+	}
+	return false
+}
+
+// octoDNSValue converts a record into the value shape octoDNS expects for
+// its type. Unknown types fall back to the raw content.
+func octoDNSValue(r DNSRecord) interface{} {
+	switch r.Type {
+	case "CNAME", "NS", "PTR", "ALIAS":
+		return fqdn(r.Content)
+	case "TXT", "SPF":
+		return strings.ReplaceAll(r.Content, ";", `\;`)
+	case "MX":
+		return []yamlField{
+			{"exchange", fqdn(r.Content)},
+			{"preference", dnsRecordPriority(r)},
+		}
+	case "SRV":
+		weight, port, target := srvFields(r)
+		return []yamlField{
+			{"port", port},
+			{"priority", dnsRecordPriority(r)},
+			{"target", fqdn(target)},
+			{"weight", weight},
+		}
+	case "CAA":
+		parts := strings.SplitN(r.Content, " ", 3)
+		if len(parts) != 3 {
+			return r.Content
+		}
+		flags, _ := strconv.Atoi(parts[0])
+		return []yamlField{
+			{"flags", flags},
+			{"tag", parts[1]},
+			{"value", strings.Trim(parts[2], `"`)},
+		}
+	}
+	return r.Content
+}
+
+// srvFields returns the weight, port and target of an SRV record, preferring
+// the structured data over the space separated content.
+func srvFields(r DNSRecord) (int, int, string) {
+	if data := dnsRecordData(r); data != nil {
+		weight, _ := data["weight"].(float64)
+		port, _ := data["port"].(float64)
+		target, _ := data["target"].(string)
+		return int(weight), int(port), target
+	}
+
+	parts := strings.Fields(r.Content)
+	if len(parts) != 3 {
+		return 0, 0, r.Content
+	}
+	weight, _ := strconv.Atoi(parts[0])
+	port, _ := strconv.Atoi(parts[1])
+	return weight, port, parts[2]
+}
+
+func fqdn(name string) string {
+	if name == "" || strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// yamlField is an ordered key/value pair, used so structured values are
+// written with a deterministic key order.
+type yamlField struct {
+	key   string
+	value interface{}
+}
+
+func writeYAMLValue(w *bufio.Writer, indent, key string, v interface{}) {
+	if fields, ok := v.([]yamlField); ok {
+		fmt.Fprintf(w, "%s%s:\n", indent, key)
+		for _, f := range fields {
+			writeYAMLValue(w, indent+"  ", f.key, f.value)
+		}
+		return
+	}
+	fmt.Fprintf(w, "%s%s: %s\n", indent, key, yamlScalar(v))
+}
+
+func writeYAMLListItem(w *bufio.Writer, indent string, v interface{}) {
+	fields, ok := v.([]yamlField)
+	if !ok {
+		fmt.Fprintf(w, "%s- %s\n", indent, yamlScalar(v))
+		return
+	}
+	for i, f := range fields {
+		prefix := indent + "  "
+		if i == 0 {
+			prefix = indent + "- "
+		}
+		fmt.Fprintf(w, "%s%s: %s\n", prefix, f.key, yamlScalar(f.value))
+	}
+}
+
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return yamlString(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// yamlString single quotes s, which is valid YAML for any string and avoids
+// surprises with values such as "yes", "on" or IPv6 addresses.
+func yamlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+var terraformIdentifierReplacer = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// ExportDNSRecordsTerraform writes records as cloudflare_record resources in
+// Terraform HCL. Resource names are derived from the record type and name
+// and are suffixed with a counter where several records would collide.
+func ExportDNSRecordsTerraform(w io.Writer, zoneID string, records []DNSRecord) error {
+	bw := bufio.NewWriter(w)
+	used := make(map[string]bool)
+
+	for i, r := range sortedDNSRecords(records) {
+		label := strings.ToLower(r.Type + "_" + terraformIdentifierReplacer.ReplaceAllString(r.Name, "_"))
+		label = strings.Trim(label, "_")
+		if label == "" || (label[0] >= '0' && label[0] <= '9') {
+			label = "record_" + label
+		}
+		// A suffixed label may itself be the label of another record, such
+		// as "a_example_com_2", so keep counting until one is free.
+		if used[label] {
+			base := label
+			for n := 2; used[label]; n++ {
+				label = fmt.Sprintf("%s_%d", base, n)
+			}
+		}
+		used[label] = true
+
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		fmt.Fprintf(bw, "resource \"cloudflare_record\" %s {\n", hclString(label))
+		fmt.Fprintf(bw, "  zone_id = %s\n", hclString(zoneID))
+		fmt.Fprintf(bw, "  name    = %s\n", hclString(r.Name))
+		fmt.Fprintf(bw, "  type    = %s\n", hclString(r.Type))
+
+		data := dnsRecordData(r)
+		if data == nil {
+			fmt.Fprintf(bw, "  value   = %s\n", hclString(r.Content))
+		}
+		fmt.Fprintf(bw, "  ttl     = %d\n", r.TTL)
+		if r.Proxied != nil && *r.Proxied {
+			bw.WriteString("  proxied = true\n")
+		}
+		if r.Priority != nil {
+			fmt.Fprintf(bw, "  priority = %d\n", *r.Priority)
+		}
+
+		if data != nil {
+			keys := make([]string, 0, len(data))
+			for k := range data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			bw.WriteString("\n  data {\n")
+			for _, k := range keys {
+				fmt.Fprintf(bw, "    %s = %s\n", k, hclValue(data[k]))
+			}
+			bw.WriteString("  }\n")
+		}
+		bw.WriteString("}\n")
+	}
+
+	return bw.Flush()
+}
+
+func hclValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return hclString(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		b, _ := json.Marshal(v)
+		return hclString(string(b))
+	}
+}
+
+// hclString quotes s as an HCL string literal, escaping template sequences
+// so values are taken literally. Only the escapes HCL understands are used;
+// other control characters are written as \uNNNN.
+func hclString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '$', '%':
+			b.WriteRune(r)
+			if strings.HasPrefix(s[i+1:], "{") {
+				b.WriteRune(r)
+			}
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,194 @@
+package cloudflare
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportTestRecords() []DNSRecord {
+	proxied := true
+	notProxied := false
+	mxPriority := uint16(10)
+	srvPriority := uint16(5)
+
+	return []DNSRecord{
+		{ID: "2", Type: "CNAME", Name: "www.example.com", Content: "example.com", TTL: 1, Proxied: &proxied},
+		{ID: "3", Type: "MX", Name: "example.com", Content: "mx.example.net", TTL: 3600, Priority: &mxPriority, Proxied: &notProxied},
+		{ID: "1", Type: "A", Name: "example.com", Content: "198.51.100.5", TTL: 1, Proxied: &proxied},
+		{ID: "4", Type: "A", Name: "example.com", Content: "198.51.100.4", TTL: 1, Proxied: &proxied},
+		{ID: "5", Type: "TXT", Name: "example.com", Content: "v=spf1 -all; it's ok", TTL: 300},
+		{
+			ID: "6", Type: "SRV", Name: "_sip._tcp.example.com", Content: "1\t5060\tsip.example.com", TTL: 1, Priority: &srvPriority,
+			Data: map[string]interface{}{
+				"service": "_sip", "proto": "_tcp", "name": "example.com",
+				"priority": float64(5), "weight": float64(1), "port": float64(5060), "target": "sip.example.com",
+			},
+		},
+	}
+}
+
+func TestExportDNSRecordsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, ExportDNSRecordsJSON(&buf, exportTestRecords()[:3]))
+
+	want := `[
+  {
+    "name": "example.com",
+    "type": "A",
+    "content": "198.51.100.5",
+    "ttl": 1,
+    "proxied": true
+  },
+  {
+    "name": "example.com",
+    "type": "MX",
+    "content": "mx.example.net",
+    "ttl": 3600,
+    "proxied": false,
+    "priority": 10
+  },
+  {
+    "name": "www.example.com",
+    "type": "CNAME",
+    "content": "example.com",
+    "ttl": 1,
+    "proxied": true
+  }
+]
+`
+	assert.Equal(t, want, buf.String())
+
+	// Input order must not affect the output.
+	records := exportTestRecords()[:3]
+	records[0], records[2] = records[2], records[0]
+	var again bytes.Buffer
+	require.NoError(t, ExportDNSRecordsJSON(&again, records))
+	assert.Equal(t, buf.String(), again.String())
+}
+
+func TestExportDNSRecordsOctoDNS(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, ExportDNSRecordsOctoDNS(&buf, "example.com", exportTestRecords()))
+
+	want := `---
+'':
+- type: A
+  octodns:
+    cloudflare:
+      proxied: true
+  values:
+  - '198.51.100.4'
+  - '198.51.100.5'
+- type: MX
+  ttl: 3600
+  values:
+  - exchange: 'mx.example.net.'
+    preference: 10
+- type: TXT
+  ttl: 300
+  values:
+  - 'v=spf1 -all\; it''s ok'
+'_sip._tcp':
+- type: SRV
+  values:
+  - port: 5060
+    priority: 5
+    target: 'sip.example.com.'
+    weight: 1
+'www':
+- type: CNAME
+  octodns:
+    cloudflare:
+      proxied: true
+  value: 'example.com.'
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestExportDNSRecordsTerraform(t *testing.T) {
+	var buf bytes.Buffer
+	records := exportTestRecords()
+	require.NoError(t, ExportDNSRecordsTerraform(&buf, "023e105f4ecef8ad9ca31a8372d0c353", []DNSRecord{records[2], records[3], records[5]}))
+
+	want := `resource "cloudflare_record" "srv__sip__tcp_example_com" {
+  zone_id = "023e105f4ecef8ad9ca31a8372d0c353"
+  name    = "_sip._tcp.example.com"
+  type    = "SRV"
+  ttl     = 1
+  priority = 5
+
+  data {
+    name = "example.com"
+    port = 5060
+    priority = 5
+    proto = "_tcp"
+    service = "_sip"
+    target = "sip.example.com"
+    weight = 1
+  }
+}
+
+resource "cloudflare_record" "a_example_com" {
+  zone_id = "023e105f4ecef8ad9ca31a8372d0c353"
+  name    = "example.com"
+  type    = "A"
+  value   = "198.51.100.4"
+  ttl     = 1
+  proxied = true
+}
+
+resource "cloudflare_record" "a_example_com_2" {
+  zone_id = "023e105f4ecef8ad9ca31a8372d0c353"
+  name    = "example.com"
+  type    = "A"
+  value   = "198.51.100.5"
+  ttl     = 1
+  proxied = true
+}
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestExportDNSRecords_UnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, ExportDNSRecords(&buf, DNSExportFormat("xml"), "", "", nil))
+	assert.Error(t, ExportDNSRecords(&buf, DNSExportFormatBIND, "", "", nil))
+}
+
+func TestExportDNSRecordsOctoDNS_LowestTTL(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, ExportDNSRecordsOctoDNS(&buf, "example.com", []DNSRecord{
+		{Type: "A", Name: "example.com", Content: "198.51.100.4", TTL: 3600},
+		{Type: "A", Name: "example.com", Content: "198.51.100.5", TTL: 1},
+		{Type: "A", Name: "example.com", Content: "198.51.100.6", TTL: 300},
+	}))
+	assert.Contains(t, buf.String(), "- type: A\n  ttl: 300\n")
+}
+
+func TestExportDNSRecordsTerraform_LabelCollision(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, ExportDNSRecordsTerraform(&buf, "023e105f4ecef8ad9ca31a8372d0c353", []DNSRecord{
+		{Type: "A", Name: "example.com", Content: "198.51.100.4", TTL: 1},
+		{Type: "A", Name: "example.com", Content: "198.51.100.5", TTL: 1},
+		{Type: "A", Name: "example.com.2", Content: "198.51.100.6", TTL: 1},
+		{Type: "A", Name: "example.com", Content: "198.51.100.7", TTL: 1},
+	}))
+
+	labels := regexp.MustCompile(`resource "cloudflare_record" "([^"]+)"`).FindAllStringSubmatch(buf.String(), -1)
+	require.Len(t, labels, 4)
+	seen := make(map[string]bool)
+	for _, l := range labels {
+		assert.False(t, seen[l[1]], "duplicate label %s", l[1])
+		seen[l[1]] = true
+	}
+}
+
+func Test_hclString(t *testing.T) {
+	assert.Equal(t, `"v=$${x} %%{y} \"q\""`, hclString(`v=${x} %{y} "q"`))
+	assert.Equal(t, `"$$${x} 100% {y}"`, hclString(`$${x} 100% {y}`))
+	assert.Equal(t, `"a\tb\nc\\d\u0007é"`, hclString("a\tb\nc\\d\aé"))
+}
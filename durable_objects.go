@@ -0,0 +1,158 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// DurableObjectNamespace is a Durable Object class exported by a script,
+// under which that class's objects are stored.
+type DurableObjectNamespace struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Script string `json:"script"`
+	Class  string `json:"class"`
+}
+
+// ListDurableObjectNamespacesResponse contains a slice of Durable Object
+// namespaces associated with an account, pagination information, and an
+// embedded response struct.
+type ListDurableObjectNamespacesResponse struct {
+	Response
+	Result     []DurableObjectNamespace `json:"result"`
+	ResultInfo `json:"result_info"`
+}
+
+// DurableObject is a single object within a Durable Object namespace.
+type DurableObject struct {
+	ID            string `json:"id"`
+	HasStoredData bool   `json:"hasStoredData"`
+}
+
+// ListDurableObjectsOptions contains optional parameters for listing a
+// namespace's objects.
+type ListDurableObjectsOptions struct {
+	Limit  *int
+	Cursor *string
+}
+
+// ListDurableObjectsResponse contains a slice of objects belonging to a
+// Durable Object namespace, the cursor of the next page, and an embedded
+// response struct.
+type ListDurableObjectsResponse struct {
+	Response
+	Result     []DurableObject `json:"result"`
+	ResultInfo `json:"result_info"`
+}
+
+// ListDurableObjectNamespaces lists the account's Durable Object namespaces.
+//
+// API reference: https://api.cloudflare.com/#durable-objects-namespace-list-namespaces
+func (api *API) ListDurableObjectNamespaces(ctx context.Context) ([]DurableObjectNamespace, error) {
+	if err := api.checkAccountID(); err != nil {
+		return nil, err
+	}
+
+	v := url.Values{}
+	v.Set("per_page", "100")
+
+	var namespaces []DurableObjectNamespace
+	page := 1
+
+	for {
+		v.Set("page", strconv.Itoa(page))
+		uri := fmt.Sprintf("/accounts/%s/workers/durable_objects/namespaces?%s", api.AccountID, v.Encode())
+		res, err := api.makeRequestContext(ctx, http.MethodGet, uri, nil)
+		if err != nil {
+			return []DurableObjectNamespace{}, err
+		}
+
+		var p ListDurableObjectNamespacesResponse
+		if err := json.Unmarshal(res, &p); err != nil {
+			return []DurableObjectNamespace{}, errors.Wrap(err, errUnmarshalError)
+		}
+
+		if !p.Success {
+			return []DurableObjectNamespace{}, errors.New(errRequestNotSuccessful)
+		}
+
+		namespaces = append(namespaces, p.Result...)
+		if p.ResultInfo.Page >= p.ResultInfo.TotalPages {
+			break
+		}
+
+		page++
+	}
+
+	return namespaces, nil
+}
+
+// encode encodes non-nil fields into URL encoded form.
+func (o ListDurableObjectsOptions) encode() string {
+	v := url.Values{}
+	if o.Limit != nil {
+		v.Set("limit", strconv.Itoa(*o.Limit))
+	}
+	if o.Cursor != nil {
+		v.Set("cursor", *o.Cursor)
+	}
+	return v.Encode()
+}
+
+// ListDurableObjects returns a single page of the objects stored in a
+// Durable Object namespace. The cursor of the next page is in
+// ResultInfo.Cursor and is empty on the last page.
+//
+// API reference: https://api.cloudflare.com/#durable-objects-namespace-list-objects
+func (api *API) ListDurableObjects(ctx context.Context, namespaceID string, o ListDurableObjectsOptions) (ListDurableObjectsResponse, error) {
+	if err := api.checkAccountID(); err != nil {
+		return ListDurableObjectsResponse{}, err
+	}
+
+	uri := fmt.Sprintf("/accounts/%s/workers/durable_objects/namespaces/%s/objects", api.AccountID, namespaceID)
+	if q := o.encode(); q != "" {
+		uri += "?" + q
+	}
+	res, err := api.makeRequestContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return ListDurableObjectsResponse{}, err
+	}
+
+	result := ListDurableObjectsResponse{}
+	if err := json.Unmarshal(res, &result); err != nil {
+		return result, errors.Wrap(err, errUnmarshalError)
+	}
+	return result, err
+}
+
+// ListAllDurableObjects returns every object stored in a Durable Object
+// namespace, following cursors until the last page.
+//
+// API reference: https://api.cloudflare.com/#durable-objects-namespace-list-objects
+func (api *API) ListAllDurableObjects(ctx context.Context, namespaceID string) ([]DurableObject, error) {
+	var objects []DurableObject
+	o := ListDurableObjectsOptions{}
+
+	for {
+		p, err := api.ListDurableObjects(ctx, namespaceID, o)
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, p.Result...)
+		if p.ResultInfo.Cursor == "" {
+			break
+		}
+
+		cursor := p.ResultInfo.Cursor
+		o.Cursor = &cursor
+	}
+
+	return objects, nil
+}
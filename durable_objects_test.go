@@ -0,0 +1,93 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDurableObjectNamespaces(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/durable_objects/namespaces", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		w.Header().Set("content-type", "application/json")
+		if r.URL.Query().Get("page") == "1" {
+			fmt.Fprint(w, `{
+				"success": true,
+				"errors": [],
+				"messages": [],
+				"result": [{"id": "5fd1cafff895419c8bcc647fc64ab8f0", "name": "chat_ChatRoom", "script": "chat", "class": "ChatRoom"}],
+				"result_info": {"page": 1, "per_page": 1, "count": 1, "total_count": 2, "total_pages": 2}
+			}`)
+			return
+		}
+		fmt.Fprint(w, `{
+			"success": true,
+			"errors": [],
+			"messages": [],
+			"result": [{"id": "9a5c8ea3f1f64a9c8d3b7bd5d29d7b41", "name": "chat_RateLimiter", "script": "chat", "class": "RateLimiter"}],
+			"result_info": {"page": 2, "per_page": 1, "count": 1, "total_count": 2, "total_pages": 2}
+		}`)
+	})
+
+	want := []DurableObjectNamespace{
+		{ID: "5fd1cafff895419c8bcc647fc64ab8f0", Name: "chat_ChatRoom", Script: "chat", Class: "ChatRoom"},
+		{ID: "9a5c8ea3f1f64a9c8d3b7bd5d29d7b41", Name: "chat_RateLimiter", Script: "chat", Class: "RateLimiter"},
+	}
+
+	actual, err := client.ListDurableObjectNamespaces(context.Background())
+	if assert.NoError(t, err) {
+		assert.Equal(t, want, actual)
+	}
+}
+
+func TestListDurableObjects(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/durable_objects/namespaces/5fd1cafff895419c8bcc647fc64ab8f0/objects", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		assert.Equal(t, "cursor=AAAA&limit=1", r.URL.RawQuery)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{
+			"success": true,
+			"errors": [],
+			"messages": [],
+			"result": [{"id": "fe7803fc55b964e09d94666545aab688d360c6bda69ba349ced1e5f28d2fc2c8", "hasStoredData": true}],
+			"result_info": {"count": 1, "cursor": "BBBB"}
+		}`)
+	})
+
+	limit, cursor := 1, "AAAA"
+	actual, err := client.ListDurableObjects(context.Background(), "5fd1cafff895419c8bcc647fc64ab8f0", ListDurableObjectsOptions{Limit: &limit, Cursor: &cursor})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []DurableObject{{ID: "fe7803fc55b964e09d94666545aab688d360c6bda69ba349ced1e5f28d2fc2c8", HasStoredData: true}}, actual.Result)
+		assert.Equal(t, "BBBB", actual.ResultInfo.Cursor)
+	}
+}
+
+func TestListAllDurableObjects(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/durable_objects/namespaces/5fd1cafff895419c8bcc647fc64ab8f0/objects", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		w.Header().Set("content-type", "application/json")
+		if r.URL.Query().Get("cursor") == "" {
+			fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [{"id": "a", "hasStoredData": true}], "result_info": {"count": 1, "cursor": "next"}}`)
+			return
+		}
+		assert.Equal(t, "next", r.URL.Query().Get("cursor"))
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [{"id": "b", "hasStoredData": false}], "result_info": {"count": 1, "cursor": ""}}`)
+	})
+
+	actual, err := client.ListAllDurableObjects(context.Background(), "5fd1cafff895419c8bcc647fc64ab8f0")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []DurableObject{{ID: "a", HasStoredData: true}, {ID: "b"}}, actual)
+	}
+}
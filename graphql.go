@@ -0,0 +1,68 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GraphQLRequest is a query sent to the GraphQL Analytics API.
+type GraphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphQLError is an error reported in the errors field of a GraphQL
+// response.
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLErrors is returned when a GraphQL response contains errors. The
+// API reports these with a 200 status, often alongside partial data.
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Message)
+	}
+	return "GraphQL error: " + strings.Join(messages, ", ")
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+// GraphQLQuery runs a raw query against the GraphQL Analytics API and
+// decodes the data field of the response into result. It uses the same
+// authentication, retries and rate limiting as every other call.
+//
+// API reference: https://developers.cloudflare.com/analytics/graphql-api/
+func (api *API) GraphQLQuery(ctx context.Context, req GraphQLRequest, result interface{}) error {
+	res, err := api.makeRequestContext(ctx, http.MethodPost, "/graphql", req)
+	if err != nil {
+		return err
+	}
+
+	var r graphQLResponse
+	if err := json.Unmarshal(res, &r); err != nil {
+		return errors.Wrap(err, errUnmarshalError)
+	}
+	if len(r.Errors) > 0 {
+		return r.Errors
+	}
+	if result == nil || len(r.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Data, result); err != nil {
+		return errors.Wrap(err, errUnmarshalError)
+	}
+	return nil
+}
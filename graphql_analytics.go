@@ -0,0 +1,418 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultAnalyticsLimit is the number of rows requested when a query does
+// not set a limit.
+const defaultAnalyticsLimit = 100
+
+var graphQLIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// AnalyticsTimeRange bounds an analytics query. Since is inclusive and Until
+// is exclusive; a zero value leaves that side open.
+type AnalyticsTimeRange struct {
+	Since time.Time
+	Until time.Time
+}
+
+// AnalyticsLast returns the time range covering the last d up to now.
+func AnalyticsLast(d time.Duration) AnalyticsTimeRange {
+	now := time.Now().UTC()
+	return AnalyticsTimeRange{Since: now.Add(-d), Until: now}
+}
+
+// AnalyticsFilter is a GraphQL Analytics filter object. Keys are a field
+// name, optionally followed by an operator suffix such as "_in" or "_geq".
+// The helper methods return the filter so calls can be chained:
+//
+//	AnalyticsFilter{}.Eq("clientCountryName", "US").In("edgeResponseStatus", []int{403, 429})
+type AnalyticsFilter map[string]interface{}
+
+func (f AnalyticsFilter) set(key string, value interface{}) AnalyticsFilter {
+	if f == nil {
+		f = AnalyticsFilter{}
+	}
+	f[key] = value
+	return f
+}
+
+// Eq matches rows where field equals value.
+func (f AnalyticsFilter) Eq(field string, value interface{}) AnalyticsFilter {
+	return f.set(field, value)
+}
+
+// Neq matches rows where field does not equal value.
+func (f AnalyticsFilter) Neq(field string, value interface{}) AnalyticsFilter {
+	return f.set(field+"_neq", value)
+}
+
+// In matches rows where field is one of values.
+func (f AnalyticsFilter) In(field string, values interface{}) AnalyticsFilter {
+	return f.set(field+"_in", values)
+}
+
+// NotIn matches rows where field is none of values.
+func (f AnalyticsFilter) NotIn(field string, values interface{}) AnalyticsFilter {
+	return f.set(field+"_notin", values)
+}
+
+// Gt matches rows where field is greater than value.
+func (f AnalyticsFilter) Gt(field string, value interface{}) AnalyticsFilter {
+	return f.set(field+"_gt", value)
+}
+
+// Geq matches rows where field is greater than or equal to value.
+func (f AnalyticsFilter) Geq(field string, value interface{}) AnalyticsFilter {
+	return f.set(field+"_geq", value)
+}
+
+// Lt matches rows where field is less than value.
+func (f AnalyticsFilter) Lt(field string, value interface{}) AnalyticsFilter {
+	return f.set(field+"_lt", value)
+}
+
+// Leq matches rows where field is less than or equal to value.
+func (f AnalyticsFilter) Leq(field string, value interface{}) AnalyticsFilter {
+	return f.set(field+"_leq", value)
+}
+
+// Like matches rows where field matches a SQL LIKE pattern.
+func (f AnalyticsFilter) Like(field, pattern string) AnalyticsFilter {
+	return f.set(field+"_like", pattern)
+}
+
+// Or matches rows that match any of filters.
+func (f AnalyticsFilter) Or(filters ...AnalyticsFilter) AnalyticsFilter {
+	return f.set("OR", filters)
+}
+
+// withTimeRange returns a copy of the filter restricted to r on the
+// datetime field.
+func (f AnalyticsFilter) withTimeRange(r AnalyticsTimeRange) AnalyticsFilter {
+	out := make(AnalyticsFilter, len(f)+2)
+	for k, v := range f {
+		out[k] = v
+	}
+	if !r.Since.IsZero() {
+		out["datetime_geq"] = r.Since.UTC().Format(time.RFC3339)
+	}
+	if !r.Until.IsZero() {
+		out["datetime_lt"] = r.Until.UTC().Format(time.RFC3339)
+	}
+	return out
+}
+
+// analyticsQuery holds what the typed dataset queries have in common.
+type analyticsQuery struct {
+	scope      string // "zones" or "accounts"
+	tag        string
+	dataset    string
+	filterType string
+	filter     AnalyticsFilter
+	orderBy    []string
+	limit      int
+	selection  string
+}
+
+func (q analyticsQuery) request() (GraphQLRequest, error) {
+	for _, o := range q.orderBy {
+		if !graphQLIdentifier.MatchString(o) {
+			return GraphQLRequest{}, errors.Errorf("invalid orderBy %q", o)
+		}
+	}
+
+	tag := "zoneTag"
+	if q.scope == "accounts" {
+		tag = "accountTag"
+	}
+
+	limit := q.limit
+	if limit <= 0 {
+		limit = defaultAnalyticsLimit
+	}
+
+	orderBy := ""
+	if len(q.orderBy) > 0 {
+		orderBy = fmt.Sprintf(", orderBy: [%s]", strings.Join(q.orderBy, ", "))
+	}
+
+	query := fmt.Sprintf(`query ($%s: string, $filter: %s, $limit: uint64!) {
+  viewer {
+    %s(filter: {%s: $%s}) {
+      %s(filter: $filter, limit: $limit%s) {
+%s
+      }
+    }
+  }
+}`, tag, q.filterType, q.scope, tag, tag, q.dataset, orderBy, q.selection)
+
+	return GraphQLRequest{
+		Query: query,
+		Variables: map[string]interface{}{
+			tag:      q.tag,
+			"filter": q.filter,
+			"limit":  limit,
+		},
+	}, nil
+}
+
+// run executes the query and decodes the rows of the dataset into out.
+func (q analyticsQuery) run(ctx context.Context, api *API, out interface{}) error {
+	req, err := q.request()
+	if err != nil {
+		return err
+	}
+
+	var data struct {
+		Viewer map[string][]map[string]json.RawMessage `json:"viewer"`
+	}
+	if err := api.GraphQLQuery(ctx, req, &data); err != nil {
+		return err
+	}
+
+	scopes := data.Viewer[q.scope]
+	if len(scopes) == 0 {
+		return nil
+	}
+	rows, ok := scopes[0][q.dataset]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(rows, out); err != nil {
+		return errors.Wrap(err, errUnmarshalError)
+	}
+	return nil
+}
+
+// dimensionsSelection renders the dimensions block of a grouped dataset.
+func dimensionsSelection(dimensions []string) (string, error) {
+	if len(dimensions) == 0 {
+		return "", nil
+	}
+	for _, d := range dimensions {
+		if !graphQLIdentifier.MatchString(d) {
+			return "", errors.Errorf("invalid dimension %q", d)
+		}
+	}
+	return fmt.Sprintf("        dimensions { %s }\n", strings.Join(dimensions, " ")), nil
+}
+
+// HTTPRequestsAdaptiveGroupsQuery queries the httpRequestsAdaptiveGroups
+// dataset of a zone, which aggregates sampled HTTP requests by the given
+// dimensions.
+type HTTPRequestsAdaptiveGroupsQuery struct {
+	ZoneID     string
+	TimeRange  AnalyticsTimeRange
+	Filter     AnalyticsFilter
+	Dimensions []string // for example "clientCountryName" or "edgeResponseStatus"
+	OrderBy    []string // for example "count_DESC"
+	Limit      int
+}
+
+// HTTPRequestsAdaptiveGroup is a row of the httpRequestsAdaptiveGroups
+// dataset.
+type HTTPRequestsAdaptiveGroup struct {
+	Count      int64                   `json:"count"`
+	Dimensions map[string]interface{}  `json:"dimensions"`
+	Sum        HTTPRequestsAdaptiveSum `json:"sum"`
+}
+
+// HTTPRequestsAdaptiveSum holds the summed metrics of a
+// HTTPRequestsAdaptiveGroup.
+type HTTPRequestsAdaptiveSum struct {
+	EdgeResponseBytes int64 `json:"edgeResponseBytes"`
+	Visits            int64 `json:"visits"`
+}
+
+func (q HTTPRequestsAdaptiveGroupsQuery) query() (analyticsQuery, error) {
+	dims, err := dimensionsSelection(q.Dimensions)
+	if err != nil {
+		return analyticsQuery{}, err
+	}
+	return analyticsQuery{
+		scope:      "zones",
+		tag:        q.ZoneID,
+		dataset:    "httpRequestsAdaptiveGroups",
+		filterType: "ZoneHttpRequestsAdaptiveGroupsFilter_InputObject",
+		filter:     q.Filter.withTimeRange(q.TimeRange),
+		orderBy:    q.OrderBy,
+		limit:      q.Limit,
+		selection:  "        count\n" + dims + "        sum { edgeResponseBytes visits }",
+	}, nil
+}
+
+// GraphQLRequest returns the query and variables that would be sent.
+func (q HTTPRequestsAdaptiveGroupsQuery) GraphQLRequest() (GraphQLRequest, error) {
+	aq, err := q.query()
+	if err != nil {
+		return GraphQLRequest{}, err
+	}
+	return aq.request()
+}
+
+// HTTPRequestsAdaptiveGroups runs q against the GraphQL Analytics API.
+//
+// API reference: https://developers.cloudflare.com/analytics/graphql-api/
+func (api *API) HTTPRequestsAdaptiveGroups(ctx context.Context, q HTTPRequestsAdaptiveGroupsQuery) ([]HTTPRequestsAdaptiveGroup, error) {
+	aq, err := q.query()
+	if err != nil {
+		return nil, err
+	}
+	var rows []HTTPRequestsAdaptiveGroup
+	if err := aq.run(ctx, api, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// FirewallEventsAdaptiveQuery queries the firewallEventsAdaptive dataset of
+// a zone, which lists individual firewall events. Events are returned
+// newest first unless OrderBy says otherwise.
+type FirewallEventsAdaptiveQuery struct {
+	ZoneID    string
+	TimeRange AnalyticsTimeRange
+	Filter    AnalyticsFilter
+	OrderBy   []string
+	Limit     int
+}
+
+// FirewallEvent is a row of the firewallEventsAdaptive dataset.
+type FirewallEvent struct {
+	Action                      string    `json:"action"`
+	ClientASN                   string    `json:"clientAsn"`
+	ClientCountryName           string    `json:"clientCountryName"`
+	ClientIP                    string    `json:"clientIP"`
+	ClientRequestHTTPHost       string    `json:"clientRequestHTTPHost"`
+	ClientRequestHTTPMethodName string    `json:"clientRequestHTTPMethodName"`
+	ClientRequestPath           string    `json:"clientRequestPath"`
+	ClientRequestQuery          string    `json:"clientRequestQuery"`
+	Datetime                    time.Time `json:"datetime"`
+	RayName                     string    `json:"rayName"`
+	RuleID                      string    `json:"ruleId"`
+	Source                      string    `json:"source"`
+	UserAgent                   string    `json:"userAgent"`
+}
+
+func (q FirewallEventsAdaptiveQuery) query() analyticsQuery {
+	orderBy := q.OrderBy
+	if len(orderBy) == 0 {
+		orderBy = []string{"datetime_DESC"}
+	}
+	return analyticsQuery{
+		scope:      "zones",
+		tag:        q.ZoneID,
+		dataset:    "firewallEventsAdaptive",
+		filterType: "ZoneFirewallEventsAdaptiveFilter_InputObject",
+		filter:     q.Filter.withTimeRange(q.TimeRange),
+		orderBy:    orderBy,
+		limit:      q.Limit,
+		selection: "        action clientAsn clientCountryName clientIP clientRequestHTTPHost\n" +
+			"        clientRequestHTTPMethodName clientRequestPath clientRequestQuery\n" +
+			"        datetime rayName ruleId source userAgent",
+	}
+}
+
+// GraphQLRequest returns the query and variables that would be sent.
+func (q FirewallEventsAdaptiveQuery) GraphQLRequest() (GraphQLRequest, error) {
+	return q.query().request()
+}
+
+// FirewallEventsAdaptive runs q against the GraphQL Analytics API.
+//
+// API reference: https://developers.cloudflare.com/analytics/graphql-api/
+func (api *API) FirewallEventsAdaptive(ctx context.Context, q FirewallEventsAdaptiveQuery) ([]FirewallEvent, error) {
+	var rows []FirewallEvent
+	if err := q.query().run(ctx, api, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// WorkersInvocationsAdaptiveQuery queries the workersInvocationsAdaptive
+// dataset of an account, which aggregates Worker invocations by the given
+// dimensions. Dimensions default to the script name.
+type WorkersInvocationsAdaptiveQuery struct {
+	AccountID  string
+	TimeRange  AnalyticsTimeRange
+	Filter     AnalyticsFilter
+	Dimensions []string
+	OrderBy    []string
+	Limit      int
+}
+
+// WorkersInvocationsGroup is a row of the workersInvocationsAdaptive
+// dataset.
+type WorkersInvocationsGroup struct {
+	Dimensions map[string]interface{}      `json:"dimensions"`
+	Sum        WorkersInvocationsSum       `json:"sum"`
+	Quantiles  WorkersInvocationsQuantiles `json:"quantiles"`
+}
+
+// WorkersInvocationsSum holds the summed metrics of a
+// WorkersInvocationsGroup.
+type WorkersInvocationsSum struct {
+	Requests    int64 `json:"requests"`
+	Errors      int64 `json:"errors"`
+	Subrequests int64 `json:"subrequests"`
+}
+
+// WorkersInvocationsQuantiles holds CPU time percentiles, in microseconds,
+// of a WorkersInvocationsGroup.
+type WorkersInvocationsQuantiles struct {
+	CPUTimeP50 float64 `json:"cpuTimeP50"`
+	CPUTimeP99 float64 `json:"cpuTimeP99"`
+}
+
+func (q WorkersInvocationsAdaptiveQuery) query() (analyticsQuery, error) {
+	dimensions := q.Dimensions
+	if len(dimensions) == 0 {
+		dimensions = []string{"scriptName"}
+	}
+	dims, err := dimensionsSelection(dimensions)
+	if err != nil {
+		return analyticsQuery{}, err
+	}
+	return analyticsQuery{
+		scope:      "accounts",
+		tag:        q.AccountID,
+		dataset:    "workersInvocationsAdaptive",
+		filterType: "AccountWorkersInvocationsAdaptiveFilter_InputObject",
+		filter:     q.Filter.withTimeRange(q.TimeRange),
+		orderBy:    q.OrderBy,
+		limit:      q.Limit,
+		selection:  dims + "        sum { requests errors subrequests }\n        quantiles { cpuTimeP50 cpuTimeP99 }",
+	}, nil
+}
+
+// GraphQLRequest returns the query and variables that would be sent.
+func (q WorkersInvocationsAdaptiveQuery) GraphQLRequest() (GraphQLRequest, error) {
+	aq, err := q.query()
+	if err != nil {
+		return GraphQLRequest{}, err
+	}
+	return aq.request()
+}
+
+// WorkersInvocationsAdaptive runs q against the GraphQL Analytics API.
+//
+// API reference: https://developers.cloudflare.com/analytics/graphql-api/
+func (api *API) WorkersInvocationsAdaptive(ctx context.Context, q WorkersInvocationsAdaptiveQuery) ([]WorkersInvocationsGroup, error) {
+	aq, err := q.query()
+	if err != nil {
+		return nil, err
+	}
+	var rows []WorkersInvocationsGroup
+	if err := aq.run(ctx, api, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,160 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyticsFilter(t *testing.T) {
+	var f AnalyticsFilter
+	f = f.Eq("clientCountryName", "US").In("edgeResponseStatus", []int{403, 429}).Neq("clientRequestHTTPHost", "a.example.com")
+
+	since := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	ranged := f.withTimeRange(AnalyticsTimeRange{Since: since, Until: since.Add(time.Hour)})
+
+	assert.Equal(t, AnalyticsFilter{
+		"clientCountryName":         "US",
+		"edgeResponseStatus_in":     []int{403, 429},
+		"clientRequestHTTPHost_neq": "a.example.com",
+		"datetime_geq":              "2021-06-01T00:00:00Z",
+		"datetime_lt":               "2021-06-01T01:00:00Z",
+	}, ranged)
+	assert.Len(t, f, 3, "withTimeRange must not modify the filter")
+}
+
+func TestHTTPRequestsAdaptiveGroupsQuery_InvalidDimension(t *testing.T) {
+	_, err := HTTPRequestsAdaptiveGroupsQuery{Dimensions: []string{"count } }"}}.GraphQLRequest()
+	assert.EqualError(t, err, `invalid dimension "count } }"`)
+}
+
+func TestHTTPRequestsAdaptiveGroups(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method, "Expected method 'POST', got %s", r.Method)
+
+		var req GraphQLRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Contains(t, req.Query, "$filter: ZoneHttpRequestsAdaptiveGroupsFilter_InputObject")
+		assert.Contains(t, req.Query, "zones(filter: {zoneTag: $zoneTag})")
+		assert.Contains(t, req.Query, "httpRequestsAdaptiveGroups(filter: $filter, limit: $limit, orderBy: [count_DESC])")
+		assert.Contains(t, req.Query, "dimensions { clientCountryName }")
+		assert.Equal(t, testZoneID, req.Variables["zoneTag"])
+		assert.Equal(t, float64(10), req.Variables["limit"])
+		assert.Equal(t, map[string]interface{}{
+			"edgeResponseStatus": float64(403),
+			"datetime_geq":       "2021-06-01T00:00:00Z",
+			"datetime_lt":        "2021-06-02T00:00:00Z",
+		}, req.Variables["filter"])
+
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{
+			"data": {
+				"viewer": {
+					"zones": [{
+						"httpRequestsAdaptiveGroups": [
+							{"count": 42, "dimensions": {"clientCountryName": "US"}, "sum": {"edgeResponseBytes": 1024, "visits": 7}},
+							{"count": 3, "dimensions": {"clientCountryName": "GB"}, "sum": {"edgeResponseBytes": 10, "visits": 1}}
+						]
+					}]
+				}
+			},
+			"errors": null
+		}`)
+	}
+	mux.HandleFunc("/graphql", handler)
+
+	since := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := client.HTTPRequestsAdaptiveGroups(context.Background(), HTTPRequestsAdaptiveGroupsQuery{
+		ZoneID:     testZoneID,
+		TimeRange:  AnalyticsTimeRange{Since: since, Until: since.Add(24 * time.Hour)},
+		Filter:     AnalyticsFilter{}.Eq("edgeResponseStatus", 403),
+		Dimensions: []string{"clientCountryName"},
+		OrderBy:    []string{"count_DESC"},
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []HTTPRequestsAdaptiveGroup{
+		{Count: 42, Dimensions: map[string]interface{}{"clientCountryName": "US"}, Sum: HTTPRequestsAdaptiveSum{EdgeResponseBytes: 1024, Visits: 7}},
+		{Count: 3, Dimensions: map[string]interface{}{"clientCountryName": "GB"}, Sum: HTTPRequestsAdaptiveSum{EdgeResponseBytes: 10, Visits: 1}},
+	}, rows)
+}
+
+func TestFirewallEventsAdaptive(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var req GraphQLRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Contains(t, req.Query, "firewallEventsAdaptive(filter: $filter, limit: $limit, orderBy: [datetime_DESC])")
+		assert.Equal(t, float64(defaultAnalyticsLimit), req.Variables["limit"])
+
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"data": {"viewer": {"zones": [{"firewallEventsAdaptive": [
+			{"action": "block", "clientIP": "192.0.2.1", "datetime": "2021-06-01T12:00:00Z", "rayName": "6598b5c3cb1b1b1b", "ruleId": "abc", "source": "firewallrules"}
+		]}]}}}`)
+	}
+	mux.HandleFunc("/graphql", handler)
+
+	events, err := client.FirewallEventsAdaptive(context.Background(), FirewallEventsAdaptiveQuery{ZoneID: testZoneID})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "block", events[0].Action)
+	assert.Equal(t, "192.0.2.1", events[0].ClientIP)
+	assert.Equal(t, time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC), events[0].Datetime)
+}
+
+func TestWorkersInvocationsAdaptive(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var req GraphQLRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Contains(t, req.Query, "accounts(filter: {accountTag: $accountTag})")
+		assert.Contains(t, req.Query, "dimensions { scriptName }")
+		assert.Equal(t, testAccountID, req.Variables["accountTag"])
+
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"data": {"viewer": {"accounts": [{"workersInvocationsAdaptive": [
+			{"dimensions": {"scriptName": "api"}, "sum": {"requests": 100, "errors": 2, "subrequests": 50}, "quantiles": {"cpuTimeP50": 1200.5, "cpuTimeP99": 8000}}
+		]}]}}}`)
+	}
+	mux.HandleFunc("/graphql", handler)
+
+	rows, err := client.WorkersInvocationsAdaptive(context.Background(), WorkersInvocationsAdaptiveQuery{AccountID: testAccountID})
+	require.NoError(t, err)
+	assert.Equal(t, []WorkersInvocationsGroup{{
+		Dimensions: map[string]interface{}{"scriptName": "api"},
+		Sum:        WorkersInvocationsSum{Requests: 100, Errors: 2, Subrequests: 50},
+		Quantiles:  WorkersInvocationsQuantiles{CPUTimeP50: 1200.5, CPUTimeP99: 8000},
+	}}, rows)
+}
+
+func TestGraphQLQuery_Errors(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"data": null, "errors": [{"message": "cannot request data older than 2678400s", "path": ["viewer", "zones", 0]}]}`)
+	})
+
+	var out map[string]interface{}
+	err := client.GraphQLQuery(context.Background(), GraphQLRequest{Query: "{ viewer { zones { __typename } } }"}, &out)
+	require.Error(t, err)
+	assert.EqualError(t, err, "GraphQL error: cannot request data older than 2678400s")
+
+	var gqlErrs GraphQLErrors
+	require.ErrorAs(t, err, &gqlErrs)
+	assert.Equal(t, []interface{}{"viewer", "zones", float64(0)}, gqlErrs[0].Path)
+}
@@ -0,0 +1,134 @@
+// Package kvbackup dumps Workers KV namespaces to local archives and
+// restores them, into the same namespace or another one.
+//
+// Three archive formats are supported: a directory holding one file per
+// value, a tar archive, and NDJSON with one base64 encoded pair per line.
+// NDJSON archives are also valid input for
+// cloudflare.API.WriteWorkersKVBulkNDJSON.
+package kvbackup
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
+)
+
+// Entry is a single key of a namespace.
+type Entry struct {
+	Key        string
+	Value      []byte
+	Expiration int
+	Metadata   interface{}
+}
+
+// Writer writes entries to an archive. Close must be called to complete
+// the archive.
+type Writer interface {
+	Write(Entry) error
+	Close() error
+}
+
+// Reader reads entries from an archive, returning io.EOF after the last.
+type Reader interface {
+	Read() (Entry, error)
+	Close() error
+}
+
+// BackupOptions configures Backup.
+type BackupOptions struct {
+	// Prefix restricts the backup to keys starting with it.
+	Prefix string
+	// Concurrency is the number of values read at once.
+	Concurrency int
+}
+
+// Backup writes every key of a namespace to w, returning the number of
+// keys written. Keys deleted while the backup runs are skipped. w is not
+// closed.
+func Backup(ctx context.Context, api *cloudflare.API, namespaceID string, w Writer, opts BackupOptions) (int, error) {
+	it := api.WorkersKVIterator(ctx, namespaceID, cloudflare.WorkersKVIteratorOptions{
+		Prefix:      opts.Prefix,
+		FetchValues: true,
+		Concurrency: opts.Concurrency,
+	})
+
+	n := 0
+	for it.Next() {
+		e := it.Entry()
+		if err := w.Write(Entry{Key: e.Name, Value: e.Value, Expiration: e.Expiration, Metadata: e.Metadata}); err != nil {
+			return n, errors.Wrapf(err, "failed to write %q", e.Name)
+		}
+		n++
+	}
+	if err := it.Err(); err != nil {
+		return n, errors.Wrap(err, "failed to list keys")
+	}
+	return n, nil
+}
+
+// RestoreResult reports the outcome of Restore.
+type RestoreResult struct {
+	cloudflare.WorkersKVBulkResult
+	// Expired is the number of entries skipped because they have expired,
+	// or would expire before the API accepts them.
+	Expired int
+}
+
+// minExpirationTTL is the shortest time to live the API accepts.
+const minExpirationTTL = 60 * time.Second
+
+// Restore writes every entry read from r to a namespace with bulk writes,
+// overwriting keys that already exist. r is not closed.
+func Restore(ctx context.Context, api *cloudflare.API, namespaceID string, r Reader, opts cloudflare.WorkersKVBulkOptions) (RestoreResult, error) {
+	var result RestoreResult
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		enc := json.NewEncoder(pw)
+		for {
+			e, err := r.Read()
+			if err == io.EOF {
+				pw.Close()
+				return
+			}
+			if err != nil {
+				pw.CloseWithError(errors.Wrap(err, "failed to read archive"))
+				return
+			}
+
+			if e.Expiration != 0 && time.Unix(int64(e.Expiration), 0).Before(time.Now().Add(minExpirationTTL)) {
+				result.Expired++
+				continue
+			}
+			if err := enc.Encode(pair(e)); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	res, err := api.WriteWorkersKVBulkNDJSON(ctx, namespaceID, pr, opts)
+	// Stop reading the archive if writing ended early.
+	pr.Close()
+	<-done
+	result.WorkersKVBulkResult = res
+	return result, err
+}
+
+// pair returns the bulk write form of an entry, base64 encoding the value
+// so binary values survive.
+func pair(e Entry) *cloudflare.WorkersKVPair {
+	return &cloudflare.WorkersKVPair{
+		Key:        e.Key,
+		Value:      encodeValue(e.Value),
+		Expiration: e.Expiration,
+		Metadata:   e.Metadata,
+		Base64:     true,
+	}
+}
@@ -0,0 +1,206 @@
+package kvbackup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAccountID = "01a7362d577a6c3019a474fd6f485823"
+
+func newTestAPI(t *testing.T, mux *http.ServeMux) *cloudflare.API {
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	api, err := cloudflare.New("deadbeef", "cloudflare@example.org",
+		cloudflare.BaseURL(server.URL),
+		cloudflare.UsingAccount(testAccountID),
+		cloudflare.UsingRateLimit(100000),
+		cloudflare.UsingRetryPolicy(0, 0, 0))
+	require.NoError(t, err)
+	return api
+}
+
+// fakeNamespace serves the list, read and bulk write endpoints of a single
+// KV namespace, returning one key per list page.
+type fakeNamespace struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+func (f *fakeNamespace) register(mux *http.ServeMux, id string) {
+	prefix := "/accounts/" + testAccountID + "/storage/kv/namespaces/" + id
+	mux.HandleFunc(prefix+"/keys", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		var keys []string
+		for key := range f.entries {
+			if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		i := sort.SearchStrings(keys, r.URL.Query().Get("cursor"))
+		var page []cloudflare.StorageKey
+		cursor := ""
+		if i < len(keys) {
+			e := f.entries[keys[i]]
+			page = append(page, cloudflare.StorageKey{Name: e.Key, Expiration: e.Expiration, Metadata: e.Metadata})
+			if i+1 < len(keys) {
+				cursor = keys[i+1]
+			}
+		}
+		writeJSON(w, map[string]interface{}{
+			"success": true, "errors": []string{}, "messages": []string{},
+			"result":      page,
+			"result_info": map[string]interface{}{"count": len(page), "cursor": cursor},
+		})
+	})
+	mux.HandleFunc(prefix+"/values/", func(w http.ResponseWriter, r *http.Request) {
+		key, _ := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), prefix+"/values/"))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		w.Write(f.entries[key].Value) //nolint
+	})
+	mux.HandleFunc(prefix+"/bulk", func(w http.ResponseWriter, r *http.Request) {
+		var pairs []cloudflare.WorkersKVPair
+		if err := json.NewDecoder(r.Body).Decode(&pairs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for _, p := range pairs {
+			e, err := fromPair(p)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.entries[e.Key] = e
+		}
+		writeJSON(w, map[string]interface{}{"success": true, "errors": []string{}, "messages": []string{}, "result": nil})
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("content-type", "application/json")
+	json.NewEncoder(w).Encode(v) //nolint
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	future := int(time.Now().Add(24 * time.Hour).Unix())
+	source := &fakeNamespace{entries: map[string]Entry{
+		"config/flags": {Key: "config/flags", Value: []byte(`{"beta":true}`), Metadata: map[string]interface{}{"owner": "ops"}},
+		"session:1":    {Key: "session:1", Value: []byte{0, 1, 2, 0xff}, Expiration: future},
+		"session:2":    {Key: "session:2", Value: []byte("expired"), Expiration: 1},
+		"100%":         {Key: "100%", Value: []byte("percent")},
+	}}
+	target := &fakeNamespace{entries: make(map[string]Entry)}
+
+	mux := http.NewServeMux()
+	source.register(mux, "source")
+	target.register(mux, "target")
+	api := newTestAPI(t, mux)
+	ctx := context.Background()
+
+	for _, name := range []string{"backup", "backup.tar", "backup.ndjson"} {
+		t.Run(name, func(t *testing.T) {
+			target.entries = make(map[string]Entry)
+			path := filepath.Join(t.TempDir(), name)
+
+			w, err := Create(path)
+			require.NoError(t, err)
+			n, err := Backup(ctx, api, "source", w, BackupOptions{Concurrency: 2})
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			assert.Equal(t, 4, n)
+
+			r, err := Open(path)
+			require.NoError(t, err)
+			defer r.Close()
+			res, err := Restore(ctx, api, "target", r, cloudflare.WorkersKVBulkOptions{MaxPairs: 2})
+			require.NoError(t, err)
+			assert.Equal(t, 3, res.Succeeded)
+			assert.Empty(t, res.Failed)
+			assert.Equal(t, 1, res.Expired)
+
+			want := make(map[string]Entry)
+			for key, e := range source.entries {
+				if key != "session:2" {
+					want[key] = e
+				}
+			}
+			assert.Equal(t, want, target.entries)
+		})
+	}
+}
+
+func TestBackup_Prefix(t *testing.T) {
+	source := &fakeNamespace{entries: map[string]Entry{
+		"a:1": {Key: "a:1", Value: []byte("1")},
+		"b:1": {Key: "b:1", Value: []byte("2")},
+	}}
+	mux := http.NewServeMux()
+	source.register(mux, "source")
+
+	var b strings.Builder
+	n, err := Backup(context.Background(), newTestAPI(t, mux), "source", NewNDJSONWriter(&b), BackupOptions{Prefix: "b:"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, `{"key":"b:1","value":"Mg==","base64":true}`+"\n", b.String())
+}
+
+func TestDirWriter_FileNames(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewDirWriter(dir)
+	require.NoError(t, err)
+
+	keys := []string{"a/b", "A/B", "..", strings.Repeat("k", 300), "x%2Fy"}
+	for i, key := range keys {
+		require.NoError(t, w.Write(Entry{Key: key, Value: []byte(fmt.Sprint(i))}))
+	}
+	require.NoError(t, w.Close())
+
+	r, err := NewDirReader(dir)
+	require.NoError(t, err)
+	defer r.Close()
+
+	for i, key := range keys {
+		e, err := r.Read()
+		require.NoError(t, err)
+		assert.Equal(t, Entry{Key: key, Value: []byte(fmt.Sprint(i))}, e)
+	}
+	_, err = r.Read()
+	assert.Equal(t, io.EOF, err)
+
+	assert.Len(t, fileName(".."), 64)
+	matches, err := filepath.Glob(filepath.Join(dir, "values", "*"))
+	require.NoError(t, err)
+	var files []string
+	for _, m := range matches {
+		files = append(files, filepath.Base(m))
+	}
+	assert.ElementsMatch(t, []string{
+		"a%2Fb",
+		"A%2FB~1",
+		fileName(".."),
+		fileName(strings.Repeat("k", 300)),
+		"x%252Fy",
+	}, files)
+}
@@ -0,0 +1,369 @@
+package kvbackup
+
+import (
+	"archive/tar"
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
+)
+
+func encodeValue(value []byte) string {
+	return base64.StdEncoding.EncodeToString(value)
+}
+
+// fromPair converts a bulk write pair back into an entry.
+func fromPair(p cloudflare.WorkersKVPair) (Entry, error) {
+	e := Entry{Key: p.Key, Value: []byte(p.Value), Expiration: p.Expiration, Metadata: p.Metadata}
+	if p.Base64 {
+		value, err := base64.StdEncoding.DecodeString(p.Value)
+		if err != nil {
+			return Entry{}, errors.Wrapf(err, "failed to decode value of %q", p.Key)
+		}
+		e.Value = value
+	}
+	return e, nil
+}
+
+// fileName returns the name a key's value is stored under in directory and
+// tar archives. Keys that can't be used as a file name are hashed.
+func fileName(key string) string {
+	name := escapeFileName(key)
+	if name == "." || name == ".." || len(name) > 200 {
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	}
+	return name
+}
+
+// escapeFileName percent-encodes percent signs and the bytes that are
+// unsafe in file names on common file systems.
+func escapeFileName(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c < 0x20 || c == 0x7f || strings.IndexByte(`%/\:*?"<>|`, c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+// NewNDJSONWriter returns a Writer that writes one JSON encoded
+// cloudflare.WorkersKVPair per line to w, with base64 encoded values.
+// Closing it does not close w.
+func NewNDJSONWriter(w io.Writer) Writer {
+	return ndjsonWriter{enc: json.NewEncoder(w)}
+}
+
+func (w ndjsonWriter) Write(e Entry) error {
+	return w.enc.Encode(pair(e))
+}
+
+func (w ndjsonWriter) Close() error {
+	return nil
+}
+
+type ndjsonReader struct {
+	dec *json.Decoder
+}
+
+// NewNDJSONReader returns a Reader of the archives written by
+// NewNDJSONWriter. Values without "base64": true are read as is.
+func NewNDJSONReader(r io.Reader) Reader {
+	return ndjsonReader{dec: json.NewDecoder(r)}
+}
+
+func (r ndjsonReader) Read() (Entry, error) {
+	var p cloudflare.WorkersKVPair
+	if err := r.dec.Decode(&p); err != nil {
+		return Entry{}, err
+	}
+	return fromPair(p)
+}
+
+func (r ndjsonReader) Close() error {
+	return nil
+}
+
+// Tar archives hold one file per key under values/, with the key,
+// expiration and metadata in PAX records.
+const (
+	paxKey        = "CLOUDFLARE.kv.key"
+	paxExpiration = "CLOUDFLARE.kv.expiration"
+	paxMetadata   = "CLOUDFLARE.kv.metadata"
+)
+
+type tarWriter struct {
+	tw    *tar.Writer
+	names map[string]int
+	now   time.Time
+}
+
+// NewTarWriter returns a Writer that writes a tar archive to w. Closing it
+// writes the tar footer but does not close w.
+func NewTarWriter(w io.Writer) Writer {
+	return &tarWriter{tw: tar.NewWriter(w), names: make(map[string]int), now: time.Now()}
+}
+
+func (w *tarWriter) Write(e Entry) error {
+	records := map[string]string{paxKey: e.Key}
+	if e.Expiration != 0 {
+		records[paxExpiration] = strconv.Itoa(e.Expiration)
+	}
+	if e.Metadata != nil {
+		b, err := json.Marshal(e.Metadata)
+		if err != nil {
+			return err
+		}
+		records[paxMetadata] = string(b)
+	}
+
+	if err := w.tw.WriteHeader(&tar.Header{
+		Typeflag:   tar.TypeReg,
+		Name:       "values/" + uniqueName(w.names, fileName(e.Key)),
+		Size:       int64(len(e.Value)),
+		Mode:       0o644,
+		ModTime:    w.now,
+		PAXRecords: records,
+		Format:     tar.FormatPAX,
+	}); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(e.Value)
+	return err
+}
+
+func (w *tarWriter) Close() error {
+	return w.tw.Close()
+}
+
+type tarReader struct {
+	tr *tar.Reader
+}
+
+// NewTarReader returns a Reader of the archives written by NewTarWriter.
+func NewTarReader(r io.Reader) Reader {
+	return tarReader{tr: tar.NewReader(r)}
+}
+
+func (r tarReader) Read() (Entry, error) {
+	for {
+		hdr, err := r.tr.Next()
+		if err != nil {
+			return Entry{}, err
+		}
+		key, ok := hdr.PAXRecords[paxKey]
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		e := Entry{Key: key}
+		if s, ok := hdr.PAXRecords[paxExpiration]; ok {
+			if e.Expiration, err = strconv.Atoi(s); err != nil {
+				return Entry{}, errors.Wrapf(err, "invalid expiration of %q", key)
+			}
+		}
+		if s, ok := hdr.PAXRecords[paxMetadata]; ok {
+			if err := json.Unmarshal([]byte(s), &e.Metadata); err != nil {
+				return Entry{}, errors.Wrapf(err, "invalid metadata of %q", key)
+			}
+		}
+		if e.Value, err = ioutil.ReadAll(r.tr); err != nil {
+			return Entry{}, err
+		}
+		return e, nil
+	}
+}
+
+func (r tarReader) Close() error {
+	return nil
+}
+
+// dirIndexEntry is a line of the index of a directory archive.
+type dirIndexEntry struct {
+	Key        string      `json:"key"`
+	File       string      `json:"file"`
+	Expiration int         `json:"expiration,omitempty"`
+	Metadata   interface{} `json:"metadata,omitempty"`
+}
+
+const dirIndexName = "keys.ndjson"
+
+type dirWriter struct {
+	dir   string
+	index *os.File
+	buf   *bufio.Writer
+	enc   *json.Encoder
+	names map[string]int
+}
+
+// NewDirWriter returns a Writer that stores each value in its own file
+// under dir/values, and the keys, expirations and metadata in
+// dir/keys.ndjson. dir is created if needed.
+func NewDirWriter(dir string) (Writer, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "values"), 0o755); err != nil {
+		return nil, err
+	}
+	index, err := os.Create(filepath.Join(dir, dirIndexName))
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(index)
+	return &dirWriter{dir: dir, index: index, buf: buf, enc: json.NewEncoder(buf), names: make(map[string]int)}, nil
+}
+
+func (w *dirWriter) Write(e Entry) error {
+	name := uniqueName(w.names, fileName(e.Key))
+	if err := ioutil.WriteFile(filepath.Join(w.dir, "values", name), e.Value, 0o644); err != nil {
+		return err
+	}
+	return w.enc.Encode(dirIndexEntry{Key: e.Key, File: name, Expiration: e.Expiration, Metadata: e.Metadata})
+}
+
+func (w *dirWriter) Close() error {
+	if err := w.buf.Flush(); err != nil {
+		w.index.Close()
+		return err
+	}
+	return w.index.Close()
+}
+
+type dirReader struct {
+	dir   string
+	index *os.File
+	dec   *json.Decoder
+}
+
+// NewDirReader returns a Reader of the archives written by NewDirWriter.
+func NewDirReader(dir string) (Reader, error) {
+	index, err := os.Open(filepath.Join(dir, dirIndexName))
+	if err != nil {
+		return nil, err
+	}
+	return &dirReader{dir: dir, index: index, dec: json.NewDecoder(bufio.NewReader(index))}, nil
+}
+
+func (r *dirReader) Read() (Entry, error) {
+	var ie dirIndexEntry
+	if err := r.dec.Decode(&ie); err != nil {
+		return Entry{}, err
+	}
+	if ie.File != filepath.Base(ie.File) {
+		return Entry{}, errors.Errorf("invalid file name %q for %q", ie.File, ie.Key)
+	}
+
+	value, err := ioutil.ReadFile(filepath.Join(r.dir, "values", ie.File))
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Key: ie.Key, Value: value, Expiration: ie.Expiration, Metadata: ie.Metadata}, nil
+}
+
+func (r *dirReader) Close() error {
+	return r.index.Close()
+}
+
+// uniqueName returns name, suffixed if a name equal to it ignoring case
+// was returned before, so keys differing only in case don't overwrite each
+// other on case-insensitive file systems.
+func uniqueName(seen map[string]int, name string) string {
+	folded := strings.ToLower(name)
+	n := seen[folded]
+	seen[folded] = n + 1
+	if n == 0 {
+		return name
+	}
+	return uniqueName(seen, fmt.Sprintf("%s~%d", name, n))
+}
+
+// Create returns a Writer for a new archive at path. Paths ending in .tar
+// are tar archives, paths ending in .ndjson or .jsonl are NDJSON archives
+// and anything else is a directory. Closing the Writer closes the file.
+func Create(path string) (Writer, error) {
+	var newWriter func(io.Writer) Writer
+	switch archiveExt(path) {
+	case ".tar":
+		newWriter = NewTarWriter
+	case ".ndjson", ".jsonl":
+		newWriter = NewNDJSONWriter
+	default:
+		return NewDirWriter(path)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(f)
+	return fileWriter{Writer: newWriter(buf), buf: buf, f: f}, nil
+}
+
+// Open returns a Reader of the archive at path, choosing the format like
+// Create. Closing the Reader closes the file.
+func Open(path string) (Reader, error) {
+	var newReader func(io.Reader) Reader
+	switch archiveExt(path) {
+	case ".tar":
+		newReader = NewTarReader
+	case ".ndjson", ".jsonl":
+		newReader = NewNDJSONReader
+	default:
+		return NewDirReader(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return fileReader{Reader: newReader(bufio.NewReader(f)), f: f}, nil
+}
+
+func archiveExt(path string) string {
+	return strings.ToLower(filepath.Ext(path))
+}
+
+type fileWriter struct {
+	Writer
+	buf *bufio.Writer
+	f   *os.File
+}
+
+func (w fileWriter) Close() error {
+	err := w.Writer.Close()
+	if err == nil {
+		err = w.buf.Flush()
+	}
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type fileReader struct {
+	Reader
+	f *os.File
+}
+
+func (r fileReader) Close() error {
+	r.Reader.Close()
+	return r.f.Close()
+}
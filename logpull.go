@@ -1,10 +1,17 @@
 package cloudflare
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -57,3 +64,265 @@ func (api *API) SetLogpullRetentionFlag(ctx context.Context, zoneID string, enab
 	}
 	return &r.Result, nil
 }
+
+// LogpullTimestampFormat is the format of timestamps in Logpull logs.
+type LogpullTimestampFormat string
+
+// LogpullTimestampFormat values.
+const (
+	LogpullTimestampsUnix     LogpullTimestampFormat = "unix"
+	LogpullTimestampsUnixNano LogpullTimestampFormat = "unixnano"
+	LogpullTimestampsRFC3339  LogpullTimestampFormat = "rfc3339"
+)
+
+// LogpullMaxWindow is the longest time range a single Logpull request may
+// cover.
+const LogpullMaxWindow = time.Hour
+
+// ErrLogpullRayIDNotFound is returned by LogpullRayID when there is no log
+// for a Ray ID.
+var ErrLogpullRayIDNotFound = errors.New("no log found for Ray ID")
+
+// LogpullOptions selects the fields of Logpull logs and the format of their
+// timestamps. Without Fields, the API returns a default set of fields.
+type LogpullOptions struct {
+	Fields     []string
+	Timestamps LogpullTimestampFormat
+}
+
+func (o LogpullOptions) values() url.Values {
+	v := url.Values{}
+	if len(o.Fields) > 0 {
+		v.Set("fields", strings.Join(o.Fields, ","))
+	}
+	if o.Timestamps != "" {
+		v.Set("timestamps", string(o.Timestamps))
+	}
+	return v
+}
+
+// LogpullReceivedOptions configures LogpullReceived.
+type LogpullReceivedOptions struct {
+	LogpullOptions
+	// Start is inclusive and End exclusive. Both are truncated to the
+	// second.
+	Start time.Time
+	End   time.Time
+	// Sample is the fraction of logs to return, from 0.001 to 1. Zero
+	// returns all of them.
+	Sample float64
+	// Count limits the number of logs returned over the whole time range.
+	Count int
+	// ChunkSize is the time range of each request, at most and by default
+	// LogpullMaxWindow.
+	ChunkSize time.Duration
+}
+
+// LogpullLog holds the default fields of a Logpull log. Decode into a map
+// to get other fields.
+type LogpullLog struct {
+	ClientIP            string           `json:"ClientIP"`
+	ClientRequestHost   string           `json:"ClientRequestHost"`
+	ClientRequestMethod string           `json:"ClientRequestMethod"`
+	ClientRequestURI    string           `json:"ClientRequestURI"`
+	EdgeEndTimestamp    LogpullTimestamp `json:"EdgeEndTimestamp"`
+	EdgeResponseBytes   int64            `json:"EdgeResponseBytes"`
+	EdgeResponseStatus  int              `json:"EdgeResponseStatus"`
+	EdgeStartTimestamp  LogpullTimestamp `json:"EdgeStartTimestamp"`
+	RayID               string           `json:"RayID"`
+}
+
+// LogpullTimestamp is a Logpull timestamp in any LogpullTimestampFormat.
+type LogpullTimestamp struct {
+	time.Time
+}
+
+// UnmarshalJSON decodes an RFC 3339 string or a number of seconds or
+// nanoseconds since the Unix epoch, told apart by their magnitude.
+func (t *LogpullTimestamp) UnmarshalJSON(b []byte) error {
+	if bytes.HasPrefix(b, []byte(`"`)) {
+		return t.Time.UnmarshalJSON(b)
+	}
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "invalid Logpull timestamp %s", b)
+	}
+	// Nanosecond timestamps passed 1e12 in 1970, second ones won't until
+	// the year 33658.
+	if n > 1e12 || n < -1e12 {
+		t.Time = time.Unix(0, n).UTC()
+	} else {
+		t.Time = time.Unix(n, 0).UTC()
+	}
+	return nil
+}
+
+// LogpullIterator reads the logs of a time range one at a time, requesting
+// each chunk of the range in turn. It must be closed.
+type LogpullIterator struct {
+	api    *API
+	ctx    context.Context
+	zoneID string
+	opts   LogpullReceivedOptions
+
+	next      time.Time
+	remaining int
+	body      io.ReadCloser
+	reader    *bufio.Reader
+	line      []byte
+	err       error
+}
+
+// LogpullReceived returns an iterator over the logs of the requests a zone
+// received between o.Start and o.End. The range is split into requests of
+// at most LogpullMaxWindow, which are only made as the iterator advances.
+//
+// API reference: https://developers.cloudflare.com/logs/logpull/requesting-logs/
+func (api *API) LogpullReceived(ctx context.Context, zoneID string, o LogpullReceivedOptions) *LogpullIterator {
+	it := &LogpullIterator{
+		api:       api,
+		ctx:       ctx,
+		zoneID:    zoneID,
+		opts:      o,
+		next:      o.Start.Truncate(time.Second),
+		remaining: o.Count,
+	}
+	it.opts.End = o.End.Truncate(time.Second)
+	if it.opts.ChunkSize <= 0 || it.opts.ChunkSize > LogpullMaxWindow {
+		it.opts.ChunkSize = LogpullMaxWindow
+	}
+	it.opts.ChunkSize = it.opts.ChunkSize.Truncate(time.Second)
+
+	switch {
+	case !it.opts.End.After(it.next):
+		it.err = errors.New("end must be after start")
+	case o.Sample != 0 && (o.Sample < 0.001 || o.Sample > 1):
+		it.err = errors.New("sample must be between 0.001 and 1")
+	case o.Count < 0:
+		it.err = errors.New("count must not be negative")
+	case it.opts.ChunkSize == 0:
+		it.err = errors.New("chunk size must be at least a second")
+	}
+	return it
+}
+
+// Next advances to the next log, returning false when there are no more or
+// on error.
+func (it *LogpullIterator) Next() bool {
+	for it.err == nil {
+		if it.body == nil {
+			if !it.next.Before(it.opts.End) || (it.opts.Count > 0 && it.remaining == 0) {
+				return false
+			}
+			it.err = it.open()
+			continue
+		}
+
+		line, err := it.reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			it.line = line
+			it.remaining--
+			return true
+		}
+		if err == io.EOF {
+			it.body.Close()
+			it.body = nil
+		} else if err != nil {
+			it.err = errors.Wrap(err, "failed to read logs")
+		}
+	}
+	return false
+}
+
+// open requests the next chunk of the time range.
+func (it *LogpullIterator) open() error {
+	end := it.next.Add(it.opts.ChunkSize)
+	if end.After(it.opts.End) {
+		end = it.opts.End
+	}
+
+	v := it.opts.values()
+	v.Set("start", strconv.FormatInt(it.next.Unix(), 10))
+	v.Set("end", strconv.FormatInt(end.Unix(), 10))
+	if it.opts.Sample != 0 {
+		v.Set("sample", strconv.FormatFloat(it.opts.Sample, 'f', -1, 64))
+	}
+	if it.opts.Count > 0 {
+		v.Set("count", strconv.Itoa(it.remaining))
+	}
+
+	uri := fmt.Sprintf("/zones/%s/logs/received?%s", it.zoneID, v.Encode())
+	body, err := it.api.makeStreamingRequestContext(it.ctx, http.MethodGet, uri, nil, nil)
+	if err != nil {
+		return err
+	}
+	it.body, it.reader, it.next = body, bufio.NewReader(body), end
+	return nil
+}
+
+// Bytes returns the current log as JSON. It is only valid until the next
+// call to Next.
+func (it *LogpullIterator) Bytes() []byte {
+	return it.line
+}
+
+// Decode unmarshals the current log into v, such as a *LogpullLog or a
+// *map[string]interface{}.
+func (it *LogpullIterator) Decode(v interface{}) error {
+	if err := json.Unmarshal(it.line, v); err != nil {
+		return errors.Wrap(err, errUnmarshalError)
+	}
+	return nil
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *LogpullIterator) Err() error {
+	return it.err
+}
+
+// Close releases the response being read, if any.
+func (it *LogpullIterator) Close() error {
+	if it.body == nil {
+		return nil
+	}
+	err := it.body.Close()
+	it.body = nil
+	return err
+}
+
+// LogpullRayID returns the log of the request with a Ray ID as JSON, or
+// ErrLogpullRayIDNotFound.
+//
+// API reference: https://developers.cloudflare.com/logs/logpull/requesting-logs/
+func (api *API) LogpullRayID(ctx context.Context, zoneID, rayID string, o LogpullOptions) (json.RawMessage, error) {
+	uri := fmt.Sprintf("/zones/%s/logs/rayids/%s", zoneID, url.PathEscape(rayID))
+	if v := o.values(); len(v) > 0 {
+		uri += "?" + v.Encode()
+	}
+	res, err := api.makeRequestContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	res = bytes.TrimSpace(res)
+	if len(res) == 0 {
+		return nil, ErrLogpullRayIDNotFound
+	}
+	return json.RawMessage(res), nil
+}
+
+// LogpullFields returns the fields available in Logpull logs and their
+// descriptions.
+//
+// API reference: https://developers.cloudflare.com/logs/logpull/requesting-logs/
+func (api *API) LogpullFields(ctx context.Context, zoneID string) (map[string]string, error) {
+	uri := fmt.Sprintf("/zones/%s/logs/received/fields", zoneID)
+	res, err := api.makeRequestContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]string
+	if err := json.Unmarshal(res, &fields); err != nil {
+		return nil, errors.Wrap(err, errUnmarshalError)
+	}
+	return fields, nil
+}
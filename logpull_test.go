@@ -2,11 +2,15 @@ package cloudflare
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetLogpullRetentionFlag(t *testing.T) {
@@ -60,3 +64,132 @@ func TestSetLogpullRetentionFlag(t *testing.T) {
 		assert.Equal(t, want, actual)
 	}
 }
+
+func TestLogpullReceived(t *testing.T) {
+	setup()
+	defer teardown()
+
+	start := time.Date(2021, time.October, 1, 10, 0, 0, 0, time.UTC)
+	var windows [][2]int64
+	mux.HandleFunc("/zones/"+testZoneID+"/logs/received", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		q := r.URL.Query()
+		assert.Equal(t, "ClientIP,RayID,EdgeStartTimestamp", q.Get("fields"))
+		assert.Equal(t, "unixnano", q.Get("timestamps"))
+		assert.Equal(t, "0.5", q.Get("sample"))
+		from, _ := strconv.ParseInt(q.Get("start"), 10, 64)
+		to, _ := strconv.ParseInt(q.Get("end"), 10, 64)
+		windows = append(windows, [2]int64{from, to})
+
+		count, _ := strconv.Atoi(q.Get("count"))
+		w.Header().Set("content-type", "application/json")
+		for i := 0; i < 2 && i < count; i++ {
+			fmt.Fprintf(w, `{"ClientIP":"192.0.2.1","RayID":"%d-%d","EdgeStartTimestamp":%d}`+"\n", from, i, from*int64(time.Second))
+		}
+	})
+
+	it := client.LogpullReceived(context.Background(), testZoneID, LogpullReceivedOptions{
+		LogpullOptions: LogpullOptions{
+			Fields:     []string{"ClientIP", "RayID", "EdgeStartTimestamp"},
+			Timestamps: LogpullTimestampsUnixNano,
+		},
+		Start:  start,
+		End:    start.Add(150 * time.Minute),
+		Sample: 0.5,
+		Count:  5,
+	})
+	defer it.Close()
+
+	var logs []LogpullLog
+	for it.Next() {
+		var log LogpullLog
+		require.NoError(t, it.Decode(&log))
+		logs = append(logs, log)
+	}
+	require.NoError(t, it.Err())
+
+	assert.Equal(t, [][2]int64{
+		{start.Unix(), start.Add(time.Hour).Unix()},
+		{start.Add(time.Hour).Unix(), start.Add(2 * time.Hour).Unix()},
+		{start.Add(2 * time.Hour).Unix(), start.Add(150 * time.Minute).Unix()},
+	}, windows)
+	require.Len(t, logs, 5)
+	assert.Equal(t, fmt.Sprintf("%d-0", start.Unix()), logs[0].RayID)
+	assert.Equal(t, "192.0.2.1", logs[0].ClientIP)
+	assert.True(t, start.Equal(logs[0].EdgeStartTimestamp.Time))
+	assert.True(t, start.Add(2*time.Hour).Equal(logs[4].EdgeStartTimestamp.Time))
+
+	it = client.LogpullReceived(context.Background(), testZoneID, LogpullReceivedOptions{Start: start, End: start})
+	assert.False(t, it.Next())
+	assert.EqualError(t, it.Err(), "end must be after start")
+}
+
+func TestLogpullReceived_Error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/zones/"+testZoneID+"/logs/received", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"success": false, "errors": [{"code": 1004, "message": "too early: logs older than 168h0m0s are not available"}], "messages": [], "result": null}`)
+	})
+
+	start := time.Now().Add(-30 * 24 * time.Hour)
+	it := client.LogpullReceived(context.Background(), testZoneID, LogpullReceivedOptions{Start: start, End: start.Add(time.Minute)})
+	assert.False(t, it.Next())
+	assert.Error(t, it.Err())
+	assert.NoError(t, it.Close())
+}
+
+func TestLogpullRayID(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/zones/"+testZoneID+"/logs/rayids/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		assert.Equal(t, "rfc3339", r.URL.Query().Get("timestamps"))
+		w.Header().Set("content-type", "application/json")
+		if r.URL.Path == "/zones/"+testZoneID+"/logs/rayids/41ddf1740f67442d" {
+			fmt.Fprint(w, `{"RayID":"41ddf1740f67442d","EdgeResponseStatus":200,"EdgeStartTimestamp":"2021-10-01T10:00:00Z","WAFAction":"unknown"}`+"\n")
+		}
+	})
+
+	res, err := client.LogpullRayID(context.Background(), testZoneID, "41ddf1740f67442d", LogpullOptions{Timestamps: LogpullTimestampsRFC3339})
+	require.NoError(t, err)
+
+	var log LogpullLog
+	require.NoError(t, json.Unmarshal(res, &log))
+	assert.Equal(t, 200, log.EdgeResponseStatus)
+	assert.Equal(t, time.Date(2021, time.October, 1, 10, 0, 0, 0, time.UTC), log.EdgeStartTimestamp.Time)
+
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(res, &fields))
+	assert.Equal(t, "unknown", fields["WAFAction"])
+
+	_, err = client.LogpullRayID(context.Background(), testZoneID, "0000000000000000", LogpullOptions{Timestamps: LogpullTimestampsRFC3339})
+	assert.Equal(t, ErrLogpullRayIDNotFound, err)
+}
+
+func TestLogpullFields(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/zones/"+testZoneID+"/logs/received/fields", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"ClientIP": "IP address of the client", "RayID": "ID of the request"}`)
+	})
+
+	fields, err := client.LogpullFields(context.Background(), testZoneID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ClientIP": "IP address of the client", "RayID": "ID of the request"}, fields)
+}
+
+func TestLogpullTimestamp(t *testing.T) {
+	want := time.Date(2021, time.October, 1, 10, 0, 0, 0, time.UTC)
+	for _, input := range []string{`1633082400`, `1633082400000000000`, `"2021-10-01T10:00:00Z"`} {
+		var ts LogpullTimestamp
+		require.NoError(t, ts.UnmarshalJSON([]byte(input)), input)
+		assert.True(t, want.Equal(ts.Time), input)
+	}
+}
@@ -0,0 +1,184 @@
+package cloudflare
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PurgeCacheMaxItems is the number of files, tags, hosts or prefixes the
+// API accepts in a single purge request on every plan.
+const PurgeCacheMaxItems = 30
+
+// PurgeCacheFile is a URL to purge from the cache. Headers are only needed
+// for variants whose cache key includes request headers, such as
+// CF-Device-Type or Accept-Language.
+type PurgeCacheFile struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// MarshalJSON encodes a file without headers as a plain URL string, which
+// is the form the API documents for the common case.
+func (f PurgeCacheFile) MarshalJSON() ([]byte, error) {
+	if len(f.Headers) == 0 {
+		return json.Marshal(f.URL)
+	}
+	type file PurgeCacheFile
+	return json.Marshal(file(f))
+}
+
+// UnmarshalJSON accepts either a URL string or an object with url and
+// headers.
+func (f *PurgeCacheFile) UnmarshalJSON(data []byte) error {
+	var url string
+	if err := json.Unmarshal(data, &url); err == nil {
+		*f = PurgeCacheFile{URL: url}
+		return nil
+	}
+	type file PurgeCacheFile
+	return json.Unmarshal(data, (*file)(f))
+}
+
+// PurgeCacheBatchRequest is a purge request of any size. PurgeCacheBatch
+// splits it into as many API requests as needed.
+type PurgeCacheBatchRequest struct {
+	Files    []PurgeCacheFile `json:"files,omitempty"`
+	Tags     []string         `json:"tags,omitempty"`
+	Hosts    []string         `json:"hosts,omitempty"`
+	Prefixes []string         `json:"prefixes,omitempty"`
+}
+
+// PurgeCacheBatchOptions configures PurgeCacheBatch.
+type PurgeCacheBatchOptions struct {
+	// ChunkSize is the number of items sent per request. It defaults to
+	// PurgeCacheMaxItems; a larger size is only accepted by the API on some
+	// plans.
+	ChunkSize int
+
+	// StopOnError stops at the first failed request instead of attempting
+	// the remaining chunks.
+	StopOnError bool
+}
+
+// PurgeCacheChunkResult is the outcome of a single purge request sent by
+// PurgeCacheBatch. Chunk holds only the items sent in that request.
+type PurgeCacheChunkResult struct {
+	Chunk PurgeCacheBatchRequest
+	ID    string
+	Err   error
+}
+
+// PurgeCacheBatch purges every file, tag, host and prefix in batch, sending
+// one request per chunk of at most opts.ChunkSize items of a single kind.
+// Requests go through the client's rate limiter like any other call.
+//
+// The result of every chunk attempted is returned. The error is non-nil if
+// any chunk failed or the context was cancelled.
+func (api *API) PurgeCacheBatch(ctx context.Context, zoneID string, batch PurgeCacheBatchRequest, opts PurgeCacheBatchOptions) ([]PurgeCacheChunkResult, error) {
+	size := opts.ChunkSize
+	if size <= 0 {
+		size = PurgeCacheMaxItems
+	}
+
+	chunks := batch.chunks(size)
+	results := make([]PurgeCacheChunkResult, 0, len(chunks))
+	failed := 0
+	for _, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		res := PurgeCacheChunkResult{Chunk: chunk}
+		res.ID, res.Err = api.purgeCacheChunk(ctx, zoneID, chunk)
+		results = append(results, res)
+
+		if res.Err != nil {
+			failed++
+			if opts.StopOnError {
+				break
+			}
+		}
+	}
+
+	if failed > 0 {
+		return results, errors.Errorf("%d of %d purge requests failed", failed, len(chunks))
+	}
+	return results, nil
+}
+
+func (api *API) purgeCacheChunk(ctx context.Context, zoneID string, chunk PurgeCacheBatchRequest) (string, error) {
+	uri := fmt.Sprintf("/zones/%s/purge_cache", zoneID)
+	res, err := api.makeRequestContext(ctx, http.MethodPost, uri, chunk)
+	if err != nil {
+		return "", err
+	}
+	var r PurgeCacheResponse
+	if err := json.Unmarshal(res, &r); err != nil {
+		return "", errors.Wrap(err, errUnmarshalError)
+	}
+	return r.Result.ID, nil
+}
+
+// chunks splits the batch into requests holding at most size items of a
+// single kind.
+func (b PurgeCacheBatchRequest) chunks(size int) []PurgeCacheBatchRequest {
+	var chunks []PurgeCacheBatchRequest
+	for i := 0; i < len(b.Files); i += size {
+		chunks = append(chunks, PurgeCacheBatchRequest{Files: b.Files[i:minInt(i+size, len(b.Files))]})
+	}
+	for i := 0; i < len(b.Tags); i += size {
+		chunks = append(chunks, PurgeCacheBatchRequest{Tags: b.Tags[i:minInt(i+size, len(b.Tags))]})
+	}
+	for i := 0; i < len(b.Hosts); i += size {
+		chunks = append(chunks, PurgeCacheBatchRequest{Hosts: b.Hosts[i:minInt(i+size, len(b.Hosts))]})
+	}
+	for i := 0; i < len(b.Prefixes); i += size {
+		chunks = append(chunks, PurgeCacheBatchRequest{Prefixes: b.Prefixes[i:minInt(i+size, len(b.Prefixes))]})
+	}
+	return chunks
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ReadPurgeCacheFiles reads files to purge, one per line. A line is either a
+// URL or a JSON object such as
+//
+//	{"url": "https://example.com/", "headers": {"CF-Device-Type": "mobile"}}
+//
+// Blank lines and lines starting with # are skipped.
+func ReadPurgeCacheFiles(r io.Reader) ([]PurgeCacheFile, error) {
+	var files []PurgeCacheFile
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var f PurgeCacheFile
+		if strings.HasPrefix(line, "{") {
+			if err := json.Unmarshal([]byte(line), &f); err != nil {
+				return nil, errors.Wrapf(err, "line %d", n)
+			}
+			if f.URL == "" {
+				return nil, errors.Errorf("line %d: missing url", n)
+			}
+		} else {
+			f.URL = line
+		}
+		files = append(files, f)
+	}
+	return files, scanner.Err()
+}
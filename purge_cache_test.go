@@ -0,0 +1,129 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeCacheFile_MarshalJSON(t *testing.T) {
+	out, err := json.Marshal([]PurgeCacheFile{
+		{URL: "https://example.com/a"},
+		{URL: "https://example.com/b", Headers: map[string]string{"CF-Device-Type": "mobile"}},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `["https://example.com/a", {"url": "https://example.com/b", "headers": {"CF-Device-Type": "mobile"}}]`, string(out))
+
+	var files []PurgeCacheFile
+	require.NoError(t, json.Unmarshal(out, &files))
+	assert.Equal(t, "https://example.com/a", files[0].URL)
+	assert.Equal(t, "mobile", files[1].Headers["CF-Device-Type"])
+}
+
+func TestPurgeCacheBatch(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var requests []PurgeCacheBatchRequest
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method, "Expected method 'POST', got %s", r.Method)
+
+		var req PurgeCacheBatchRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+
+		w.Header().Set("content-type", "application/json")
+		if len(req.Tags) > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"success": false, "errors": [{"code": 1134, "message": "Only enterprise zones can purge by tag"}], "messages": [], "result": null}`)
+			return
+		}
+		fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": {"id": "purge-%d"}}`, len(requests))
+	}
+	mux.HandleFunc("/zones/"+testZoneID+"/purge_cache", handler)
+
+	batch := PurgeCacheBatchRequest{Tags: []string{"a", "b"}, Hosts: []string{"static.example.com"}}
+	for i := 0; i < 65; i++ {
+		batch.Files = append(batch.Files, PurgeCacheFile{URL: fmt.Sprintf("https://example.com/%d", i)})
+	}
+
+	results, err := client.PurgeCacheBatch(context.Background(), testZoneID, batch, PurgeCacheBatchOptions{})
+	assert.EqualError(t, err, "1 of 5 purge requests failed")
+	require.Len(t, results, 5)
+
+	assert.Len(t, results[0].Chunk.Files, PurgeCacheMaxItems)
+	assert.Len(t, results[1].Chunk.Files, PurgeCacheMaxItems)
+	assert.Len(t, results[2].Chunk.Files, 5)
+	assert.Equal(t, "purge-3", results[2].ID)
+	assert.Equal(t, []string{"a", "b"}, results[3].Chunk.Tags)
+	assert.Error(t, results[3].Err)
+	assert.Equal(t, []string{"static.example.com"}, results[4].Chunk.Hosts)
+	assert.NoError(t, results[4].Err)
+
+	assert.Equal(t, "https://example.com/64", requests[2].Files[4].URL)
+}
+
+func TestPurgeCacheBatch_ChunkSize(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var sizes []int
+	mux.HandleFunc("/zones/"+testZoneID+"/purge_cache", func(w http.ResponseWriter, r *http.Request) {
+		var req PurgeCacheBatchRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		sizes = append(sizes, len(req.Files))
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": {"id": "purge"}}`)
+	})
+
+	var batch PurgeCacheBatchRequest
+	for i := 0; i < 150; i++ {
+		batch.Files = append(batch.Files, PurgeCacheFile{URL: fmt.Sprintf("https://example.com/%d", i)})
+	}
+	_, err := client.PurgeCacheBatch(context.Background(), testZoneID, batch, PurgeCacheBatchOptions{ChunkSize: 100})
+	require.NoError(t, err)
+	assert.Equal(t, []int{100, 50}, sizes)
+}
+
+func TestPurgeCacheBatch_StopOnError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc("/zones/"+testZoneID+"/purge_cache", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"success": false, "errors": [{"code": 1000, "message": "bad request"}], "messages": [], "result": null}`)
+	})
+
+	results, err := client.PurgeCacheBatch(context.Background(), testZoneID, PurgeCacheBatchRequest{
+		Prefixes: []string{"example.com/a", "example.com/b", "example.com/c"},
+	}, PurgeCacheBatchOptions{ChunkSize: 1, StopOnError: true})
+	assert.EqualError(t, err, "1 of 3 purge requests failed")
+	assert.Len(t, results, 1)
+	assert.Equal(t, 1, calls)
+}
+
+func TestReadPurgeCacheFiles(t *testing.T) {
+	input := `# deployed assets
+https://example.com/app.js
+
+{"url": "https://example.com/", "headers": {"Accept-Language": "en"}}
+`
+	files, err := ReadPurgeCacheFiles(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, []PurgeCacheFile{
+		{URL: "https://example.com/app.js"},
+		{URL: "https://example.com/", Headers: map[string]string{"Accept-Language": "en"}},
+	}, files)
+
+	_, err = ReadPurgeCacheFiles(strings.NewReader(`{"headers": {}}`))
+	assert.EqualError(t, err, "line 1: missing url")
+}
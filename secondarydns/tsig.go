@@ -0,0 +1,349 @@
+package secondarydns
+
+import (
+	"crypto/hmac"
+	"crypto/md5"  //nolint:gosec
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"hash"
+	"strings"
+	"time"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// typeTSIG is the RR type of a transaction signature (RFC 8945).
+const typeTSIG dnsmessage.Type = 250
+
+// classANY is the class TSIG records are sent with.
+const classANY dnsmessage.Class = 255
+
+// tsigFudge is the permitted clock skew, in seconds, between us and the
+// primary.
+const tsigFudge = 300
+
+var tsigAlgorithms = map[string]func() hash.Hash{
+	"hmac-md5.sig-alg.reg.int.": md5.New,
+	"hmac-sha1.":                sha1.New,
+	"hmac-sha224.":              sha256.New224,
+	"hmac-sha256.":              sha256.New,
+	"hmac-sha384.":              sha512.New384,
+	"hmac-sha512.":              sha512.New,
+}
+
+// tsigKey is a parsed SecondaryDNSTSIG ready for signing.
+type tsigKey struct {
+	name      string
+	algorithm string
+	secret    []byte
+	hash      func() hash.Hash
+}
+
+func newTSIGKey(t cloudflare.SecondaryDNSTSIG) (*tsigKey, error) {
+	algo := canonicalName(t.Algo)
+	h, ok := tsigAlgorithms[algo]
+	if !ok {
+		return nil, errors.Errorf("unsupported TSIG algorithm %q", t.Algo)
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(t.Secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "TSIG secret is not valid base64")
+	}
+
+	return &tsigKey{
+		name:      canonicalName(t.Name),
+		algorithm: algo,
+		secret:    secret,
+		hash:      h,
+	}, nil
+}
+
+// tsigRecord is the RDATA of a TSIG resource record.
+type tsigRecord struct {
+	algorithm  string
+	timeSigned uint64
+	fudge      uint16
+	mac        []byte
+	originalID uint16
+	error      uint16
+	other      []byte
+}
+
+func (t *tsigRecord) pack() []byte {
+	b := appendWireName(nil, t.algorithm)
+	b = appendUint48(b, t.timeSigned)
+	b = appendUint16(b, t.fudge)
+	b = appendUint16(b, uint16(len(t.mac)))
+	b = append(b, t.mac...)
+	b = appendUint16(b, t.originalID)
+	b = appendUint16(b, t.error)
+	b = appendUint16(b, uint16(len(t.other)))
+	return append(b, t.other...)
+}
+
+func unpackTSIGRecord(b []byte) (*tsigRecord, error) {
+	t := &tsigRecord{}
+	var off int
+	var err error
+
+	t.algorithm, off, err = readWireName(b, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < off+10 {
+		return nil, errors.New("truncated TSIG record")
+	}
+	t.timeSigned = uint64(binary.BigEndian.Uint16(b[off:]))<<32 | uint64(binary.BigEndian.Uint32(b[off+2:]))
+	t.fudge = binary.BigEndian.Uint16(b[off+6:])
+	macLen := int(binary.BigEndian.Uint16(b[off+8:]))
+	off += 10
+	if len(b) < off+macLen+6 {
+		return nil, errors.New("truncated TSIG record")
+	}
+	t.mac = b[off : off+macLen]
+	off += macLen
+	t.originalID = binary.BigEndian.Uint16(b[off:])
+	t.error = binary.BigEndian.Uint16(b[off+2:])
+	otherLen := int(binary.BigEndian.Uint16(b[off+4:]))
+	off += 6
+	if len(b) < off+otherLen {
+		return nil, errors.New("truncated TSIG record")
+	}
+	t.other = b[off : off+otherLen]
+
+	return t, nil
+}
+
+// variables returns the TSIG variables covered by the MAC. timersOnly is
+// used for all but the first signed message of a multi-message response.
+func (t *tsigRecord) variables(key *tsigKey, timersOnly bool) []byte {
+	var b []byte
+	if !timersOnly {
+		b = appendWireName(b, key.name)
+		b = appendUint16(b, uint16(classANY))
+		b = append(b, 0, 0, 0, 0) // TTL
+		b = appendWireName(b, t.algorithm)
+	}
+	b = appendUint48(b, t.timeSigned)
+	b = appendUint16(b, t.fudge)
+	if !timersOnly {
+		b = appendUint16(b, t.error)
+		b = appendUint16(b, uint16(len(t.other)))
+		b = append(b, t.other...)
+	}
+	return b
+}
+
+// sign appends a TSIG record to the packed query msg and returns the signed
+// message along with its MAC, which is needed to verify the response.
+func (k *tsigKey) sign(msg []byte, now time.Time) ([]byte, []byte, error) {
+	if len(msg) < 12 {
+		return nil, nil, errors.New("DNS message too short to sign")
+	}
+
+	t := &tsigRecord{
+		algorithm:  k.algorithm,
+		timeSigned: uint64(now.Unix()),
+		fudge:      tsigFudge,
+		originalID: binary.BigEndian.Uint16(msg[0:2]),
+	}
+
+	mac := hmac.New(k.hash, k.secret)
+	mac.Write(msg)
+	mac.Write(t.variables(k, false))
+	t.mac = mac.Sum(nil)
+
+	signed := make([]byte, len(msg), len(msg)+256)
+	copy(signed, msg)
+	signed = appendWireName(signed, k.name)
+	signed = appendUint16(signed, uint16(typeTSIG))
+	signed = appendUint16(signed, uint16(classANY))
+	signed = append(signed, 0, 0, 0, 0)
+	rdata := t.pack()
+	signed = appendUint16(signed, uint16(len(rdata)))
+	signed = append(signed, rdata...)
+
+	arcount := binary.BigEndian.Uint16(signed[10:12])
+	binary.BigEndian.PutUint16(signed[10:12], arcount+1)
+
+	return signed, t.mac, nil
+}
+
+// tsigVerifier checks the signatures on the messages of a zone transfer.
+// Per RFC 8945 section 5.3.1 intermediate messages may be unsigned, in which
+// case they are folded into the MAC of the next signed message.
+type tsigVerifier struct {
+	key      *tsigKey
+	priorMAC []byte
+	pending  []byte
+	signed   int
+	unsigned int
+	now      func() time.Time // defaults to time.Now
+}
+
+// verify checks msg, a raw response message. last is true for the final
+// message of the transfer, which must be signed.
+func (v *tsigVerifier) verify(msg []byte, last bool) error {
+	body, rec, err := splitTSIG(msg, v.key.name)
+	if err != nil {
+		return err
+	}
+
+	if rec == nil {
+		if last || v.signed == 0 {
+			return errors.New("response is not TSIG signed")
+		}
+		v.unsigned++
+		if v.unsigned >= 100 {
+			return errors.New("too many unsigned messages in TSIG signed transfer")
+		}
+		v.pending = append(v.pending, msg...)
+		return nil
+	}
+
+	if rec.algorithm != v.key.algorithm {
+		return errors.Errorf("response signed with %s, expected %s", rec.algorithm, v.key.algorithm)
+	}
+	if rec.error != 0 {
+		return errors.Errorf("primary rejected TSIG with error %d", rec.error)
+	}
+
+	mac := hmac.New(v.key.hash, v.key.secret)
+	if len(v.priorMAC) > 0 {
+		mac.Write(appendUint16(nil, uint16(len(v.priorMAC))))
+		mac.Write(v.priorMAC)
+	}
+	mac.Write(v.pending)
+	mac.Write(body)
+	mac.Write(rec.variables(v.key, v.signed > 0))
+	if !hmac.Equal(mac.Sum(nil), rec.mac) {
+		return errors.New("TSIG signature mismatch")
+	}
+
+	clock := v.now
+	if clock == nil {
+		clock = time.Now
+	}
+	if d := clock().Unix() - int64(rec.timeSigned); d > int64(rec.fudge) || -d > int64(rec.fudge) {
+		return errors.New("TSIG signature time outside of permitted fudge")
+	}
+
+	v.priorMAC = rec.mac
+	v.pending = nil
+	v.signed++
+	v.unsigned = 0
+	return nil
+}
+
+// splitTSIG strips a trailing TSIG record from msg and returns the message
+// as it was before signing, with ARCOUNT restored. rec is nil if msg is not
+// signed.
+func splitTSIG(msg []byte, keyName string) ([]byte, *tsigRecord, error) {
+	var p dnsmessage.Parser
+	if _, err := p.Start(msg); err != nil {
+		return nil, nil, errors.Wrap(err, "could not parse DNS message")
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, nil, errors.Wrap(err, "could not parse DNS message")
+	}
+	if err := p.SkipAllAnswers(); err != nil {
+		return nil, nil, errors.Wrap(err, "could not parse DNS message")
+	}
+	if err := p.SkipAllAuthorities(); err != nil {
+		return nil, nil, errors.Wrap(err, "could not parse DNS message")
+	}
+
+	var last *dnsmessage.Resource
+	for {
+		r, err := p.Additional()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "could not parse DNS message")
+		}
+		last = &r
+	}
+	if last == nil || last.Header.Type != typeTSIG {
+		return msg, nil, nil
+	}
+
+	unknown, ok := last.Body.(*dnsmessage.UnknownResource)
+	if !ok {
+		return nil, nil, errors.New("could not parse TSIG record")
+	}
+	rec, err := unpackTSIGRecord(unknown.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if canonicalName(last.Header.Name.String()) != keyName {
+		return nil, nil, errors.Errorf("response signed with unexpected key %s", last.Header.Name)
+	}
+
+	// The TSIG record is never compressed, so its length on the wire is
+	// fully determined by its owner name and RDATA.
+	rrLen := len(appendWireName(nil, keyName)) + 10 + len(unknown.Data)
+	if rrLen > len(msg)-12 {
+		return nil, nil, errors.New("could not locate TSIG record")
+	}
+	body := make([]byte, len(msg)-rrLen)
+	copy(body, msg)
+	binary.BigEndian.PutUint16(body[10:12], binary.BigEndian.Uint16(body[10:12])-1)
+
+	return body, rec, nil
+}
+
+// canonicalName lowercases name and ensures it is fully qualified.
+func canonicalName(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+func appendWireName(b []byte, name string) []byte {
+	name = strings.TrimSuffix(canonicalName(name), ".")
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+	}
+	return append(b, 0)
+}
+
+// readWireName reads an uncompressed domain name from b at off.
+func readWireName(b []byte, off int) (string, int, error) {
+	var labels []string
+	for {
+		if off >= len(b) {
+			return "", 0, errors.New("truncated domain name")
+		}
+		l := int(b[off])
+		off++
+		if l == 0 {
+			break
+		}
+		if l&0xC0 != 0 || off+l > len(b) {
+			return "", 0, errors.New("invalid domain name")
+		}
+		labels = append(labels, string(b[off:off+l]))
+		off += l
+	}
+	return canonicalName(strings.Join(labels, ".")), off, nil
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint48(b []byte, v uint64) []byte {
+	return append(b, byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
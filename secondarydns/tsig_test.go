@@ -0,0 +1,83 @@
+package secondarydns
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The known-answer vectors below are taken from the TSIG tests of
+// github.com/miekg/dns, which are interoperable with BIND.
+const tsigTestTime = 1594855491
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestTSIGKey_SignKnownAnswer(t *testing.T) {
+	// An UPDATE for example.com. SOA with message ID 42.
+	msg := "002a28000001000000000000076578616d706c6503636f6d0000060001"
+
+	tests := []struct {
+		algo   string
+		secret string
+		mac    string
+	}{
+		{"hmac-sha224.", "hVEkQuAqnTmBuRrT9KF1Udr91gOMGWPw9LaTtw==",
+			"d6daf9ea189e48bc38f9aed63d6cc4140cdfa38a7a333ee2eefdbd31"},
+		{"hmac-sha384.", "Qjer2TL2lAdpq9w6Gjs98/ClCQx/L3vtgVHCmrZ8l/oKEPjqUUMFO18gMCRwd5H4",
+			"89a48936d29187870c325cbdba5ad71609bd038d0459d6010c844d659c570e881d3650e4fe7310be53ebe5178d0d1001"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.algo, func(t *testing.T) {
+			key, err := newTSIGKey(cloudflare.SecondaryDNSTSIG{Name: "testkey.", Algo: tc.algo, Secret: tc.secret})
+			require.NoError(t, err)
+
+			signed, mac, err := key.sign(mustDecodeHex(t, msg), time.Unix(tsigTestTime, 0))
+			require.NoError(t, err)
+			assert.Equal(t, tc.mac, hex.EncodeToString(mac))
+
+			_, rec, err := splitTSIG(signed, key.name)
+			require.NoError(t, err)
+			assert.Equal(t, tc.mac, hex.EncodeToString(rec.mac))
+		})
+	}
+}
+
+func TestTSIGVerifier_KnownAnswer(t *testing.T) {
+	// An UPDATE for example.com. signed with hmac-sha256 by "testkey.";
+	// the placeholder is the time signed.
+	const msg = "c60028000001000000010001076578616d706c6503636f6d00000600010161c00c0001000100000e100004c0000201077465" +
+		"73746b65790000fa00ff00000000003d0b686d61632d73686132353600" +
+		"%012x" +
+		"012c00208cf23e0081d915478a182edcea7ff48ad102948e6c7ef8e887536957d1fa5616c60000000000"
+
+	key, err := newTSIGKey(cloudflare.SecondaryDNSTSIG{
+		Name:   "testkey.",
+		Algo:   "hmac-sha256.",
+		Secret: "NoTCJU+DMqFWywaPyxSijrDEA/eC3nK0xi3AMEZuPVk=",
+	})
+	require.NoError(t, err)
+
+	at := func(sec int64) func() time.Time {
+		return func() time.Time { return time.Unix(sec, 0) }
+	}
+
+	v := &tsigVerifier{key: key, now: at(tsigTestTime)}
+	assert.NoError(t, v.verify(mustDecodeHex(t, fmt.Sprintf(msg, tsigTestTime)), true))
+
+	v = &tsigVerifier{key: key, now: at(tsigTestTime + 301)}
+	assert.EqualError(t, v.verify(mustDecodeHex(t, fmt.Sprintf(msg, tsigTestTime)), true),
+		"TSIG signature time outside of permitted fudge")
+
+	v = &tsigVerifier{key: key, now: at(tsigTestTime + 1)}
+	assert.EqualError(t, v.verify(mustDecodeHex(t, fmt.Sprintf(msg, tsigTestTime+1)), true),
+		"TSIG signature mismatch")
+}
@@ -0,0 +1,285 @@
+// Package secondarydns verifies that a Cloudflare secondary DNS zone holds
+// the same data as its primary by comparing an AXFR of the primary with the
+// records Cloudflare serves.
+package secondarydns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
+)
+
+// DefaultIgnoreTypes are record types left out of a comparison unless
+// Options.IgnoreTypes says otherwise. Cloudflare does not expose the SOA or
+// DNSSEC records of a secondary zone through the DNS records API.
+var DefaultIgnoreTypes = []string{"SOA", "RRSIG", "NSEC", "NSEC3", "NSEC3PARAM", "DNSKEY"}
+
+// Options configures Verify.
+type Options struct {
+	// Primary is the "host:port" address to transfer the zone from. If
+	// empty, the first primary configured for the secondary zone is used.
+	Primary string
+
+	// TSIG signs the transfer. If nil and the configured primary has a TSIG
+	// attached, that TSIG is fetched from the API.
+	TSIG *cloudflare.SecondaryDNSTSIG
+
+	// IgnoreTypes overrides DefaultIgnoreTypes.
+	IgnoreTypes []string
+
+	// CompareTTL also reports RRsets whose TTL differs. Cloudflare records
+	// with an automatic TTL are never compared.
+	CompareTTL bool
+}
+
+// RRset is every record of one name and type.
+type RRset struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	TTL    uint32   `json:"ttl"`
+	Values []string `json:"values"`
+}
+
+// RRsetDiff is an RRset present on both sides with different contents.
+type RRsetDiff struct {
+	Primary    RRset `json:"primary"`
+	Cloudflare RRset `json:"cloudflare"`
+}
+
+// Report is the result of comparing a primary with Cloudflare.
+type Report struct {
+	Zone string `json:"zone"`
+	// Missing RRsets exist on the primary but not on Cloudflare.
+	Missing []RRset `json:"missing"`
+	// Extra RRsets exist on Cloudflare but not on the primary.
+	Extra     []RRset     `json:"extra"`
+	Different []RRsetDiff `json:"different"`
+}
+
+// InSync reports whether no differences were found.
+func (r *Report) InSync() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Different) == 0
+}
+
+// Verify transfers the secondary zone zoneID from its primary and compares
+// the result with the records Cloudflare returns for it. Looking up the
+// configured primary requires api.AccountID to be set.
+func Verify(ctx context.Context, api *cloudflare.API, zoneID string, opts Options) (*Report, error) {
+	zone, err := api.GetSecondaryDNSZone(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, tsig := opts.Primary, opts.TSIG
+	if addr == "" {
+		if len(zone.Primaries) == 0 {
+			return nil, errors.New("secondary zone has no primaries configured")
+		}
+		if api.AccountID == "" {
+			return nil, errors.New("an account ID is required to look up the configured primary")
+		}
+
+		primary, err := api.GetSecondaryDNSPrimary(ctx, api.AccountID, zone.Primaries[0])
+		if err != nil {
+			return nil, err
+		}
+		addr = net.JoinHostPort(primary.IP, strconv.Itoa(primary.Port))
+
+		if tsig == nil && primary.TsigID != "" {
+			t, err := api.GetSecondaryDNSTSIG(ctx, api.AccountID, primary.TsigID)
+			if err != nil {
+				return nil, err
+			}
+			tsig = &t
+		}
+	}
+
+	transferred, err := Transfer(ctx, addr, zone.Name, tsig)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := api.DNSRecords(ctx, zoneID, cloudflare.DNSRecord{})
+	if err != nil {
+		return nil, err
+	}
+
+	report := Compare(transferred, FromDNSRecords(records), opts)
+	report.Zone = normaliseName(zone.Name)
+	return report, nil
+}
+
+// Compare diffs the records of a primary against those on Cloudflare.
+func Compare(primary, cf []RR, opts Options) *Report {
+	ignore := opts.IgnoreTypes
+	if ignore == nil {
+		ignore = DefaultIgnoreTypes
+	}
+
+	p := groupRRsets(primary, ignore)
+	c := groupRRsets(cf, ignore)
+
+	report := &Report{}
+	for _, key := range sortedKeys(p) {
+		ps := p[key]
+		cs, ok := c[key]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, ps)
+		case !equalValues(ps.Values, cs.Values) || (opts.CompareTTL && cs.TTL > 1 && ps.TTL != cs.TTL):
+			report.Different = append(report.Different, RRsetDiff{Primary: ps, Cloudflare: cs})
+		}
+	}
+	for _, key := range sortedKeys(c) {
+		if _, ok := p[key]; !ok {
+			report.Extra = append(report.Extra, c[key])
+		}
+	}
+
+	return report
+}
+
+func groupRRsets(records []RR, ignore []string) map[string]RRset {
+	sets := make(map[string]RRset)
+	for _, r := range records {
+		if containsType(ignore, r.Type) {
+			continue
+		}
+		key := r.Name + " " + r.Type
+		s, ok := sets[key]
+		if !ok {
+			s = RRset{Name: r.Name, Type: r.Type, TTL: r.TTL}
+		}
+		s.Values = append(s.Values, r.Data)
+		sets[key] = s
+	}
+	for key, s := range sets {
+		sort.Strings(s.Values)
+		sets[key] = s
+	}
+	return sets
+}
+
+func containsType(types []string, t string) bool {
+	for _, v := range types {
+		if strings.EqualFold(v, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]RRset) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// FromDNSRecords converts Cloudflare DNS records into the same normalised
+// form Transfer produces.
+func FromDNSRecords(records []cloudflare.DNSRecord) []RR {
+	out := make([]RR, 0, len(records))
+	for _, r := range records {
+		rr := RR{
+			Name: normaliseName(r.Name),
+			Type: strings.ToUpper(r.Type),
+			TTL:  uint32(r.TTL),
+			Data: r.Content,
+		}
+
+		data, _ := r.Data.(map[string]interface{})
+		switch rr.Type {
+		case "A", "AAAA":
+			if ip := net.ParseIP(r.Content); ip != nil {
+				rr.Data = ip.String()
+			}
+		case "NS", "CNAME", "PTR":
+			rr.Data = normaliseName(r.Content)
+		case "MX":
+			rr.Data = fmt.Sprintf("%d %s", priority(r, data), normaliseName(r.Content))
+		case "SRV":
+			rr.Data = srvData(r, data)
+		case "TXT", "SPF":
+			rr.Data = unquoteTXT(r.Content)
+		case "CAA":
+			rr.Data = caaData(r, data)
+		}
+
+		out = append(out, rr)
+	}
+	return out
+}
+
+func priority(r cloudflare.DNSRecord, data map[string]interface{}) int {
+	if r.Priority != nil {
+		return int(*r.Priority)
+	}
+	if p, ok := data["priority"].(float64); ok {
+		return int(p)
+	}
+	return 0
+}
+
+// srvData renders an SRV record as "priority weight port target". The API
+// keeps the priority separately from the content.
+func srvData(r cloudflare.DNSRecord, data map[string]interface{}) string {
+	if target, ok := data["target"].(string); ok {
+		weight, _ := data["weight"].(float64)
+		port, _ := data["port"].(float64)
+		return fmt.Sprintf("%d %d %d %s", priority(r, data), int(weight), int(port), normaliseName(target))
+	}
+
+	parts := strings.Fields(r.Content)
+	if len(parts) != 3 {
+		return r.Content
+	}
+	return fmt.Sprintf("%d %s %s %s", priority(r, data), parts[0], parts[1], normaliseName(parts[2]))
+}
+
+// unquoteTXT joins quoted character strings ("a" "b") into the single value
+// a zone transfer produces. Unquoted content is returned as is.
+func unquoteTXT(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	var parts []string
+	for _, p := range strings.Split(s[1:len(s)-1], `" "`) {
+		parts = append(parts, strings.ReplaceAll(p, `\"`, `"`))
+	}
+	return strings.Join(parts, "")
+}
+
+func caaData(r cloudflare.DNSRecord, data map[string]interface{}) string {
+	if tag, ok := data["tag"].(string); ok {
+		flags, _ := data["flags"].(float64)
+		value, _ := data["value"].(string)
+		return fmt.Sprintf("%d %s %s", int(flags), strings.ToLower(tag), value)
+	}
+
+	parts := strings.SplitN(r.Content, " ", 3)
+	if len(parts) != 3 {
+		return r.Content
+	}
+	return fmt.Sprintf("%s %s %s", parts[0], strings.ToLower(parts[1]), strings.Trim(parts[2], `"`))
+}
@@ -0,0 +1,296 @@
+package secondarydns
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var testTSIG = cloudflare.SecondaryDNSTSIG{
+	Name:   "tsig.example.com.",
+	Algo:   "hmac-sha256.",
+	Secret: "yveaeAS0QzfJxmzNe++RkKHhZ5td0D2KoQ96RV4anauStBeJbBXU0AfHwUGUU40qXQ/v/ezFp/DhxXDPpwCDfA==",
+}
+
+// axfrServer is a minimal authoritative server that answers a single AXFR
+// over TCP, splitting the zone across several messages. If key is set the
+// query must be signed and every response is signed too, except for the
+// messages listed in unsigned.
+type axfrServer struct {
+	listener net.Listener
+	records  []dnsmessage.Resource
+	key      *tsigKey
+	unsigned map[int]bool
+	refuse   bool
+}
+
+func newAXFRServer(t *testing.T, records []dnsmessage.Resource) *axfrServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	s := &axfrServer{listener: l, records: records}
+	go s.serve(t)
+	return s
+}
+
+func (s *axfrServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *axfrServer) serve(t *testing.T) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.handle(t, conn)
+		conn.Close()
+	}
+}
+
+func (s *axfrServer) handle(t *testing.T, conn net.Conn) {
+	query, err := readTCPMessage(conn)
+	if err != nil {
+		return
+	}
+
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if !assert.NoError(t, err) {
+		return
+	}
+	q, err := p.Question()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, dnsmessage.TypeAXFR, q.Type)
+
+	var priorMAC []byte
+	if s.key != nil {
+		_, rec, err := splitTSIG(query, s.key.name)
+		if !assert.NoError(t, err) || !assert.NotNil(t, rec, "query is not signed") {
+			return
+		}
+		priorMAC = rec.mac
+	}
+
+	header := dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true}
+	if s.refuse {
+		header.RCode = dnsmessage.RCodeRefused
+		msg, _ := (&dnsmessage.Message{Header: header, Questions: []dnsmessage.Question{q}}).Pack()
+		conn.Write(append(appendUint16(nil, uint16(len(msg))), msg...)) //nolint:errcheck
+		return
+	}
+
+	// Send two records per message so the client has to reassemble the
+	// transfer and carry the TSIG state across messages.
+	signed := 0
+	for i := 0; i*2 < len(s.records); i++ {
+		end := i*2 + 2
+		if end > len(s.records) {
+			end = len(s.records)
+		}
+		m := dnsmessage.Message{Header: header, Answers: s.records[i*2 : end]}
+		if i == 0 {
+			m.Questions = []dnsmessage.Question{q}
+		}
+		msg, err := m.Pack()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		if s.key != nil && !s.unsigned[i] {
+			msg, priorMAC = signResponse(s.key, msg, priorMAC, signed == 0)
+			signed++
+		} else if s.key != nil {
+			// Unsigned messages are folded into the next MAC.
+			priorMAC = append(append([]byte{}, priorMAC...), msg...)
+		}
+		conn.Write(append(appendUint16(nil, uint16(len(msg))), msg...)) //nolint:errcheck
+	}
+}
+
+// signResponse signs msg the way a primary would. prior holds the request
+// MAC, or the previous MAC followed by any unsigned messages since.
+func signResponse(key *tsigKey, msg, prior []byte, first bool) ([]byte, []byte) {
+	t := &tsigRecord{
+		algorithm:  key.algorithm,
+		timeSigned: uint64(time.Now().Unix()),
+		fudge:      tsigFudge,
+		originalID: binary.BigEndian.Uint16(msg),
+	}
+
+	// prior may have unsigned messages appended after the MAC itself.
+	macLen := key.hash().Size()
+	mac := hmac.New(key.hash, key.secret)
+	mac.Write(appendUint16(nil, uint16(macLen)))
+	mac.Write(prior)
+	mac.Write(msg)
+	mac.Write(t.variables(key, !first))
+	t.mac = mac.Sum(nil)
+
+	signed := append([]byte{}, msg...)
+	signed = appendWireName(signed, key.name)
+	signed = appendUint16(signed, uint16(typeTSIG))
+	signed = appendUint16(signed, uint16(classANY))
+	signed = append(signed, 0, 0, 0, 0)
+	rdata := t.pack()
+	signed = appendUint16(signed, uint16(len(rdata)))
+	signed = append(signed, rdata...)
+	binary.BigEndian.PutUint16(signed[10:12], binary.BigEndian.Uint16(signed[10:12])+1)
+
+	return signed, t.mac
+}
+
+func rrHeader(name string, typ dnsmessage.Type, ttl uint32) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: typ, Class: dnsmessage.ClassINET, TTL: ttl}
+}
+
+func testZone() []dnsmessage.Resource {
+	soa := dnsmessage.Resource{
+		Header: rrHeader("example.com.", dnsmessage.TypeSOA, 3600),
+		Body: &dnsmessage.SOAResource{
+			NS: dnsmessage.MustNewName("ns1.example.net."), MBox: dnsmessage.MustNewName("hostmaster.example.com."),
+			Serial: 2021101801, Refresh: 3600, Retry: 600, Expire: 604800, MinTTL: 300,
+		},
+	}
+
+	return []dnsmessage.Resource{
+		soa,
+		{Header: rrHeader("example.com.", dnsmessage.TypeA, 300), Body: &dnsmessage.AResource{A: [4]byte{198, 51, 100, 4}}},
+		{Header: rrHeader("example.com.", dnsmessage.TypeA, 300), Body: &dnsmessage.AResource{A: [4]byte{198, 51, 100, 5}}},
+		{Header: rrHeader("Www.Example.com.", dnsmessage.TypeCNAME, 300), Body: &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("example.com.")}},
+		{Header: rrHeader("example.com.", dnsmessage.TypeMX, 300), Body: &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mx.example.net.")}},
+		{Header: rrHeader("example.com.", dnsmessage.TypeTXT, 300), Body: &dnsmessage.TXTResource{TXT: []string{"v=spf1 ", "-all"}}},
+		{Header: rrHeader("_sip._tcp.example.com.", dnsmessage.TypeSRV, 300), Body: &dnsmessage.SRVResource{Priority: 5, Weight: 1, Port: 5060, Target: dnsmessage.MustNewName("sip.example.com.")}},
+		{Header: rrHeader("example.com.", typeCAA, 300), Body: &dnsmessage.UnknownResource{Type: typeCAA, Data: append([]byte{0, 5}, "issueletsencrypt.org"...)}},
+		{Header: rrHeader("old.example.com.", dnsmessage.TypeAAAA, 300), Body: &dnsmessage.AAAAResource{AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}}},
+		soa,
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	s := newAXFRServer(t, testZone())
+
+	records, err := Transfer(context.Background(), s.addr(), "example.com", nil)
+	require.NoError(t, err)
+	require.Len(t, records, 9)
+
+	assert.Equal(t, RR{Name: "example.com", Type: "SOA", TTL: 3600, Data: "ns1.example.net hostmaster.example.com 2021101801 3600 600 604800 300"}, records[0])
+	assert.Equal(t, RR{Name: "www.example.com", Type: "CNAME", TTL: 300, Data: "example.com"}, records[3])
+	assert.Equal(t, RR{Name: "example.com", Type: "TXT", TTL: 300, Data: "v=spf1 -all"}, records[5])
+	assert.Equal(t, RR{Name: "_sip._tcp.example.com", Type: "SRV", TTL: 300, Data: "5 1 5060 sip.example.com"}, records[6])
+	assert.Equal(t, RR{Name: "example.com", Type: "CAA", TTL: 300, Data: "0 issue letsencrypt.org"}, records[7])
+	assert.Equal(t, RR{Name: "old.example.com", Type: "AAAA", TTL: 300, Data: "2001:db8::1"}, records[8])
+}
+
+func TestTransfer_TSIG(t *testing.T) {
+	key, err := newTSIGKey(testTSIG)
+	require.NoError(t, err)
+
+	s := newAXFRServer(t, testZone())
+	s.key = key
+	s.unsigned = map[int]bool{2: true}
+
+	records, err := Transfer(context.Background(), s.addr(), "example.com", &testTSIG)
+	require.NoError(t, err)
+	assert.Len(t, records, 9)
+
+	wrong := testTSIG
+	wrong.Secret = "c2VjcmV0"
+	_, err = Transfer(context.Background(), s.addr(), "example.com", &wrong)
+	assert.EqualError(t, err, "TSIG signature mismatch")
+}
+
+func TestTransfer_Refused(t *testing.T) {
+	s := newAXFRServer(t, testZone())
+	s.refuse = true
+
+	_, err := Transfer(context.Background(), s.addr(), "example.com", nil)
+	assert.Error(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	dns := newAXFRServer(t, testZone())
+	host, port, _ := net.SplitHostPort(dns.addr())
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/zones/023e105f4ecef8ad9ca31a8372d0c353/secondary_dns", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": {
+			"id": "269d8f4853475ca241c4e730be286b20",
+			"name": "example.com.",
+			"primaries": ["23ff594956f20c2a721606e94745a8aa"]
+		}}`)
+	})
+	mux.HandleFunc("/accounts/01a7362d577a6c3019a474fd6f485823/secondary_dns/primaries/23ff594956f20c2a721606e94745a8aa", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": {
+			"id": "23ff594956f20c2a721606e94745a8aa",
+			"ip": %q,
+			"port": %s,
+			"name": "my-primary"
+		}}`, host, port)
+	})
+	mux.HandleFunc("/zones/023e105f4ecef8ad9ca31a8372d0c353/dns_records", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [
+			{"type": "A", "name": "example.com", "content": "198.51.100.4", "ttl": 300},
+			{"type": "A", "name": "example.com", "content": "198.51.100.5", "ttl": 300},
+			{"type": "CNAME", "name": "www.example.com", "content": "example.com", "ttl": 300},
+			{"type": "MX", "name": "example.com", "content": "mx.example.net", "priority": 20, "ttl": 300},
+			{"type": "TXT", "name": "example.com", "content": "v=spf1 -all", "ttl": 300},
+			{"type": "SRV", "name": "_sip._tcp.example.com", "content": "1 5060 sip.example.com", "priority": 5, "ttl": 300,
+			 "data": {"priority": 5, "weight": 1, "port": 5060, "target": "sip.example.com"}},
+			{"type": "CAA", "name": "example.com", "content": "0 issue \"letsencrypt.org\"", "ttl": 300},
+			{"type": "A", "name": "new.example.com", "content": "198.51.100.9", "ttl": 1}
+		], "result_info": {"page": 1, "per_page": 100, "count": 8, "total_count": 8, "total_pages": 1}}`)
+	})
+
+	api, err := cloudflare.New("deadbeef", "cloudflare@example.org",
+		cloudflare.BaseURL(server.URL),
+		cloudflare.UsingAccount("01a7362d577a6c3019a474fd6f485823"),
+		cloudflare.UsingRateLimit(100000),
+		cloudflare.UsingRetryPolicy(0, 0, 0))
+	require.NoError(t, err)
+
+	report, err := Verify(context.Background(), api, "023e105f4ecef8ad9ca31a8372d0c353", Options{})
+	require.NoError(t, err)
+
+	assert.False(t, report.InSync())
+	assert.Equal(t, "example.com", report.Zone)
+	assert.Equal(t, []RRset{{Name: "old.example.com", Type: "AAAA", TTL: 300, Values: []string{"2001:db8::1"}}}, report.Missing)
+	assert.Equal(t, []RRset{{Name: "new.example.com", Type: "A", TTL: 1, Values: []string{"198.51.100.9"}}}, report.Extra)
+	assert.Equal(t, []RRsetDiff{{
+		Primary:    RRset{Name: "example.com", Type: "MX", TTL: 300, Values: []string{"10 mx.example.net"}},
+		Cloudflare: RRset{Name: "example.com", Type: "MX", TTL: 300, Values: []string{"20 mx.example.net"}},
+	}}, report.Different)
+}
+
+func TestCompare_TTL(t *testing.T) {
+	primary := []RR{{Name: "example.com", Type: "A", TTL: 300, Data: "198.51.100.4"}}
+
+	report := Compare(primary, []RR{{Name: "example.com", Type: "A", TTL: 600, Data: "198.51.100.4"}}, Options{})
+	assert.True(t, report.InSync())
+
+	report = Compare(primary, []RR{{Name: "example.com", Type: "A", TTL: 600, Data: "198.51.100.4"}}, Options{CompareTTL: true})
+	assert.Len(t, report.Different, 1)
+
+	report = Compare(primary, []RR{{Name: "example.com", Type: "A", TTL: 1, Data: "198.51.100.4"}}, Options{CompareTTL: true})
+	assert.True(t, report.InSync())
+}
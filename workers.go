@@ -11,6 +11,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
+	"path"
 	"time"
 
 	"github.com/pkg/errors"
@@ -26,9 +27,98 @@ type WorkerRequestParams struct {
 type WorkerScriptParams struct {
 	Script string
 
+	// MainModule is the name of the module exporting the Worker's handlers.
+	// Setting it uploads the Worker in the ES module format; Script, if
+	// set, is then uploaded as the main module unless Modules already
+	// contains a module of that name.
+	MainModule string
+
+	// Modules are the additional modules of an ES module Worker.
+	Modules []WorkerModule
+
+	// CompatibilityDate and CompatibilityFlags select the runtime behaviour
+	// of the Worker.
+	//
+	// https://developers.cloudflare.com/workers/platform/compatibility-dates
+	CompatibilityDate  string
+	CompatibilityFlags []string
+
 	// Bindings should be a map where the keys are the binding name, and the
 	// values are the binding content
 	Bindings map[string]WorkerBinding
+
+	// Migrations are the Durable Object migrations to apply with the upload.
+	Migrations *WorkerMigrations
+}
+
+// WorkerMigrations moves a script's Durable Object classes from the
+// migration tagged OldTag to the one tagged NewTag by applying Steps.
+//
+// https://developers.cloudflare.com/workers/learning/using-durable-objects#durable-object-migrations-in-wranglertoml
+type WorkerMigrations struct {
+	OldTag string                `json:"old_tag,omitempty"`
+	NewTag string                `json:"new_tag,omitempty"`
+	Steps  []WorkerMigrationStep `json:"steps"`
+}
+
+// WorkerMigrationStep creates, renames and deletes Durable Object classes.
+type WorkerMigrationStep struct {
+	NewClasses     []string             `json:"new_classes,omitempty"`
+	RenamedClasses []WorkerRenamedClass `json:"renamed_classes,omitempty"`
+	DeletedClasses []string             `json:"deleted_classes,omitempty"`
+}
+
+// WorkerRenamedClass renames a Durable Object class.
+type WorkerRenamedClass struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// WorkerModuleType is the content type of a module part.
+type WorkerModuleType string
+
+const (
+	// WorkerModuleTypeESM is an ES module.
+	WorkerModuleTypeESM WorkerModuleType = "application/javascript+module"
+	// WorkerModuleTypeCommonJS is a CommonJS module.
+	WorkerModuleTypeCommonJS WorkerModuleType = "application/javascript"
+	// WorkerModuleTypeText is imported as a string.
+	WorkerModuleTypeText WorkerModuleType = "text/plain"
+	// WorkerModuleTypeData is imported as an ArrayBuffer.
+	WorkerModuleTypeData WorkerModuleType = "application/octet-stream"
+	// WorkerModuleTypeWasm is imported as a WebAssembly.Module.
+	WorkerModuleTypeWasm WorkerModuleType = "application/wasm"
+)
+
+// WorkerModule is a single module of an ES module Worker.
+type WorkerModule struct {
+	// Name is the path other modules import this module by, such as
+	// "lib/util.mjs".
+	Name string
+
+	// Type defaults to a type guessed from the extension of Name.
+	Type WorkerModuleType
+
+	Content []byte
+}
+
+// contentType returns the module's type, guessing it from the file
+// extension when unset.
+func (m WorkerModule) contentType() WorkerModuleType {
+	if m.Type != "" {
+		return m.Type
+	}
+	switch path.Ext(m.Name) {
+	case ".js", ".mjs":
+		return WorkerModuleTypeESM
+	case ".cjs":
+		return WorkerModuleTypeCommonJS
+	case ".wasm":
+		return WorkerModuleTypeWasm
+	case ".txt", ".html", ".json", ".css", ".svg":
+		return WorkerModuleTypeText
+	}
+	return WorkerModuleTypeData
 }
 
 // WorkerRoute is used to map traffic matching a URL pattern to a workers
@@ -66,6 +156,11 @@ type WorkerMetaData struct {
 	Size       int       `json:"size,omitempty"`
 	CreatedOn  time.Time `json:"created_on,omitempty"`
 	ModifiedOn time.Time `json:"modified_on,omitempty"`
+	// MigrationTag is the tag of the last Durable Object migration applied
+	// to the script.
+	MigrationTag       string   `json:"migration_tag,omitempty"`
+	CompatibilityDate  string   `json:"compatibility_date,omitempty"`
+	CompatibilityFlags []string `json:"compatibility_flags,omitempty"`
 }
 
 // WorkerListResponse wrapper struct for API response to worker script list API call
@@ -98,6 +193,18 @@ const (
 	WorkerSecretTextBindingType WorkerBindingType = "secret_text"
 	// WorkerPlainTextBindingType is the type for plain text bindings
 	WorkerPlainTextBindingType WorkerBindingType = "plain_text"
+	// WorkerDurableObjectBindingType is the type for Durable Object namespace bindings
+	WorkerDurableObjectBindingType WorkerBindingType = "durable_object_namespace"
+	// WorkerServiceBindingType is the type for service bindings
+	WorkerServiceBindingType WorkerBindingType = "service"
+	// WorkerR2BucketBindingType is the type for R2 bucket bindings
+	WorkerR2BucketBindingType WorkerBindingType = "r2_bucket"
+	// WorkerQueueBindingType is the type for queue producer bindings
+	WorkerQueueBindingType WorkerBindingType = "queue"
+	// WorkerAnalyticsEngineBindingType is the type for Analytics Engine dataset bindings
+	WorkerAnalyticsEngineBindingType WorkerBindingType = "analytics_engine"
+	// WorkerJSONBindingType is the type for JSON variable bindings
+	WorkerJSONBindingType WorkerBindingType = "json"
 )
 
 // WorkerBindingListItem a struct representing an individual binding in a list of bindings
@@ -267,6 +374,186 @@ func (b WorkerSecretTextBinding) serialize(bindingName string) (workerBindingMet
 	}, nil, nil
 }
 
+// WorkerDurableObjectBinding is a binding to a Durable Object namespace
+//
+// https://developers.cloudflare.com/workers/runtime-apis/durable-objects/
+type WorkerDurableObjectBinding struct {
+	ClassName string
+	// ScriptName is the Worker that implements ClassName. It may be left
+	// empty if the class is exported by the Worker being uploaded.
+	ScriptName string
+}
+
+// Type returns the type of the binding
+func (b WorkerDurableObjectBinding) Type() WorkerBindingType {
+	return WorkerDurableObjectBindingType
+}
+
+func (b WorkerDurableObjectBinding) serialize(bindingName string) (workerBindingMeta, workerBindingBodyWriter, error) {
+	if b.ClassName == "" {
+		return nil, nil, errors.Errorf(`ClassName for binding "%s" cannot be empty`, bindingName)
+	}
+
+	meta := workerBindingMeta{
+		"name":       bindingName,
+		"type":       b.Type(),
+		"class_name": b.ClassName,
+	}
+	if b.ScriptName != "" {
+		meta["script_name"] = b.ScriptName
+	}
+	return meta, nil, nil
+}
+
+// WorkerServiceBinding is a binding to another Worker
+//
+// https://developers.cloudflare.com/workers/platform/bindings/about-service-bindings/
+type WorkerServiceBinding struct {
+	Service string
+	// Environment defaults to the production environment of Service.
+	Environment string
+}
+
+// Type returns the type of the binding
+func (b WorkerServiceBinding) Type() WorkerBindingType {
+	return WorkerServiceBindingType
+}
+
+func (b WorkerServiceBinding) serialize(bindingName string) (workerBindingMeta, workerBindingBodyWriter, error) {
+	if b.Service == "" {
+		return nil, nil, errors.Errorf(`Service for binding "%s" cannot be empty`, bindingName)
+	}
+
+	meta := workerBindingMeta{
+		"name":    bindingName,
+		"type":    b.Type(),
+		"service": b.Service,
+	}
+	if b.Environment != "" {
+		meta["environment"] = b.Environment
+	}
+	return meta, nil, nil
+}
+
+// WorkerR2BucketBinding is a binding to an R2 bucket
+//
+// https://developers.cloudflare.com/r2/
+type WorkerR2BucketBinding struct {
+	BucketName string
+}
+
+// Type returns the type of the binding
+func (b WorkerR2BucketBinding) Type() WorkerBindingType {
+	return WorkerR2BucketBindingType
+}
+
+func (b WorkerR2BucketBinding) serialize(bindingName string) (workerBindingMeta, workerBindingBodyWriter, error) {
+	if b.BucketName == "" {
+		return nil, nil, errors.Errorf(`BucketName for binding "%s" cannot be empty`, bindingName)
+	}
+
+	return workerBindingMeta{
+		"name":        bindingName,
+		"type":        b.Type(),
+		"bucket_name": b.BucketName,
+	}, nil, nil
+}
+
+// WorkerQueueBinding is a binding that lets a Worker send messages to a
+// queue
+//
+// https://developers.cloudflare.com/queues/
+type WorkerQueueBinding struct {
+	QueueName string
+}
+
+// Type returns the type of the binding
+func (b WorkerQueueBinding) Type() WorkerBindingType {
+	return WorkerQueueBindingType
+}
+
+func (b WorkerQueueBinding) serialize(bindingName string) (workerBindingMeta, workerBindingBodyWriter, error) {
+	if b.QueueName == "" {
+		return nil, nil, errors.Errorf(`QueueName for binding "%s" cannot be empty`, bindingName)
+	}
+
+	return workerBindingMeta{
+		"name":       bindingName,
+		"type":       b.Type(),
+		"queue_name": b.QueueName,
+	}, nil, nil
+}
+
+// WorkerAnalyticsEngineBinding is a binding to an Analytics Engine dataset
+//
+// https://developers.cloudflare.com/analytics/analytics-engine/
+type WorkerAnalyticsEngineBinding struct {
+	Dataset string
+}
+
+// Type returns the type of the binding
+func (b WorkerAnalyticsEngineBinding) Type() WorkerBindingType {
+	return WorkerAnalyticsEngineBindingType
+}
+
+func (b WorkerAnalyticsEngineBinding) serialize(bindingName string) (workerBindingMeta, workerBindingBodyWriter, error) {
+	if b.Dataset == "" {
+		return nil, nil, errors.Errorf(`Dataset for binding "%s" cannot be empty`, bindingName)
+	}
+
+	return workerBindingMeta{
+		"name":    bindingName,
+		"type":    b.Type(),
+		"dataset": b.Dataset,
+	}, nil, nil
+}
+
+// WorkerJSONBinding is a binding to an arbitrary JSON value, exposed to the
+// Worker as the decoded value rather than a string.
+type WorkerJSONBinding struct {
+	Value interface{}
+}
+
+// Type returns the type of the binding
+func (b WorkerJSONBinding) Type() WorkerBindingType {
+	return WorkerJSONBindingType
+}
+
+func (b WorkerJSONBinding) serialize(bindingName string) (workerBindingMeta, workerBindingBodyWriter, error) {
+	if b.Value == nil {
+		return nil, nil, errors.Errorf(`Value for binding "%s" cannot be empty`, bindingName)
+	}
+
+	return workerBindingMeta{
+		"name": bindingName,
+		"type": b.Type(),
+		"json": b.Value,
+	}, nil, nil
+}
+
+// WorkerUnknownBinding is a binding of a type this library doesn't know
+// about. It keeps the binding's metadata as returned by the API, so it is
+// uploaded again unchanged.
+type WorkerUnknownBinding struct {
+	BindingType WorkerBindingType
+	Metadata    map[string]interface{}
+}
+
+// Type returns the type of the binding
+func (b WorkerUnknownBinding) Type() WorkerBindingType {
+	return b.BindingType
+}
+
+func (b WorkerUnknownBinding) serialize(bindingName string) (workerBindingMeta, workerBindingBodyWriter, error) {
+	meta := make(workerBindingMeta, len(b.Metadata)+2)
+	for k, v := range b.Metadata {
+		meta[k] = v
+	}
+	meta["name"] = bindingName
+	meta["type"] = b.BindingType
+	return meta, nil, nil
+}
+
 // Each binding that adds a part to the multipart form body will need
 // a unique part name so we just generate a random 128bit hex string
 func getRandomPartName() string {
@@ -416,8 +703,50 @@ func (api *API) ListWorkerBindings(ctx context.Context, requestParams *WorkerReq
 			}
 		case WorkerSecretTextBindingType:
 			bindingListItem.Binding = WorkerSecretTextBinding{}
+		case WorkerDurableObjectBindingType:
+			className, _ := jsonBinding["class_name"].(string)
+			scriptName, _ := jsonBinding["script_name"].(string)
+			bindingListItem.Binding = WorkerDurableObjectBinding{
+				ClassName:  className,
+				ScriptName: scriptName,
+			}
+		case WorkerServiceBindingType:
+			service, _ := jsonBinding["service"].(string)
+			environment, _ := jsonBinding["environment"].(string)
+			bindingListItem.Binding = WorkerServiceBinding{
+				Service:     service,
+				Environment: environment,
+			}
+		case WorkerR2BucketBindingType:
+			bucketName, _ := jsonBinding["bucket_name"].(string)
+			bindingListItem.Binding = WorkerR2BucketBinding{
+				BucketName: bucketName,
+			}
+		case WorkerQueueBindingType:
+			queueName, _ := jsonBinding["queue_name"].(string)
+			bindingListItem.Binding = WorkerQueueBinding{
+				QueueName: queueName,
+			}
+		case WorkerAnalyticsEngineBindingType:
+			dataset, _ := jsonBinding["dataset"].(string)
+			bindingListItem.Binding = WorkerAnalyticsEngineBinding{
+				Dataset: dataset,
+			}
+		case WorkerJSONBindingType:
+			bindingListItem.Binding = WorkerJSONBinding{
+				Value: jsonBinding["json"],
+			}
 		default:
-			bindingListItem.Binding = WorkerInheritBinding{}
+			metadata := make(map[string]interface{}, len(jsonBinding))
+			for k, v := range jsonBinding {
+				if k != "name" && k != "type" {
+					metadata[k] = v
+				}
+			}
+			bindingListItem.Binding = WorkerUnknownBinding{
+				BindingType: WorkerBindingType(bType),
+				Metadata:    metadata,
+			}
 		}
 		r.BindingList = append(r.BindingList, bindingListItem)
 	}
@@ -553,14 +882,29 @@ func formatMultipartBody(params *WorkerScriptParams) (string, []byte, error) {
 	var mpw = multipart.NewWriter(buf)
 	defer mpw.Close()
 
+	modules, err := params.modules()
+	if err != nil {
+		return "", nil, err
+	}
+
 	// Write metadata part
 	scriptPartName := "script"
 	meta := struct {
-		BodyPart string              `json:"body_part"`
-		Bindings []workerBindingMeta `json:"bindings"`
+		BodyPart           string              `json:"body_part,omitempty"`
+		MainModule         string              `json:"main_module,omitempty"`
+		CompatibilityDate  string              `json:"compatibility_date,omitempty"`
+		CompatibilityFlags []string            `json:"compatibility_flags,omitempty"`
+		Bindings           []workerBindingMeta `json:"bindings"`
+		Migrations         *WorkerMigrations   `json:"migrations,omitempty"`
 	}{
-		BodyPart: scriptPartName,
-		Bindings: make([]workerBindingMeta, 0, len(params.Bindings)),
+		MainModule:         params.MainModule,
+		CompatibilityDate:  params.CompatibilityDate,
+		CompatibilityFlags: params.CompatibilityFlags,
+		Migrations:         params.Migrations,
+		Bindings:           make([]workerBindingMeta, 0, len(params.Bindings)),
+	}
+	if params.MainModule == "" {
+		meta.BodyPart = scriptPartName
 	}
 
 	bodyWriters := make([]workerBindingBodyWriter, 0, len(params.Bindings))
@@ -590,17 +934,34 @@ func formatMultipartBody(params *WorkerScriptParams) (string, []byte, error) {
 		return "", nil, err
 	}
 
-	// Write script part
-	hdr = textproto.MIMEHeader{}
-	hdr.Set("content-disposition", fmt.Sprintf(`form-data; name="%s"`, scriptPartName))
-	hdr.Set("content-type", "application/javascript")
-	pw, err = mpw.CreatePart(hdr)
-	if err != nil {
-		return "", nil, err
+	if params.MainModule == "" {
+		// Write script part
+		hdr = textproto.MIMEHeader{}
+		hdr.Set("content-disposition", fmt.Sprintf(`form-data; name="%s"`, scriptPartName))
+		hdr.Set("content-type", "application/javascript")
+		pw, err = mpw.CreatePart(hdr)
+		if err != nil {
+			return "", nil, err
+		}
+		_, err = pw.Write([]byte(params.Script))
+		if err != nil {
+			return "", nil, err
+		}
 	}
-	_, err = pw.Write([]byte(params.Script))
-	if err != nil {
-		return "", nil, err
+
+	// Write module parts, named after the module so imports resolve
+	for _, m := range modules {
+		hdr = textproto.MIMEHeader{}
+		hdr.Set("content-disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, m.Name, m.Name))
+		hdr.Set("content-type", string(m.contentType()))
+		pw, err = mpw.CreatePart(hdr)
+		if err != nil {
+			return "", nil, err
+		}
+		_, err = pw.Write(m.Content)
+		if err != nil {
+			return "", nil, err
+		}
 	}
 
 	// Write other bindings with parts
@@ -618,6 +979,43 @@ func formatMultipartBody(params *WorkerScriptParams) (string, []byte, error) {
 	return mpw.FormDataContentType(), buf.Bytes(), nil
 }
 
+// modules returns the module parts to upload, including Script as the main
+// module when it isn't among Modules.
+func (params *WorkerScriptParams) modules() ([]WorkerModule, error) {
+	if params.MainModule == "" {
+		if len(params.Modules) > 0 {
+			return nil, errors.New("MainModule is required when uploading modules")
+		}
+		return nil, nil
+	}
+
+	modules := make([]WorkerModule, 0, len(params.Modules)+1)
+	seen := make(map[string]bool, len(params.Modules))
+	for _, m := range params.Modules {
+		if m.Name == "" {
+			return nil, errors.New("module name cannot be empty")
+		}
+		if seen[m.Name] {
+			return nil, errors.Errorf(`duplicate module "%s"`, m.Name)
+		}
+		seen[m.Name] = true
+		modules = append(modules, m)
+	}
+
+	if !seen[params.MainModule] {
+		if params.Script == "" {
+			return nil, errors.Errorf(`main module "%s" not found in modules`, params.MainModule)
+		}
+		modules = append([]WorkerModule{{
+			Name:    params.MainModule,
+			Type:    WorkerModuleTypeESM,
+			Content: []byte(params.Script),
+		}}, modules...)
+	}
+
+	return modules, nil
+}
+
 // CreateWorkerRoute creates worker route for a zone
 //
 // API reference: https://api.cloudflare.com/#worker-filters-create-filter, https://api.cloudflare.com/#worker-routes-create-route
@@ -0,0 +1,405 @@
+package cloudflare
+
+import (
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WorkerCronSchedule is a parsed Workers cron expression. Workers accept five
+// fields, evaluated in UTC:
+//
+//	minute        0-59
+//	hour          0-23
+//	day of month  1-31, L (last day), nW (weekday nearest to n), LW
+//	month         1-12 or JAN-DEC
+//	day of week   1-7 or SUN-SAT, where 1 is Sunday; nL (last n of the
+//	              month) and n#k (k-th n of the month)
+//
+// Every field accepts *, lists separated by commas, ranges like 1-5 and
+// steps like */15 or 10-50/20. As with standard cron, when both day fields
+// are restricted a day matching either of them fires.
+type WorkerCronSchedule struct {
+	expr   string
+	minute uint64
+	hour   uint64
+	month  uint64
+
+	dom         uint64
+	domLast     bool
+	domWeekdays []int // nW, with n == 0 for LW
+	dow         uint64
+	dowLast     []time.Weekday
+	dowNth      []cronNthWeekday
+	domStar     bool
+	dowStar     bool
+}
+
+type cronNthWeekday struct {
+	weekday time.Weekday
+	n       int
+}
+
+var (
+	cronMonthNames = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+	cronDayNames   = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+)
+
+// cronField describes the range and names of a field.
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var (
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDOM    = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: cronMonthNames}
+	cronDOW    = cronField{name: "day of week", min: 1, max: 7, names: cronDayNames}
+)
+
+// ParseWorkerCron parses a Workers cron expression.
+func ParseWorkerCron(expr string) (*WorkerCronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("invalid cron %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	s := &WorkerCronSchedule{
+		expr:    expr,
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}
+	var err error
+	if s.minute, err = cronMinute.parse(fields[0], nil); err != nil {
+		return nil, errors.Wrapf(err, "invalid cron %q", expr)
+	}
+	if s.hour, err = cronHour.parse(fields[1], nil); err != nil {
+		return nil, errors.Wrapf(err, "invalid cron %q", expr)
+	}
+	if s.dom, err = cronDOM.parse(fields[2], s.parseDOMSpecial); err != nil {
+		return nil, errors.Wrapf(err, "invalid cron %q", expr)
+	}
+	if s.month, err = cronMonth.parse(fields[3], nil); err != nil {
+		return nil, errors.Wrapf(err, "invalid cron %q", expr)
+	}
+	if s.dow, err = cronDOW.parse(fields[4], s.parseDOWSpecial); err != nil {
+		return nil, errors.Wrapf(err, "invalid cron %q", expr)
+	}
+	return s, nil
+}
+
+// parse returns the set of values matched by a field. special is offered
+// each list item first and reports whether it handled it.
+func (f cronField) parse(field string, special func(string) (bool, error)) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		if special != nil {
+			ok, err := special(strings.ToUpper(item))
+			if err != nil {
+				return 0, err
+			}
+			if ok {
+				continue
+			}
+		}
+
+		rng, step := item, 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			var err error
+			rng = item[:i]
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step < 1 {
+				return 0, errors.Errorf("%s field: invalid step %q", f.name, item[i+1:])
+			}
+		}
+
+		lo, hi := f.min, f.max
+		switch i := strings.IndexByte(rng, '-'); {
+		case rng == "*":
+		case i >= 0:
+			var err error
+			if lo, err = f.value(rng[:i]); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(rng[i+1:]); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, errors.Errorf("%s field: range %q is backwards", f.name, rng)
+			}
+		default:
+			var err error
+			if lo, err = f.value(rng); err != nil {
+				return 0, err
+			}
+			// "5/15" means every 15 starting at 5.
+			if step > 1 || strings.Contains(item, "/") {
+				hi = f.max
+			} else {
+				hi = lo
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// value parses a single number or name of a field.
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, errors.Errorf("%s field: %q is not between %d and %d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+func (s *WorkerCronSchedule) parseDOMSpecial(item string) (bool, error) {
+	switch {
+	case item == "L":
+		s.domLast = true
+	case item == "LW":
+		s.domWeekdays = append(s.domWeekdays, 0)
+	case strings.HasSuffix(item, "W"):
+		n, err := cronDOM.value(strings.TrimSuffix(item, "W"))
+		if err != nil {
+			return false, err
+		}
+		s.domWeekdays = append(s.domWeekdays, n)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *WorkerCronSchedule) parseDOWSpecial(item string) (bool, error) {
+	switch {
+	case item == "L":
+		s.dowLast = append(s.dowLast, time.Saturday)
+	case strings.HasSuffix(item, "L"):
+		d, err := cronDOW.value(strings.TrimSuffix(item, "L"))
+		if err != nil {
+			return false, err
+		}
+		s.dowLast = append(s.dowLast, time.Weekday(d-1))
+	case strings.Contains(item, "#"):
+		i := strings.IndexByte(item, '#')
+		d, err := cronDOW.value(item[:i])
+		if err != nil {
+			return false, err
+		}
+		n, err := strconv.Atoi(item[i+1:])
+		if err != nil || n < 1 || n > 5 {
+			return false, errors.Errorf("%s field: %q is not between 1 and 5", cronDOW.name, item[i+1:])
+		}
+		s.dowNth = append(s.dowNth, cronNthWeekday{weekday: time.Weekday(d - 1), n: n})
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// String returns the expression the schedule was parsed from.
+func (s *WorkerCronSchedule) String() string {
+	return s.expr
+}
+
+// cronSearchDays bounds the search for the next firing day. Eight years
+// covers schedules such as February 29th across a skipped leap year.
+const cronSearchDays = 8 * 366
+
+// Next returns the first time after t the schedule fires, in UTC, or the
+// zero time if it never does, as with "0 0 31 2 *".
+func (s *WorkerCronSchedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < cronSearchDays; i, day = i+1, day.AddDate(0, 0, 1) {
+		if s.month&(1<<uint(day.Month())) == 0 || !s.matchDay(day) {
+			continue
+		}
+
+		fromHour, fromMinute := 0, 0
+		if i == 0 {
+			fromHour, fromMinute = t.Hour(), t.Minute()
+		}
+		for h := fromHour; h < 24; h++ {
+			if s.hour&(1<<uint(h)) == 0 {
+				continue
+			}
+			m := 0
+			if h == fromHour {
+				m = fromMinute
+			}
+			if minutes := s.minute >> uint(m); minutes != 0 {
+				return day.Add(time.Duration(h)*time.Hour + time.Duration(m+bits.TrailingZeros64(minutes))*time.Minute)
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// NextN returns the next n times after t the schedule fires, in UTC.
+func (s *WorkerCronSchedule) NextN(t time.Time, n int) []time.Time {
+	var times []time.Time
+	for len(times) < n {
+		if t = s.Next(t); t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+func (s *WorkerCronSchedule) matchDay(day time.Time) bool {
+	domMatch := s.matchDOM(day)
+	dowMatch := s.matchDOW(day)
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func (s *WorkerCronSchedule) matchDOM(day time.Time) bool {
+	d, last := day.Day(), cronLastDay(day)
+	if s.dom&(1<<uint(d)) != 0 || (s.domLast && d == last) {
+		return true
+	}
+	for _, n := range s.domWeekdays {
+		if n == 0 {
+			n = last
+		}
+		if n <= last && cronNearestWeekday(day, n, last) == d {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *WorkerCronSchedule) matchDOW(day time.Time) bool {
+	wd, d := day.Weekday(), day.Day()
+	if s.dow&(1<<uint(wd+1)) != 0 {
+		return true
+	}
+	for _, l := range s.dowLast {
+		if wd == l && d+7 > cronLastDay(day) {
+			return true
+		}
+	}
+	for _, nth := range s.dowNth {
+		if wd == nth.weekday && (d-1)/7+1 == nth.n {
+			return true
+		}
+	}
+	return false
+}
+
+func cronLastDay(day time.Time) int {
+	return time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// cronNearestWeekday returns the weekday nearest to day n of the month of
+// day, without leaving the month.
+func cronNearestWeekday(day time.Time, n, last int) int {
+	switch time.Date(day.Year(), day.Month(), n, 0, 0, 0, 0, time.UTC).Weekday() {
+	case time.Saturday:
+		if n == 1 {
+			return 3
+		}
+		return n - 1
+	case time.Sunday:
+		if n == last {
+			return n - 2
+		}
+		return n + 1
+	}
+	return n
+}
+
+// Validate reports whether the trigger's cron expression is valid.
+func (t WorkerCronTrigger) Validate() error {
+	_, err := ParseWorkerCron(t.Cron)
+	return err
+}
+
+// WorkerCronOverlap is a pair of a script's cron triggers that fire at the
+// same minute.
+type WorkerCronOverlap struct {
+	Crons [2]string
+	// Count is the number of shared firings in the checked window and
+	// Times the first of them, up to five.
+	Count int
+	Times []time.Time
+}
+
+// cronOverlapMaxTimes is the number of shared firings kept per overlap.
+const cronOverlapMaxTimes = 5
+
+// WorkerCronOverlaps validates a script's cron triggers and reports the
+// pairs of them that fire at the same minute between from and from+window.
+func WorkerCronOverlaps(triggers []WorkerCronTrigger, from time.Time, window time.Duration) ([]WorkerCronOverlap, error) {
+	schedules := make([]*WorkerCronSchedule, len(triggers))
+	for i, trigger := range triggers {
+		s, err := ParseWorkerCron(trigger.Cron)
+		if err != nil {
+			return nil, err
+		}
+		schedules[i] = s
+	}
+
+	// Firings are whole minutes, so a from with seconds starts the window
+	// at the next minute.
+	first := from.UTC().Truncate(time.Minute)
+	if first.Before(from) {
+		first = first.Add(time.Minute)
+	}
+	end := from.Add(window)
+
+	var overlaps []WorkerCronOverlap
+	for i := range triggers {
+		for j := i + 1; j < len(triggers); j++ {
+			o := WorkerCronOverlap{Crons: [2]string{triggers[i].Cron, triggers[j].Cron}}
+			cronSharedFirings(schedules[i], schedules[j], first, end, func(t time.Time) {
+				o.Count++
+				if len(o.Times) < cronOverlapMaxTimes {
+					o.Times = append(o.Times, t)
+				}
+			})
+			if o.Count > 0 {
+				overlaps = append(overlaps, o)
+			}
+		}
+	}
+	return overlaps, nil
+}
+
+// cronSharedFirings calls fn, in order, for every time from first up to end
+// that both a and b fire at. It walks both schedules together, letting the
+// one behind skip ahead to the other's next firing.
+func cronSharedFirings(a, b *WorkerCronSchedule, first, end time.Time, fn func(time.Time)) {
+	ta := a.Next(first.Add(-time.Minute))
+	tb := b.Next(first.Add(-time.Minute))
+	for !ta.IsZero() && !tb.IsZero() && ta.Before(end) && tb.Before(end) {
+		switch {
+		case ta.Equal(tb):
+			fn(ta)
+			ta, tb = a.Next(ta), b.Next(tb)
+		case ta.Before(tb):
+			ta = a.Next(tb.Add(-time.Minute))
+		default:
+			tb = b.Next(ta.Add(-time.Minute))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package cloudflare
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorkerCron_Errors(t *testing.T) {
+	for expr, want := range map[string]string{
+		"* * * *":       `invalid cron "* * * *": expected 5 fields, got 4`,
+		"60 * * * *":    `invalid cron "60 * * * *": minute field: "60" is not between 0 and 59`,
+		"*/0 * * * *":   `invalid cron "*/0 * * * *": minute field: invalid step "0"`,
+		"0 5-2 * * *":   `invalid cron "0 5-2 * * *": hour field: range "5-2" is backwards`,
+		"0 0 * FOO *":   `invalid cron "0 0 * FOO *": month field: "FOO" is not between 1 and 12`,
+		"0 0 * * 0":     `invalid cron "0 0 * * 0": day of week field: "0" is not between 1 and 7`,
+		"0 0 * * MON#6": `invalid cron "0 0 * * MON#6": day of week field: "6" is not between 1 and 5`,
+		"0 0 32W * *":   `invalid cron "0 0 32W * *": day of month field: "32" is not between 1 and 31`,
+	} {
+		_, err := ParseWorkerCron(expr)
+		assert.EqualError(t, err, want, expr)
+	}
+
+	assert.Error(t, WorkerCronTrigger{Cron: "* * *"}.Validate())
+	assert.NoError(t, WorkerCronTrigger{Cron: "*/30 * * * *"}.Validate())
+}
+
+func TestWorkerCronSchedule_NextN(t *testing.T) {
+	// Thursday, 2021-09-30 10:07:30 UTC.
+	from := time.Date(2021, time.September, 30, 10, 7, 30, 0, time.UTC)
+	date := func(month time.Month, day, hour, minute int) time.Time {
+		year := 2021
+		if month < time.September {
+			year = 2022
+		}
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	for expr, want := range map[string][]time.Time{
+		"*/15 * * * *":    {date(9, 30, 10, 15), date(9, 30, 10, 30), date(9, 30, 10, 45)},
+		"5/20 9-11 * * *": {date(9, 30, 10, 25), date(9, 30, 10, 45), date(9, 30, 11, 5)},
+		"0 0 * * MON-FRI": {date(10, 1, 0, 0), date(10, 4, 0, 0), date(10, 5, 0, 0)},
+		"0 12 L * *":      {date(9, 30, 12, 0), date(10, 31, 12, 0), date(11, 30, 12, 0)},
+		"0 0 1W * *":      {date(10, 1, 0, 0), date(11, 1, 0, 0), date(12, 1, 0, 0)},
+		"0 0 15W * *":     {date(10, 15, 0, 0), date(11, 15, 0, 0), date(12, 15, 0, 0)},
+		"0 0 LW * *":      {date(10, 29, 0, 0), date(11, 30, 0, 0), date(12, 31, 0, 0)},
+		"30 8 * * 6L":     {date(10, 29, 8, 30), date(11, 26, 8, 30), date(12, 31, 8, 30)},
+		"0 9 * * 2#1":     {date(10, 4, 9, 0), date(11, 1, 9, 0), date(12, 6, 9, 0)},
+		"0 0 13 * 6":      {date(10, 1, 0, 0), date(10, 8, 0, 0), date(10, 13, 0, 0)},
+		"0 0 29 FEB *":    {time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	} {
+		s, err := ParseWorkerCron(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, want, s.NextN(from, len(want)), expr)
+	}
+
+	s, err := ParseWorkerCron("0 0 31 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(from).IsZero())
+	assert.Empty(t, s.NextN(from, 3))
+
+	s, err = ParseWorkerCron("* * * * *")
+	require.NoError(t, err)
+	local := time.Date(2021, time.September, 30, 12, 7, 0, 0, time.FixedZone("CEST", 2*60*60))
+	assert.Equal(t, date(9, 30, 10, 8), s.Next(local))
+	assert.Equal(t, time.UTC, s.Next(local).Location())
+}
+
+func TestWorkerCronOverlaps(t *testing.T) {
+	from := time.Date(2021, time.September, 30, 0, 0, 0, 0, time.UTC)
+	overlaps, err := WorkerCronOverlaps([]WorkerCronTrigger{
+		{Cron: "*/30 * * * *"},
+		{Cron: "0 */6 * * *"},
+		{Cron: "15 * * * *"},
+	}, from, 24*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, overlaps, 1)
+	assert.Equal(t, [2]string{"*/30 * * * *", "0 */6 * * *"}, overlaps[0].Crons)
+	assert.Equal(t, 4, overlaps[0].Count)
+	assert.Equal(t, []time.Time{from, from.Add(6 * time.Hour), from.Add(12 * time.Hour), from.Add(18 * time.Hour)}, overlaps[0].Times)
+
+	_, err = WorkerCronOverlaps([]WorkerCronTrigger{{Cron: "* * * * * *"}}, from, time.Hour)
+	assert.Error(t, err)
+}
+
+func TestWorkerCronOverlaps_FromWithSeconds(t *testing.T) {
+	from := time.Date(2021, time.September, 30, 12, 0, 30, 0, time.UTC)
+	overlaps, err := WorkerCronOverlaps([]WorkerCronTrigger{
+		{Cron: "0 * * * *"},
+		{Cron: "*/15 * * * *"},
+	}, from, 90*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, overlaps, 1)
+	assert.Equal(t, 1, overlaps[0].Count)
+	assert.Equal(t, []time.Time{time.Date(2021, time.September, 30, 13, 0, 0, 0, time.UTC)}, overlaps[0].Times)
+}
+
+func TestWorkerCronOverlaps_LongWindow(t *testing.T) {
+	from := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	overlaps, err := WorkerCronOverlaps([]WorkerCronTrigger{
+		{Cron: "* * * * *"},
+		{Cron: "0 0 1 * *"},
+	}, from, 365*24*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, overlaps, 1)
+	assert.Equal(t, 12, overlaps[0].Count)
+	assert.Len(t, overlaps[0].Times, cronOverlapMaxTimes)
+}
@@ -0,0 +1,151 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// WorkerDomain is a hostname served entirely by a Worker. Unlike a route,
+// the hostname's DNS record and certificate are managed by Cloudflare.
+type WorkerDomain struct {
+	ID       string `json:"id,omitempty"`
+	ZoneID   string `json:"zone_id,omitempty"`
+	ZoneName string `json:"zone_name,omitempty"`
+	Hostname string `json:"hostname"`
+	// Service is the script the hostname is attached to and Environment
+	// the environment of it, "production" unless set.
+	Service     string `json:"service"`
+	Environment string `json:"environment,omitempty"`
+}
+
+// WorkerDomainResponse is the response received for a single Custom Domain.
+type WorkerDomainResponse struct {
+	Response
+	Result WorkerDomain `json:"result"`
+}
+
+// WorkerDomainsResponse is the response received when listing Custom
+// Domains.
+type WorkerDomainsResponse struct {
+	Response
+	Result []WorkerDomain `json:"result"`
+}
+
+// WorkerDomainFilter narrows the Custom Domains returned by
+// ListWorkerDomains. Empty fields match everything.
+type WorkerDomainFilter struct {
+	ZoneID      string
+	ZoneName    string
+	Hostname    string
+	Service     string
+	Environment string
+}
+
+func (f WorkerDomainFilter) encode() string {
+	v := url.Values{}
+	for key, value := range map[string]string{
+		"zone_id":     f.ZoneID,
+		"zone_name":   f.ZoneName,
+		"hostname":    f.Hostname,
+		"service":     f.Service,
+		"environment": f.Environment,
+	} {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	return v.Encode()
+}
+
+// ListWorkerDomains returns the account's Workers Custom Domains.
+//
+// API reference: https://api.cloudflare.com/#worker-domain-list-domains
+func (api *API) ListWorkerDomains(ctx context.Context, filter WorkerDomainFilter) ([]WorkerDomain, error) {
+	if err := api.checkAccountID(); err != nil {
+		return nil, err
+	}
+
+	uri := fmt.Sprintf("/accounts/%s/workers/domains", api.AccountID)
+	if q := filter.encode(); q != "" {
+		uri += "?" + q
+	}
+	res, err := api.makeRequestContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var r WorkerDomainsResponse
+	if err := json.Unmarshal(res, &r); err != nil {
+		return nil, errors.Wrap(err, errUnmarshalError)
+	}
+	return r.Result, nil
+}
+
+// WorkerDomain returns a single Custom Domain.
+//
+// API reference: https://api.cloudflare.com/#worker-domain-get-a-domain
+func (api *API) WorkerDomain(ctx context.Context, domainID string) (WorkerDomain, error) {
+	if err := api.checkAccountID(); err != nil {
+		return WorkerDomain{}, err
+	}
+
+	uri := fmt.Sprintf("/accounts/%s/workers/domains/%s", api.AccountID, domainID)
+	res, err := api.makeRequestContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return WorkerDomain{}, err
+	}
+
+	var r WorkerDomainResponse
+	if err := json.Unmarshal(res, &r); err != nil {
+		return WorkerDomain{}, errors.Wrap(err, errUnmarshalError)
+	}
+	return r.Result, nil
+}
+
+// AttachWorkerDomain attaches a hostname to a script, or re-attaches it if
+// it is already attached elsewhere. ZoneID, Hostname and Service are
+// required.
+//
+// API reference: https://api.cloudflare.com/#worker-domain-attach-to-domain
+func (api *API) AttachWorkerDomain(ctx context.Context, domain WorkerDomain) (WorkerDomain, error) {
+	if err := api.checkAccountID(); err != nil {
+		return WorkerDomain{}, err
+	}
+	if domain.ZoneID == "" || domain.Hostname == "" || domain.Service == "" {
+		return WorkerDomain{}, errors.New("ZoneID, Hostname and Service are required")
+	}
+	if domain.Environment == "" {
+		domain.Environment = "production"
+	}
+
+	uri := fmt.Sprintf("/accounts/%s/workers/domains", api.AccountID)
+	res, err := api.makeRequestContext(ctx, http.MethodPut, uri, domain)
+	if err != nil {
+		return WorkerDomain{}, err
+	}
+
+	var r WorkerDomainResponse
+	if err := json.Unmarshal(res, &r); err != nil {
+		return WorkerDomain{}, errors.Wrap(err, errUnmarshalError)
+	}
+	return r.Result, nil
+}
+
+// DetachWorkerDomain detaches a Custom Domain from its script, removing the
+// DNS record and certificate that were created for it.
+//
+// API reference: https://api.cloudflare.com/#worker-domain-detach-from-domain
+func (api *API) DetachWorkerDomain(ctx context.Context, domainID string) error {
+	if err := api.checkAccountID(); err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("/accounts/%s/workers/domains/%s", api.AccountID, domainID)
+	_, err := api.makeRequestContext(ctx, http.MethodDelete, uri, nil)
+	return err
+}
@@ -0,0 +1,108 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWorkerDomain = `{
+	"id": "dbe10b4bc17c295377eabd600e1787fd",
+	"zone_id": "` + testZoneID + `",
+	"zone_name": "example.com",
+	"hostname": "api.example.com",
+	"service": "api",
+	"environment": "production"
+}`
+
+var expectedWorkerDomain = WorkerDomain{
+	ID:          "dbe10b4bc17c295377eabd600e1787fd",
+	ZoneID:      testZoneID,
+	ZoneName:    "example.com",
+	Hostname:    "api.example.com",
+	Service:     "api",
+	Environment: "production",
+}
+
+func TestListWorkerDomains(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/domains", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		assert.Equal(t, "service=api&zone_name=example.com", r.URL.RawQuery)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": [%s]}`, testWorkerDomain)
+	})
+
+	actual, err := client.ListWorkerDomains(context.Background(), WorkerDomainFilter{ZoneName: "example.com", Service: "api"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []WorkerDomain{expectedWorkerDomain}, actual)
+	}
+}
+
+func TestWorkerDomain(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/domains/dbe10b4bc17c295377eabd600e1787fd", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": %s}`, testWorkerDomain)
+	})
+
+	actual, err := client.WorkerDomain(context.Background(), "dbe10b4bc17c295377eabd600e1787fd")
+	if assert.NoError(t, err) {
+		assert.Equal(t, expectedWorkerDomain, actual)
+	}
+}
+
+func TestAttachWorkerDomain(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/domains", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method, "Expected method 'PUT', got %s", r.Method)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]interface{}{
+			"zone_id":     testZoneID,
+			"hostname":    "api.example.com",
+			"service":     "api",
+			"environment": "production",
+		}, body)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": %s}`, testWorkerDomain)
+	})
+
+	actual, err := client.AttachWorkerDomain(context.Background(), WorkerDomain{
+		ZoneID:   testZoneID,
+		Hostname: "api.example.com",
+		Service:  "api",
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, expectedWorkerDomain, actual)
+	}
+
+	_, err = client.AttachWorkerDomain(context.Background(), WorkerDomain{Hostname: "api.example.com"})
+	assert.EqualError(t, err, "ZoneID, Hostname and Service are required")
+}
+
+func TestDetachWorkerDomain(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/domains/dbe10b4bc17c295377eabd600e1787fd", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method, "Expected method 'DELETE', got %s", r.Method)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": null}`)
+	})
+
+	err := client.DetachWorkerDomain(context.Background(), "dbe10b4bc17c295377eabd600e1787fd")
+	assert.NoError(t, err)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -63,6 +65,29 @@ type ListWorkersKVsOptions struct {
 	Prefix *string
 }
 
+// WorkersKVWriteOptions contains optional parameters for writing a single
+// key. Expiration is an absolute UNIX timestamp and ExpirationTTL a number
+// of seconds from now; at most one of them should be set.
+type WorkersKVWriteOptions struct {
+	Expiration    int
+	ExpirationTTL int
+	Metadata      interface{}
+}
+
+// WorkersKVValue is a value read together with its metadata. Value streams
+// the stored bytes and must be closed.
+type WorkersKVValue struct {
+	Value    io.ReadCloser
+	Metadata interface{}
+}
+
+// WorkersKVMetadataResponse is the response received when reading a key's
+// metadata.
+type WorkersKVMetadataResponse struct {
+	Response
+	Result interface{} `json:"result"`
+}
+
 // ListStorageKeysResponse contains a slice of keys belonging to a storage namespace,
 // pagination information, and an embedded response struct
 type ListStorageKeysResponse struct {
@@ -186,6 +211,83 @@ func (api *API) WriteWorkersKV(ctx context.Context, namespaceID, key string, val
 	return result, err
 }
 
+// encode encodes non-zero expirations into URL encoded form.
+func (o WorkersKVWriteOptions) encode() string {
+	v := url.Values{}
+	if o.Expiration != 0 {
+		v.Set("expiration", strconv.Itoa(o.Expiration))
+	}
+	if o.ExpirationTTL != 0 {
+		v.Set("expiration_ttl", strconv.Itoa(o.ExpirationTTL))
+	}
+	return v.Encode()
+}
+
+// WriteWorkersKVWithOptions writes a value identified by a key, with an
+// optional expiration and metadata. The value is streamed from r as a
+// multipart form, so it is never held in memory; as a consequence the
+// request is not retried.
+//
+// API reference: https://api.cloudflare.com/#workers-kv-namespace-write-key-value-pair-with-metadata
+func (api *API) WriteWorkersKVWithOptions(ctx context.Context, namespaceID, key string, r io.Reader, o WorkersKVWriteOptions) (Response, error) {
+	var metadata []byte
+	if o.Metadata != nil {
+		var err error
+		if metadata, err = json.Marshal(o.Metadata); err != nil {
+			return Response{}, errors.Wrap(err, "error marshalling metadata to JSON")
+		}
+	}
+
+	key = url.PathEscape(key)
+	uri := fmt.Sprintf("/accounts/%s/storage/kv/namespaces/%s/values/%s", api.AccountID, namespaceID, key)
+	if q := o.encode(); q != "" {
+		uri += "?" + q
+	}
+
+	pr, pw := io.Pipe()
+	mpw := multipart.NewWriter(pw)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(writeWorkersKVForm(mpw, r, metadata))
+	}()
+
+	body, err := api.makeStreamingRequestContext(ctx, http.MethodPut, uri, pr, http.Header{
+		"Content-Type": []string{mpw.FormDataContentType()},
+	})
+	// Unblock the writer if the request ended before reading the whole form,
+	// and wait for it so r is no longer in use once this returns.
+	pr.Close()
+	<-done
+	if err != nil {
+		return Response{}, err
+	}
+	defer body.Close()
+
+	result := Response{}
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return result, errors.Wrap(err, errUnmarshalError)
+	}
+
+	return result, err
+}
+
+func writeWorkersKVForm(mpw *multipart.Writer, r io.Reader, metadata []byte) error {
+	if metadata != nil {
+		if err := mpw.WriteField("metadata", string(metadata)); err != nil {
+			return err
+		}
+	}
+	part, err := mpw.CreateFormField("value")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return err
+	}
+	return mpw.Close()
+}
+
 // WriteWorkersKVBulk writes multiple KVs at once.
 //
 // API reference: https://api.cloudflare.com/#workers-kv-namespace-write-multiple-key-value-pairs
@@ -219,6 +321,57 @@ func (api API) ReadWorkersKV(ctx context.Context, namespaceID, key string) ([]by
 	return res, nil
 }
 
+// ReadWorkersKVStream returns the value associated with the given key in the
+// given namespace as a stream, which the caller must close.
+//
+// API reference: https://api.cloudflare.com/#workers-kv-namespace-read-key-value-pair
+func (api *API) ReadWorkersKVStream(ctx context.Context, namespaceID, key string) (io.ReadCloser, error) {
+	key = url.PathEscape(key)
+	uri := fmt.Sprintf("/accounts/%s/storage/kv/namespaces/%s/values/%s", api.AccountID, namespaceID, key)
+	return api.makeStreamingRequestContext(ctx, http.MethodGet, uri, nil, nil)
+}
+
+// ReadWorkersKVMetadata returns the metadata associated with the given key
+// in the given namespace, or nil if it has none.
+//
+// API reference: https://api.cloudflare.com/#workers-kv-namespace-read-the-metadata-for-a-key
+func (api *API) ReadWorkersKVMetadata(ctx context.Context, namespaceID, key string) (interface{}, error) {
+	key = url.PathEscape(key)
+	uri := fmt.Sprintf("/accounts/%s/storage/kv/namespaces/%s/metadata/%s", api.AccountID, namespaceID, key)
+	res, err := api.makeRequestContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := WorkersKVMetadataResponse{}
+	if err := json.Unmarshal(res, &result); err != nil {
+		return nil, errors.Wrap(err, errUnmarshalError)
+	}
+	return result.Result, nil
+}
+
+// ReadWorkersKVWithMetadata returns the value associated with the given key
+// in the given namespace as a stream, along with the key's metadata. The
+// caller must close the returned Value.
+//
+// The metadata and the value are fetched with two separate requests, so the
+// result is not atomic: if the key is written in between, the metadata may
+// belong to a different version of the value.
+//
+// API reference: https://api.cloudflare.com/#workers-kv-namespace-read-the-metadata-for-a-key
+func (api *API) ReadWorkersKVWithMetadata(ctx context.Context, namespaceID, key string) (WorkersKVValue, error) {
+	metadata, err := api.ReadWorkersKVMetadata(ctx, namespaceID, key)
+	if err != nil {
+		return WorkersKVValue{}, err
+	}
+
+	value, err := api.ReadWorkersKVStream(ctx, namespaceID, key)
+	if err != nil {
+		return WorkersKVValue{}, err
+	}
+	return WorkersKVValue{Value: value, Metadata: metadata}, nil
+}
+
 // DeleteWorkersKV deletes a key and value for a provided storage namespace
 //
 // API reference: https://api.cloudflare.com/#workers-kv-namespace-delete-key-value-pair
@@ -0,0 +1,286 @@
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// WorkersKVBulkMaxPairs is the most pairs or keys the bulk endpoints
+	// accept in a single request.
+	WorkersKVBulkMaxPairs = 10000
+	// WorkersKVBulkMaxBytes is the largest request body the bulk endpoints
+	// accept.
+	WorkersKVBulkMaxBytes = 100 * 1000 * 1000
+
+	defaultWorkersKVBulkConcurrency = 4
+)
+
+// WorkersKVBulkOptions configures how WriteWorkersKVBulkChunked and friends
+// split their input. Zero values use the API limits.
+type WorkersKVBulkOptions struct {
+	MaxPairs int
+	MaxBytes int
+	// Concurrency is the number of chunks in flight at once. Requests
+	// still go through the client's rate limiter.
+	Concurrency int
+}
+
+// WorkersKVBulkFailure is a key that could not be written or deleted.
+type WorkersKVBulkFailure struct {
+	Key string
+	Err error
+}
+
+// WorkersKVBulkResult reports the outcome of a chunked bulk operation.
+// A failed chunk fails every key in it.
+type WorkersKVBulkResult struct {
+	Succeeded int
+	Failed    []WorkersKVBulkFailure
+}
+
+// workersKVBulkChunk is a JSON array body ready to be sent, along with the
+// keys it contains.
+type workersKVBulkChunk struct {
+	keys []string
+	body []byte
+}
+
+// WriteWorkersKVBulkChunked writes any number of pairs, splitting them into
+// requests within the API limits and sending the requests in parallel.
+//
+// Only context cancellation is returned as an error; failed requests are
+// reported per key in the result.
+//
+// API reference: https://api.cloudflare.com/#workers-kv-namespace-write-multiple-key-value-pairs
+func (api *API) WriteWorkersKVBulkChunked(ctx context.Context, namespaceID string, kvs WorkersKVBulkWriteRequest, opts WorkersKVBulkOptions) (WorkersKVBulkResult, error) {
+	i := 0
+	return api.runWorkersKVBulk(ctx, http.MethodPut, namespaceID, opts, func() (string, []byte, error) {
+		for i < len(kvs) && kvs[i] == nil {
+			i++
+		}
+		if i == len(kvs) {
+			return "", nil, io.EOF
+		}
+		kv := kvs[i]
+		i++
+		b, err := json.Marshal(kv)
+		return kv.Key, b, err
+	})
+}
+
+// WriteWorkersKVBulkNDJSON writes the pairs read from r, one JSON encoded
+// WorkersKVPair per line. Input is consumed as chunks are sent, so at most
+// Concurrency chunks are held in memory.
+//
+// A malformed line stops reading and is returned as an error, after the
+// chunks already read have been sent.
+//
+// API reference: https://api.cloudflare.com/#workers-kv-namespace-write-multiple-key-value-pairs
+func (api *API) WriteWorkersKVBulkNDJSON(ctx context.Context, namespaceID string, r io.Reader, opts WorkersKVBulkOptions) (WorkersKVBulkResult, error) {
+	dec := json.NewDecoder(r)
+	line := 0
+	return api.runWorkersKVBulk(ctx, http.MethodPut, namespaceID, opts, func() (string, []byte, error) {
+		var kv WorkersKVPair
+		line++
+		if err := dec.Decode(&kv); err != nil {
+			if err == io.EOF {
+				return "", nil, err
+			}
+			return "", nil, errors.Wrapf(err, "failed to decode pair %d", line)
+		}
+		if kv.Key == "" {
+			return "", nil, errors.Errorf("pair %d has no key", line)
+		}
+		b, err := json.Marshal(kv)
+		return kv.Key, b, err
+	})
+}
+
+// DeleteWorkersKVBulkChunked deletes any number of keys, splitting them into
+// requests within the API limits and sending the requests in parallel.
+//
+// Only context cancellation is returned as an error; failed requests are
+// reported per key in the result.
+//
+// API reference: https://api.cloudflare.com/#workers-kv-namespace-delete-multiple-key-value-pairs
+func (api *API) DeleteWorkersKVBulkChunked(ctx context.Context, namespaceID string, keys []string, opts WorkersKVBulkOptions) (WorkersKVBulkResult, error) {
+	i := 0
+	return api.runWorkersKVBulk(ctx, http.MethodDelete, namespaceID, opts, func() (string, []byte, error) {
+		if i == len(keys) {
+			return "", nil, io.EOF
+		}
+		key := keys[i]
+		i++
+		b, err := json.Marshal(key)
+		return key, b, err
+	})
+}
+
+// DeleteWorkersKVBulkNDJSON deletes the keys read from r. Each line is
+// either a JSON string or an object with a "key" field, so the input of
+// WriteWorkersKVBulkNDJSON can be used to delete what it wrote.
+//
+// API reference: https://api.cloudflare.com/#workers-kv-namespace-delete-multiple-key-value-pairs
+func (api *API) DeleteWorkersKVBulkNDJSON(ctx context.Context, namespaceID string, r io.Reader, opts WorkersKVBulkOptions) (WorkersKVBulkResult, error) {
+	dec := json.NewDecoder(r)
+	line := 0
+	return api.runWorkersKVBulk(ctx, http.MethodDelete, namespaceID, opts, func() (string, []byte, error) {
+		var raw json.RawMessage
+		line++
+		if err := dec.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return "", nil, err
+			}
+			return "", nil, errors.Wrapf(err, "failed to decode key %d", line)
+		}
+
+		var key string
+		if err := json.Unmarshal(raw, &key); err != nil {
+			var kv WorkersKVPair
+			if err := json.Unmarshal(raw, &kv); err != nil {
+				return "", nil, errors.Wrapf(err, "failed to decode key %d", line)
+			}
+			key = kv.Key
+		}
+		if key == "" {
+			return "", nil, errors.Errorf("key %d is empty", line)
+		}
+		b, err := json.Marshal(key)
+		return key, b, err
+	})
+}
+
+// runWorkersKVBulk reads encoded items from next until io.EOF, packs them
+// into JSON arrays within the limits in opts and sends those with method
+// to the bulk endpoint from opts.Concurrency goroutines.
+func (api *API) runWorkersKVBulk(ctx context.Context, method, namespaceID string, opts WorkersKVBulkOptions, next func() (string, []byte, error)) (WorkersKVBulkResult, error) {
+	if opts.MaxPairs <= 0 || opts.MaxPairs > WorkersKVBulkMaxPairs {
+		opts.MaxPairs = WorkersKVBulkMaxPairs
+	}
+	if opts.MaxBytes <= 0 || opts.MaxBytes > WorkersKVBulkMaxBytes {
+		opts.MaxBytes = WorkersKVBulkMaxBytes
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultWorkersKVBulkConcurrency
+	}
+
+	var (
+		mu     sync.Mutex
+		result WorkersKVBulkResult
+		wg     sync.WaitGroup
+		chunks = make(chan workersKVBulkChunk)
+	)
+	fail := func(keys []string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, key := range keys {
+			result.Failed = append(result.Failed, WorkersKVBulkFailure{Key: key, Err: err})
+		}
+	}
+
+	uri := fmt.Sprintf("/accounts/%s/storage/kv/namespaces/%s/bulk", api.AccountID, namespaceID)
+	headers := http.Header{"Content-Type": []string{"application/json"}}
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				if err := api.sendWorkersKVBulkChunk(ctx, method, uri, headers, chunk.body); err != nil {
+					fail(chunk.keys, err)
+					continue
+				}
+				mu.Lock()
+				result.Succeeded += len(chunk.keys)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var (
+		keys []string
+		body bytes.Buffer
+		err  error
+	)
+	send := func() bool {
+		if len(keys) == 0 {
+			return true
+		}
+		body.WriteByte(']')
+		chunk := workersKVBulkChunk{keys: keys, body: append([]byte(nil), body.Bytes()...)}
+		keys = nil
+		body.Reset()
+		select {
+		case chunks <- chunk:
+			return true
+		case <-ctx.Done():
+			err = ctx.Err()
+			return false
+		}
+	}
+
+	for {
+		key, item, nextErr := next()
+		if nextErr == io.EOF {
+			send()
+			break
+		}
+		if nextErr != nil {
+			if send() {
+				err = nextErr
+			}
+			break
+		}
+
+		// A comma or an opening bracket precedes every item and a closing
+		// bracket ends the array.
+		if len(item)+2 > opts.MaxBytes {
+			fail([]string{key}, errors.Errorf("pair is %d bytes, over the %d byte request limit", len(item), opts.MaxBytes))
+			continue
+		}
+		if len(keys) == opts.MaxPairs || body.Len()+len(item)+2 > opts.MaxBytes {
+			if !send() {
+				break
+			}
+		}
+		if len(keys) == 0 {
+			body.WriteByte('[')
+		} else {
+			body.WriteByte(',')
+		}
+		body.Write(item)
+		keys = append(keys, key)
+	}
+	close(chunks)
+	wg.Wait()
+
+	sort.SliceStable(result.Failed, func(i, j int) bool { return result.Failed[i].Key < result.Failed[j].Key })
+	if err == nil {
+		err = ctx.Err()
+	}
+	return result, err
+}
+
+func (api *API) sendWorkersKVBulkChunk(ctx context.Context, method, uri string, headers http.Header, body []byte) error {
+	res, err := api.makeRequestContextWithHeaders(ctx, method, uri, body, headers)
+	if err != nil {
+		return err
+	}
+
+	var r Response
+	if err := json.Unmarshal(res, &r); err != nil {
+		return errors.Wrap(err, errUnmarshalError)
+	}
+	if !r.Success {
+		return errors.New(errRequestNotSuccessful)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bulkRecorder records the bodies sent to a namespace's bulk endpoint,
+// failing requests that contain failKey.
+type bulkRecorder struct {
+	mu      sync.Mutex
+	method  string
+	failKey string
+	bodies  []string
+}
+
+func (b *bulkRecorder) register(t *testing.T, namespace string) {
+	mux.HandleFunc("/accounts/"+testAccountID+"/storage/kv/namespaces/"+namespace+"/bulk", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, b.method, r.Method, "Expected method '%s', got %s", b.method, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var body []json.RawMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		raw, _ := json.Marshal(body)
+
+		b.mu.Lock()
+		b.bodies = append(b.bodies, string(raw))
+		b.mu.Unlock()
+
+		w.Header().Set("content-type", "application/json")
+		if b.failKey != "" && strings.Contains(string(raw), b.failKey) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"success": false, "errors": [{"code": 10001, "message": "bad request"}], "messages": [], "result": null}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": null}`)
+	})
+}
+
+func TestWriteWorkersKVBulkChunked(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+	namespace := "3aeaxxxxee014exxxx4cf66xxxxc0448"
+
+	rec := &bulkRecorder{method: http.MethodPut, failKey: `"key":"k3"`}
+	rec.register(t, namespace)
+
+	var kvs WorkersKVBulkWriteRequest
+	for i := 0; i < 5; i++ {
+		kvs = append(kvs, &WorkersKVPair{Key: fmt.Sprintf("k%d", i), Value: "v", ExpirationTTL: 60})
+	}
+	kvs = append(kvs, &WorkersKVPair{Key: "huge", Value: strings.Repeat("x", 200)})
+
+	res, err := client.WriteWorkersKVBulkChunked(context.Background(), namespace, kvs, WorkersKVBulkOptions{MaxPairs: 2, MaxBytes: 150, Concurrency: 2})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, res.Succeeded)
+	require.Len(t, res.Failed, 3)
+	assert.Equal(t, "huge", res.Failed[0].Key)
+	assert.EqualError(t, res.Failed[0].Err, "pair is 225 bytes, over the 150 byte request limit")
+	assert.Equal(t, "k2", res.Failed[1].Key)
+	assert.Equal(t, "k3", res.Failed[2].Key)
+
+	assert.ElementsMatch(t, []string{
+		`[{"key":"k0","value":"v","expiration_ttl":60},{"key":"k1","value":"v","expiration_ttl":60}]`,
+		`[{"key":"k2","value":"v","expiration_ttl":60},{"key":"k3","value":"v","expiration_ttl":60}]`,
+		`[{"key":"k4","value":"v","expiration_ttl":60}]`,
+	}, rec.bodies)
+}
+
+func TestWriteWorkersKVBulkChunked_MaxBytes(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+	namespace := "3aeaxxxxee014exxxx4cf66xxxxc0448"
+
+	rec := &bulkRecorder{method: http.MethodPut}
+	rec.register(t, namespace)
+
+	// Each pair encodes to 33 bytes, so two fit in 69 but three don't.
+	kvs := WorkersKVBulkWriteRequest{
+		{Key: "a", Value: "0123456789"},
+		{Key: "b", Value: "0123456789"},
+		{Key: "c", Value: "0123456789"},
+	}
+	res, err := client.WriteWorkersKVBulkChunked(context.Background(), namespace, kvs, WorkersKVBulkOptions{MaxBytes: 69, Concurrency: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Succeeded)
+	assert.Empty(t, res.Failed)
+	assert.Equal(t, []string{
+		`[{"key":"a","value":"0123456789"},{"key":"b","value":"0123456789"}]`,
+		`[{"key":"c","value":"0123456789"}]`,
+	}, rec.bodies)
+}
+
+func TestWriteWorkersKVBulkNDJSON(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+	namespace := "3aeaxxxxee014exxxx4cf66xxxxc0448"
+
+	rec := &bulkRecorder{method: http.MethodPut}
+	rec.register(t, namespace)
+
+	input := `{"key": "a", "value": "1", "metadata": {"owner": "ops"}}
+{"key": "b", "value": "Mg==", "base64": true}
+
+{"key": "c", "value": "3", "expiration": 1893456000}
+`
+	res, err := client.WriteWorkersKVBulkNDJSON(context.Background(), namespace, strings.NewReader(input), WorkersKVBulkOptions{MaxPairs: 2, Concurrency: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Succeeded)
+	assert.Equal(t, []string{
+		`[{"key":"a","value":"1","metadata":{"owner":"ops"}},{"key":"b","value":"Mg==","base64":true}]`,
+		`[{"key":"c","value":"3","expiration":1893456000}]`,
+	}, rec.bodies)
+
+	res, err = client.WriteWorkersKVBulkNDJSON(context.Background(), namespace, strings.NewReader(`{"key": "d", "value": "4"}`+"\n"+`{"value": "5"}`), WorkersKVBulkOptions{})
+	assert.EqualError(t, err, "pair 2 has no key")
+	assert.Equal(t, 1, res.Succeeded)
+}
+
+func TestDeleteWorkersKVBulkNDJSON(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+	namespace := "3aeaxxxxee014exxxx4cf66xxxxc0448"
+
+	rec := &bulkRecorder{method: http.MethodDelete}
+	rec.register(t, namespace)
+
+	input := `"a"
+{"key": "b", "value": "ignored"}
+"c"
+`
+	res, err := client.DeleteWorkersKVBulkNDJSON(context.Background(), namespace, strings.NewReader(input), WorkersKVBulkOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, WorkersKVBulkResult{Succeeded: 3}, res)
+	assert.Equal(t, []string{`["a","b","c"]`}, rec.bodies)
+}
+
+func TestDeleteWorkersKVBulkChunked(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+	namespace := "3aeaxxxxee014exxxx4cf66xxxxc0448"
+
+	rec := &bulkRecorder{method: http.MethodDelete}
+	rec.register(t, namespace)
+
+	res, err := client.DeleteWorkersKVBulkChunked(context.Background(), namespace, []string{"a", "b", "c"}, WorkersKVBulkOptions{MaxPairs: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Succeeded)
+	assert.ElementsMatch(t, []string{`["a"]`, `["b"]`, `["c"]`}, rec.bodies)
+}
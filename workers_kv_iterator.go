@@ -0,0 +1,190 @@
+package cloudflare
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// WorkersKVIteratorOptions configures a WorkersKVIterator.
+type WorkersKVIteratorOptions struct {
+	// Prefix restricts the iteration to keys starting with it.
+	Prefix string
+	// Cursor resumes an earlier iteration from WorkersKVIterator.Cursor.
+	Cursor string
+	// PageSize is the number of keys requested per list call, up to the
+	// API maximum of 1000. Zero uses the API default.
+	PageSize int
+	// FetchValues reads the value of every key, Concurrency at a time.
+	// Keys deleted between listing and reading are skipped.
+	FetchValues bool
+	Concurrency int
+}
+
+// WorkersKVEntry is a key visited by a WorkersKVIterator. Expiration and
+// Metadata come from the key listing; Value is only set when
+// FetchValues is enabled.
+type WorkersKVEntry struct {
+	StorageKey
+	Value []byte
+}
+
+// WorkersKVIterator walks every key of a namespace, following list cursors
+// until the last page. Use it like a bufio.Scanner:
+//
+//	it := api.WorkersKVIterator(ctx, namespaceID, WorkersKVIteratorOptions{Prefix: "user:"})
+//	for it.Next() {
+//		fmt.Println(it.Entry().Name)
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type WorkersKVIterator struct {
+	api         *API
+	ctx         context.Context
+	namespaceID string
+	opts        WorkersKVIteratorOptions
+
+	page    []WorkersKVEntry
+	entry   WorkersKVEntry
+	cursor  string
+	started bool
+	err     error
+}
+
+const defaultWorkersKVIteratorConcurrency = 10
+
+// WorkersKVIterator returns an iterator over the keys of a namespace. No
+// request is made until the first call to Next.
+func (api *API) WorkersKVIterator(ctx context.Context, namespaceID string, opts WorkersKVIteratorOptions) *WorkersKVIterator {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultWorkersKVIteratorConcurrency
+	}
+	return &WorkersKVIterator{
+		api:         api,
+		ctx:         ctx,
+		namespaceID: namespaceID,
+		opts:        opts,
+		cursor:      opts.Cursor,
+	}
+}
+
+// Next advances to the next key, fetching the next page when needed. It
+// returns false at the end of the namespace or on error.
+func (it *WorkersKVIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || (it.started && it.cursor == "") {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		if err := it.fetchPage(); err != nil {
+			it.err = err
+			return false
+		}
+	}
+
+	it.entry, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Entry returns the key the iterator is positioned on.
+func (it *WorkersKVIterator) Entry() WorkersKVEntry {
+	return it.entry
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *WorkersKVIterator) Err() error {
+	return it.err
+}
+
+// Cursor returns the cursor of the page after the current one, which can
+// be passed as WorkersKVIteratorOptions.Cursor to resume iterating. It is
+// empty once the last page has been fetched.
+func (it *WorkersKVIterator) Cursor() string {
+	return it.cursor
+}
+
+func (it *WorkersKVIterator) fetchPage() error {
+	o := ListWorkersKVsOptions{}
+	if it.opts.Prefix != "" {
+		o.Prefix = &it.opts.Prefix
+	}
+	if it.opts.PageSize > 0 {
+		o.Limit = &it.opts.PageSize
+	}
+	if it.cursor != "" {
+		cursor := it.cursor
+		o.Cursor = &cursor
+	}
+
+	res, err := it.api.ListWorkersKVsWithOptions(it.ctx, it.namespaceID, o)
+	if err != nil {
+		return err
+	}
+	it.started = true
+	it.cursor = res.ResultInfo.Cursor
+
+	page := make([]WorkersKVEntry, len(res.Result))
+	for i, key := range res.Result {
+		page[i].StorageKey = key
+	}
+	if it.opts.FetchValues {
+		if page, err = it.fetchValues(page); err != nil {
+			return err
+		}
+	}
+	it.page = page
+	return nil
+}
+
+// fetchValues reads the values of a page of keys with a bounded number of
+// concurrent requests, dropping keys that no longer exist.
+func (it *WorkersKVIterator) fetchValues(page []WorkersKVEntry) ([]WorkersKVEntry, error) {
+	ctx, cancel := context.WithCancel(it.ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		missing  = make([]bool, len(page))
+		sem      = make(chan struct{}, it.opts.Concurrency)
+	)
+	for i := range page {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+
+			value, err := it.api.ReadWorkersKV(ctx, it.namespaceID, page[i].Name)
+			switch {
+			case isNotFound(err):
+				missing[i] = true
+			case err != nil:
+				once.Do(func() {
+					firstErr = errors.Wrapf(err, "failed to read %q", page[i].Name)
+					cancel()
+				})
+			default:
+				page[i].Value = value
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	entries := page[:0]
+	for i, entry := range page {
+		if !missing[i] {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,100 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkersKVIterator(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+	namespace := "3aeaxxxxee014exxxx4cf66xxxxc0448"
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/storage/kv/namespaces/"+namespace+"/keys", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		assert.Equal(t, "user:", r.URL.Query().Get("prefix"))
+		assert.Equal(t, "2", r.URL.Query().Get("limit"))
+		w.Header().Set("content-type", "application/json")
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [{"name": "user:1", "expiration": 1577836800}, {"name": "user:2", "metadata": {"role": "admin"}}], "result_info": {"count": 2, "cursor": "page2"}}`)
+		case "page2":
+			fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [], "result_info": {"count": 0, "cursor": "page3"}}`)
+		case "page3":
+			fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [{"name": "user:3"}], "result_info": {"count": 1, "cursor": ""}}`)
+		default:
+			t.Errorf("unexpected cursor %q", r.URL.Query().Get("cursor"))
+		}
+	})
+
+	it := client.WorkersKVIterator(context.Background(), namespace, WorkersKVIteratorOptions{Prefix: "user:", PageSize: 2})
+	var entries []WorkersKVEntry
+	for it.Next() {
+		entries = append(entries, it.Entry())
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []WorkersKVEntry{
+		{StorageKey: StorageKey{Name: "user:1", Expiration: 1577836800}},
+		{StorageKey: StorageKey{Name: "user:2", Metadata: map[string]interface{}{"role": "admin"}}},
+		{StorageKey: StorageKey{Name: "user:3"}},
+	}, entries)
+	assert.Empty(t, it.Cursor())
+	assert.False(t, it.Next())
+}
+
+func TestWorkersKVIterator_FetchValues(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+	namespace := "3aeaxxxxee014exxxx4cf66xxxxc0448"
+
+	prefix := "/accounts/" + testAccountID + "/storage/kv/namespaces/" + namespace
+	mux.HandleFunc(prefix+"/keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [{"name": "a"}, {"name": "deleted"}, {"name": "c/d"}], "result_info": {"count": 3, "cursor": ""}}`)
+	})
+
+	var reads int32
+	mux.HandleFunc(prefix+"/values/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		atomic.AddInt32(&reads, 1)
+		key := strings.TrimPrefix(r.URL.EscapedPath(), prefix+"/values/")
+		if key == "deleted" {
+			w.Header().Set("content-type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"success": false, "errors": [{"code": 10009, "message": "get: 'key not found'"}], "messages": [], "result": null}`)
+			return
+		}
+		fmt.Fprint(w, "value of "+key)
+	})
+
+	it := client.WorkersKVIterator(context.Background(), namespace, WorkersKVIteratorOptions{FetchValues: true, Concurrency: 2})
+	var entries []WorkersKVEntry
+	for it.Next() {
+		entries = append(entries, it.Entry())
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []WorkersKVEntry{
+		{StorageKey: StorageKey{Name: "a"}, Value: []byte("value of a")},
+		{StorageKey: StorageKey{Name: "c/d"}, Value: []byte("value of c%2Fd")},
+	}, entries)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&reads))
+}
+
+func TestWorkersKVIterator_ContextCanceled(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+	namespace := "3aeaxxxxee014exxxx4cf66xxxxc0448"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	it := client.WorkersKVIterator(ctx, namespace, WorkersKVIteratorOptions{})
+	assert.False(t, it.Next())
+	assert.Equal(t, context.Canceled, it.Err())
+}
@@ -0,0 +1,277 @@
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrKVKeyNotFound is returned by KVStore.Get for keys that don't exist or
+// have expired.
+var ErrKVKeyNotFound = errors.New("key not found")
+
+// KVStore is a Workers KV namespace. WorkersKVStore stores keys in
+// Cloudflare and MemoryKVStore in memory, for tests.
+type KVStore interface {
+	// Get returns the value and metadata of a key.
+	Get(ctx context.Context, key string) ([]byte, interface{}, error)
+	// Put writes a key, replacing its value, expiration and metadata.
+	Put(ctx context.Context, key string, value []byte, o WorkersKVWriteOptions) error
+	// Delete removes a key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// List returns a page of keys in lexicographic order, along with the
+	// cursor of the next page, which is empty on the last page.
+	List(ctx context.Context, o ListWorkersKVsOptions) ([]StorageKey, string, error)
+}
+
+// WorkersKVStore is a KVStore backed by a Workers KV namespace.
+type WorkersKVStore struct {
+	api         *API
+	namespaceID string
+}
+
+// NewWorkersKVStore returns a KVStore for the namespace with the given ID.
+func NewWorkersKVStore(api *API, namespaceID string) *WorkersKVStore {
+	return &WorkersKVStore{api: api, namespaceID: namespaceID}
+}
+
+// Get returns the value and metadata of a key, or ErrKVKeyNotFound.
+func (s *WorkersKVStore) Get(ctx context.Context, key string) ([]byte, interface{}, error) {
+	metadata, err := s.api.ReadWorkersKVMetadata(ctx, s.namespaceID, key)
+	if isNotFound(err) {
+		return nil, nil, ErrKVKeyNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	value, err := s.api.ReadWorkersKV(ctx, s.namespaceID, key)
+	if isNotFound(err) {
+		return nil, nil, ErrKVKeyNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, metadata, nil
+}
+
+// Put writes a key with WriteWorkersKVWithOptions.
+func (s *WorkersKVStore) Put(ctx context.Context, key string, value []byte, o WorkersKVWriteOptions) error {
+	_, err := s.api.WriteWorkersKVWithOptions(ctx, s.namespaceID, key, bytes.NewReader(value), o)
+	return err
+}
+
+// Delete removes a key.
+func (s *WorkersKVStore) Delete(ctx context.Context, key string) error {
+	_, err := s.api.DeleteWorkersKV(ctx, s.namespaceID, key)
+	return err
+}
+
+// List returns a page of keys with ListWorkersKVsWithOptions.
+func (s *WorkersKVStore) List(ctx context.Context, o ListWorkersKVsOptions) ([]StorageKey, string, error) {
+	res, err := s.api.ListWorkersKVsWithOptions(ctx, s.namespaceID, o)
+	if err != nil {
+		return nil, "", err
+	}
+	return res.Result, res.ResultInfo.Cursor, nil
+}
+
+// Limits enforced by MemoryKVStore, matching those of Workers KV.
+const (
+	kvMaxKeyBytes      = 512
+	kvMaxMetadataBytes = 1024
+	kvMinExpirationTTL = 60
+	kvDefaultListLimit = 1000
+	kvMinListLimit     = 10
+)
+
+// memoryKVEntry is a key stored by MemoryKVStore. Metadata is kept encoded
+// so callers get a fresh copy, decoded as it would be from the API.
+type memoryKVEntry struct {
+	value      []byte
+	expiration int
+	metadata   []byte
+}
+
+// MemoryKVStore is a KVStore held in memory. It validates keys,
+// expirations, metadata and list limits like Workers KV, hides expired
+// keys and pages List results with opaque cursors.
+//
+// The zero value is not usable; create one with NewMemoryKVStore.
+type MemoryKVStore struct {
+	// Now returns the current time, used for expirations. It defaults to
+	// time.Now and can be replaced to simulate the passing of time.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]memoryKVEntry
+}
+
+// NewMemoryKVStore returns an empty MemoryKVStore.
+func NewMemoryKVStore() *MemoryKVStore {
+	return &MemoryKVStore{Now: time.Now, entries: make(map[string]memoryKVEntry)}
+}
+
+func (s *MemoryKVStore) now() int {
+	return int(s.Now().Unix())
+}
+
+// live returns the entry of key if it exists and hasn't expired, dropping
+// it if it has. s.mu must be held.
+func (s *MemoryKVStore) live(key string) (memoryKVEntry, bool) {
+	e, ok := s.entries[key]
+	if ok && e.expiration != 0 && e.expiration <= s.now() {
+		delete(s.entries, key)
+		return memoryKVEntry{}, false
+	}
+	return e, ok
+}
+
+// Get returns the value and metadata of a key, or ErrKVKeyNotFound.
+func (s *MemoryKVStore) Get(ctx context.Context, key string) ([]byte, interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.live(key)
+	if !ok {
+		return nil, nil, ErrKVKeyNotFound
+	}
+
+	var metadata interface{}
+	if e.metadata != nil {
+		if err := json.Unmarshal(e.metadata, &metadata); err != nil {
+			return nil, nil, errors.Wrap(err, errUnmarshalError)
+		}
+	}
+	return append([]byte(nil), e.value...), metadata, nil
+}
+
+// Put writes a key. Like the API, it rejects empty or overlong keys,
+// expirations less than 60 seconds away and metadata over 1024 bytes.
+func (s *MemoryKVStore) Put(ctx context.Context, key string, value []byte, o WorkersKVWriteOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := validateKVKey(key); err != nil {
+		return err
+	}
+
+	now := s.now()
+	e := memoryKVEntry{value: append([]byte(nil), value...), expiration: o.Expiration}
+	if o.ExpirationTTL != 0 {
+		if o.ExpirationTTL < kvMinExpirationTTL {
+			return errors.Errorf("expiration_ttl must be at least %d seconds", kvMinExpirationTTL)
+		}
+		e.expiration = now + o.ExpirationTTL
+	} else if o.Expiration != 0 && o.Expiration < now+kvMinExpirationTTL {
+		return errors.Errorf("expiration must be at least %d seconds in the future", kvMinExpirationTTL)
+	}
+	if o.Metadata != nil {
+		b, err := json.Marshal(o.Metadata)
+		if err != nil {
+			return errors.Wrap(err, "error marshalling metadata to JSON")
+		}
+		if len(b) > kvMaxMetadataBytes {
+			return errors.Errorf("metadata is %d bytes, over the %d byte limit", len(b), kvMaxMetadataBytes)
+		}
+		e.metadata = b
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = e
+	return nil
+}
+
+// Delete removes a key.
+func (s *MemoryKVStore) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := validateKVKey(key); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// List returns a page of live keys. Limit defaults to 1000 and must be
+// between 10 and 1000, as with the API.
+func (s *MemoryKVStore) List(ctx context.Context, o ListWorkersKVsOptions) ([]StorageKey, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	limit := kvDefaultListLimit
+	if o.Limit != nil {
+		limit = *o.Limit
+		if limit < kvMinListLimit || limit > kvDefaultListLimit {
+			return nil, "", errors.Errorf("limit must be between %d and %d", kvMinListLimit, kvDefaultListLimit)
+		}
+	}
+	var prefix, after string
+	if o.Prefix != nil {
+		prefix = *o.Prefix
+	}
+	if o.Cursor != nil && *o.Cursor != "" {
+		b, err := base64.RawURLEncoding.DecodeString(*o.Cursor)
+		if err != nil {
+			return nil, "", errors.New("invalid cursor")
+		}
+		after = string(b)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for name := range s.entries {
+		if strings.HasPrefix(name, prefix) && name > after {
+			if _, ok := s.live(name); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	cursor := ""
+	if len(names) > limit {
+		names = names[:limit]
+		cursor = base64.RawURLEncoding.EncodeToString([]byte(names[limit-1]))
+	}
+
+	keys := make([]StorageKey, len(names))
+	for i, name := range names {
+		e := s.entries[name]
+		keys[i] = StorageKey{Name: name, Expiration: e.expiration}
+		if e.metadata != nil {
+			if err := json.Unmarshal(e.metadata, &keys[i].Metadata); err != nil {
+				return nil, "", errors.Wrap(err, errUnmarshalError)
+			}
+		}
+	}
+	return keys, cursor, nil
+}
+
+func validateKVKey(key string) error {
+	switch {
+	case key == "" || key == "." || key == "..":
+		return errors.Errorf("invalid key %q", key)
+	case len(key) > kvMaxKeyBytes:
+		return errors.Errorf("key is %d bytes, over the %d byte limit", len(key), kvMaxKeyBytes)
+	}
+	return nil
+}
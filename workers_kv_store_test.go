@@ -0,0 +1,154 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkersKVStore(t *testing.T) {
+	setup(UsingAccount("foo"))
+	defer teardown()
+
+	namespace := "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
+	prefix := fmt.Sprintf("/accounts/foo/storage/kv/namespaces/%s", namespace)
+	values := map[string]string{}
+
+	mux.HandleFunc(prefix+"/values/", func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, prefix+"/values/")
+		switch r.Method {
+		case http.MethodPut:
+			require.NoError(t, r.ParseMultipartForm(1<<20))
+			assert.Equal(t, "expiration_ttl=120", r.URL.RawQuery)
+			values[key] = r.MultipartForm.Value["value"][0]
+		case http.MethodGet:
+			fmt.Fprint(w, values[key])
+			return
+		case http.MethodDelete:
+			delete(values, key)
+		}
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"result": null, "success": true, "errors": [], "messages": []}`)
+	})
+	mux.HandleFunc(prefix+"/metadata/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		w.Header().Set("content-type", "application/json")
+		if _, ok := values[strings.TrimPrefix(r.URL.Path, prefix+"/metadata/")]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"result": null, "success": false, "errors": [{"code": 10009, "message": "get: 'key not found'"}], "messages": []}`)
+			return
+		}
+		fmt.Fprint(w, `{"result": {"v": 1}, "success": true, "errors": [], "messages": []}`)
+	})
+	mux.HandleFunc(prefix+"/keys", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "prefix=a", r.URL.RawQuery)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"result": [{"name": "a"}], "result_info": {"count": 1, "cursor": "next"}, "success": true, "errors": [], "messages": []}`)
+	})
+
+	var store KVStore = NewWorkersKVStore(client, namespace)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "a", []byte("1"), WorkersKVWriteOptions{ExpirationTTL: 120}))
+	value, metadata, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), value)
+	assert.Equal(t, map[string]interface{}{"v": float64(1)}, metadata)
+
+	prefixA := "a"
+	keys, cursor, err := store.List(ctx, ListWorkersKVsOptions{Prefix: &prefixA})
+	require.NoError(t, err)
+	assert.Equal(t, []StorageKey{{Name: "a"}}, keys)
+	assert.Equal(t, "next", cursor)
+
+	require.NoError(t, store.Delete(ctx, "a"))
+	_, _, err = store.Get(ctx, "a")
+	assert.Equal(t, ErrKVKeyNotFound, err)
+}
+
+func TestMemoryKVStore(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	store := NewMemoryKVStore()
+	store.Now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "config", []byte("v1"), WorkersKVWriteOptions{Metadata: map[string]string{"owner": "ops"}}))
+	require.NoError(t, store.Put(ctx, "session", []byte("s"), WorkersKVWriteOptions{ExpirationTTL: 60}))
+
+	value, metadata, err := store.Get(ctx, "config")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v1"), value)
+	assert.Equal(t, map[string]interface{}{"owner": "ops"}, metadata)
+
+	keys, cursor, err := store.List(ctx, ListWorkersKVsOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []StorageKey{
+		{Name: "config", Metadata: map[string]interface{}{"owner": "ops"}},
+		{Name: "session", Expiration: 1600000060},
+	}, keys)
+	assert.Empty(t, cursor)
+
+	now = now.Add(time.Minute)
+	_, _, err = store.Get(ctx, "session")
+	assert.Equal(t, ErrKVKeyNotFound, err)
+	keys, _, err = store.List(ctx, ListWorkersKVsOptions{})
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	require.NoError(t, store.Delete(ctx, "config"))
+	require.NoError(t, store.Delete(ctx, "config"))
+	_, _, err = store.Get(ctx, "config")
+	assert.Equal(t, ErrKVKeyNotFound, err)
+}
+
+func TestMemoryKVStore_Validation(t *testing.T) {
+	store := NewMemoryKVStore()
+	ctx := context.Background()
+
+	assert.EqualError(t, store.Put(ctx, "", nil, WorkersKVWriteOptions{}), `invalid key ""`)
+	assert.EqualError(t, store.Put(ctx, strings.Repeat("k", 513), nil, WorkersKVWriteOptions{}), "key is 513 bytes, over the 512 byte limit")
+	assert.EqualError(t, store.Put(ctx, "k", nil, WorkersKVWriteOptions{ExpirationTTL: 30}), "expiration_ttl must be at least 60 seconds")
+	assert.EqualError(t, store.Put(ctx, "k", nil, WorkersKVWriteOptions{Expiration: int(time.Now().Unix())}), "expiration must be at least 60 seconds in the future")
+	assert.EqualError(t, store.Put(ctx, "k", nil, WorkersKVWriteOptions{Metadata: strings.Repeat("m", 1024)}), "metadata is 1026 bytes, over the 1024 byte limit")
+
+	limit := 5
+	_, _, err := store.List(ctx, ListWorkersKVsOptions{Limit: &limit})
+	assert.EqualError(t, err, "limit must be between 10 and 1000")
+}
+
+func TestMemoryKVStore_ListPages(t *testing.T) {
+	store := NewMemoryKVStore()
+	ctx := context.Background()
+	for i := 0; i < 25; i++ {
+		require.NoError(t, store.Put(ctx, fmt.Sprintf("user:%02d", i), nil, WorkersKVWriteOptions{}))
+	}
+	require.NoError(t, store.Put(ctx, "other", nil, WorkersKVWriteOptions{}))
+
+	limit, prefix := 10, "user:"
+	o := ListWorkersKVsOptions{Limit: &limit, Prefix: &prefix}
+	var names []string
+	pages := 0
+	for {
+		keys, cursor, err := store.List(ctx, o)
+		require.NoError(t, err)
+		pages++
+		for _, k := range keys {
+			names = append(names, k.Name)
+		}
+		if cursor == "" {
+			break
+		}
+		o.Cursor = &cursor
+	}
+
+	assert.Equal(t, 3, pages)
+	require.Len(t, names, 25)
+	assert.Equal(t, "user:00", names[0])
+	assert.Equal(t, "user:24", names[24])
+}
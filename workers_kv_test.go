@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -304,6 +306,152 @@ func TestWorkersKV_ReadWorkersKV(t *testing.T) {
 	}
 }
 
+func TestWorkersKV_WriteWorkersKVWithOptions(t *testing.T) {
+	setup(UsingAccount("foo"))
+	defer teardown()
+
+	namespace := "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
+	response := `{
+		"result": null,
+		"success": true,
+		"errors": [],
+		"messages": []
+	}`
+
+	mux.HandleFunc(fmt.Sprintf("/accounts/foo/storage/kv/namespaces/%s/values/", namespace), func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method, "Expected method 'PUT', got %s", r.Method)
+		assert.Equal(t, fmt.Sprintf("/accounts/foo/storage/kv/namespaces/%s/values/dir%%2Fkey", namespace), r.URL.EscapedPath())
+		assert.Equal(t, "expiration_ttl=3600", r.URL.RawQuery)
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, []string{"test_value"}, r.MultipartForm.Value["value"])
+		assert.Equal(t, []string{`{"owner":"ops"}`}, r.MultipartForm.Value["metadata"])
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, response) //nolint
+	})
+
+	want := successResponse
+	res, err := client.WriteWorkersKVWithOptions(context.Background(), namespace, "dir/key", strings.NewReader("test_value"), WorkersKVWriteOptions{
+		ExpirationTTL: 3600,
+		Metadata:      map[string]string{"owner": "ops"},
+	})
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, want, res)
+	}
+}
+
+func TestWorkersKV_WriteWorkersKVWithOptionsError(t *testing.T) {
+	setup(UsingAccount("foo"))
+	defer teardown()
+
+	key := "test_key"
+	namespace := "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
+
+	mux.HandleFunc(fmt.Sprintf("/accounts/foo/storage/kv/namespaces/%s/values/%s", namespace, key), func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "expiration=1500000000", r.URL.RawQuery)
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"result": null, "success": false, "errors": [{"code": 10021, "message": "Invalid expiration"}], "messages": []}`)
+	})
+
+	_, err := client.WriteWorkersKVWithOptions(context.Background(), namespace, key, strings.NewReader("test_value"), WorkersKVWriteOptions{Expiration: 1500000000})
+
+	var apiErr *APIRequestError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.Equal(t, "Invalid expiration", apiErr.Errors[0].Message)
+	}
+}
+
+func TestWorkersKV_ReadWorkersKVWithMetadata(t *testing.T) {
+	setup(UsingAccount("foo"))
+	defer teardown()
+
+	key := "test_key"
+	namespace := "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
+
+	mux.HandleFunc(fmt.Sprintf("/accounts/foo/storage/kv/namespaces/%s/metadata/%s", namespace, key), func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"result": {"owner": "ops"}, "success": true, "errors": [], "messages": []}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/accounts/foo/storage/kv/namespaces/%s/values/%s", namespace, key), func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		w.Header().Set("content-type", "application/octet-stream")
+		fmt.Fprintf(w, "test_value")
+	})
+
+	res, err := client.ReadWorkersKVWithMetadata(context.Background(), namespace, key)
+	require.NoError(t, err)
+	defer res.Value.Close()
+
+	value, err := ioutil.ReadAll(res.Value)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("test_value"), value)
+	assert.Equal(t, map[string]interface{}{"owner": "ops"}, res.Metadata)
+}
+
+func TestWorkersKV_ReadWorkersKVStreamNotFound(t *testing.T) {
+	setup(UsingAccount("foo"))
+	defer teardown()
+
+	key := "test_key"
+	namespace := "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
+
+	mux.HandleFunc(fmt.Sprintf("/accounts/foo/storage/kv/namespaces/%s/values/%s", namespace, key), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"result": null, "success": false, "errors": [{"code": 10009, "message": "get: 'key not found'"}], "messages": []}`)
+	})
+
+	_, err := client.ReadWorkersKVStream(context.Background(), namespace, key)
+	assert.True(t, isNotFound(err))
+}
+
+func TestWorkersKV_ReadWorkersKVStreamRetry(t *testing.T) {
+	setup(UsingAccount("foo"), UsingRetryPolicy(2, 0, 0))
+	defer teardown()
+
+	key := "test_key"
+	namespace := "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
+
+	calls := 0
+	mux.HandleFunc(fmt.Sprintf("/accounts/foo/storage/kv/namespaces/%s/values/%s", namespace, key), func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("content-type", "application/octet-stream")
+		fmt.Fprintf(w, "test_value")
+	})
+
+	body, err := client.ReadWorkersKVStream(context.Background(), namespace, key)
+	require.NoError(t, err)
+	defer body.Close()
+
+	value, err := ioutil.ReadAll(body)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("test_value"), value)
+	assert.Equal(t, 2, calls)
+}
+
+func TestWorkersKV_ReadWorkersKVStreamServerError(t *testing.T) {
+	setup(UsingAccount("foo"))
+	defer teardown()
+
+	key := "test_key"
+	namespace := "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
+
+	mux.HandleFunc(fmt.Sprintf("/accounts/foo/storage/kv/namespaces/%s/values/%s", namespace, key), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, "internal error")
+	})
+
+	_, err := client.ReadWorkersKVStream(context.Background(), namespace, key)
+	assert.EqualError(t, err, "HTTP status 500: service failure")
+}
+
 func TestWorkersKV_DeleteWorkersKV(t *testing.T) {
 	setup(UsingAccount("foo"))
 	defer teardown()
@@ -0,0 +1,247 @@
+package cloudflare
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// workerRoutePattern is a parsed WorkerRoute pattern. A leading * in the
+// host matches any prefix, so "*example.com" matches "example.com" and
+// "notexample.com" while "*.example.com" only matches subdomains. A
+// trailing * in the path matches any suffix, including the query string;
+// without it the path and query must match exactly.
+type workerRoutePattern struct {
+	route        WorkerRoute
+	index        int
+	host         string
+	hostWildcard bool
+	path         string
+	pathWildcard bool
+}
+
+func parseWorkerRoutePattern(pattern string) (workerRoutePattern, error) {
+	p := strings.TrimSpace(pattern)
+	if i := strings.Index(p, "://"); i >= 0 {
+		switch strings.ToLower(p[:i]) {
+		case "http", "https":
+			p = p[i+3:]
+		default:
+			return workerRoutePattern{}, errors.Errorf("route pattern %q: unsupported scheme %q", pattern, p[:i])
+		}
+	}
+
+	var rp workerRoutePattern
+	rp.host, rp.path = p, "/"
+	if i := strings.IndexByte(p, '/'); i >= 0 {
+		rp.host, rp.path = p[:i], p[i:]
+	}
+	rp.host = strings.ToLower(rp.host)
+
+	if strings.HasPrefix(rp.host, "*") {
+		rp.host, rp.hostWildcard = rp.host[1:], true
+	}
+	if strings.HasSuffix(rp.path, "*") {
+		rp.path, rp.pathWildcard = strings.TrimSuffix(rp.path, "*"), true
+	}
+
+	switch {
+	case rp.host == "":
+		return workerRoutePattern{}, errors.Errorf("route pattern %q: missing host", pattern)
+	case strings.Contains(rp.host, "*") || strings.Contains(rp.path, "*"):
+		return workerRoutePattern{}, errors.Errorf("route pattern %q: wildcards are only allowed at the start of the host and the end of the path", pattern)
+	case strings.Contains(rp.path, "?"):
+		return workerRoutePattern{}, errors.Errorf("route pattern %q: query strings are not allowed", pattern)
+	}
+	return rp, nil
+}
+
+func (p workerRoutePattern) matchHost(host string) bool {
+	if p.hostWildcard {
+		return strings.HasSuffix(host, p.host)
+	}
+	return host == p.host
+}
+
+func (p workerRoutePattern) matchPath(path string) bool {
+	if p.pathWildcard {
+		return strings.HasPrefix(path, p.path)
+	}
+	return path == p.path
+}
+
+// outranks reports whether p takes precedence over q for a URL both match.
+// The more specific host wins, an exact host over a wildcard and then the
+// longer one; for the same host the more specific path wins in the same way.
+func (p workerRoutePattern) outranks(q workerRoutePattern) bool {
+	switch {
+	case p.hostWildcard != q.hostWildcard:
+		return !p.hostWildcard
+	case len(p.host) != len(q.host):
+		return len(p.host) > len(q.host)
+	case p.pathWildcard != q.pathWildcard:
+		return !p.pathWildcard
+	case len(p.path) != len(q.path):
+		return len(p.path) > len(q.path)
+	}
+	return p.index < q.index
+}
+
+// overlaps reports whether some URL matches both p and q.
+func (p workerRoutePattern) overlaps(q workerRoutePattern) bool {
+	hosts := p.host == q.host ||
+		p.hostWildcard && strings.HasSuffix(q.host, p.host) ||
+		q.hostWildcard && strings.HasSuffix(p.host, q.host)
+	paths := p.path == q.path ||
+		p.pathWildcard && strings.HasPrefix(q.path, p.path) ||
+		q.pathWildcard && strings.HasPrefix(p.path, q.path)
+	return hosts && paths
+}
+
+// equivalent reports whether p and q match the same URLs.
+func (p workerRoutePattern) equivalent(q workerRoutePattern) bool {
+	return p.host == q.host && p.hostWildcard == q.hostWildcard &&
+		p.path == q.path && p.pathWildcard == q.pathWildcard
+}
+
+// WorkerRouteMatcher finds the route that handles a URL, following the
+// precedence Cloudflare applies between a zone's Worker routes.
+type WorkerRouteMatcher struct {
+	patterns []workerRoutePattern
+}
+
+// NewWorkerRouteMatcher returns a matcher for routes, as returned by
+// ListWorkerRoutes. Patterns may not contain wildcards other than at the
+// start of the host and the end of the path, or query strings; the scheme
+// is optional and ignored.
+func NewWorkerRouteMatcher(routes []WorkerRoute) (*WorkerRouteMatcher, error) {
+	m := &WorkerRouteMatcher{patterns: make([]workerRoutePattern, 0, len(routes))}
+	for i, route := range routes {
+		p, err := parseWorkerRoutePattern(route.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		p.route, p.index = route, i
+		m.patterns = append(m.patterns, p)
+	}
+	sort.Slice(m.patterns, func(i, j int) bool {
+		return m.patterns[i].outranks(m.patterns[j])
+	})
+	return m, nil
+}
+
+// Match returns the route that handles u and whether any route matches it.
+// A matching route without a Script disables Workers for the URL.
+func (m *WorkerRouteMatcher) Match(u *url.URL) (WorkerRoute, bool) {
+	host := strings.ToLower(u.Hostname())
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" || u.ForceQuery {
+		path += "?" + u.RawQuery
+	}
+
+	for _, p := range m.patterns {
+		if p.matchHost(host) && p.matchPath(path) {
+			return p.route, true
+		}
+	}
+	return WorkerRoute{}, false
+}
+
+// WorkerRouteLintKind is the kind of problem found by LintWorkerRoutes.
+type WorkerRouteLintKind string
+
+// WorkerRouteLintKind values.
+const (
+	// WorkerRouteLintInvalid is a pattern Cloudflare won't accept.
+	WorkerRouteLintInvalid WorkerRouteLintKind = "invalid"
+	// WorkerRouteLintShadowed is a route that never matches, as another
+	// route with an equivalent pattern is used instead.
+	WorkerRouteLintShadowed WorkerRouteLintKind = "shadowed"
+	// WorkerRouteLintOverlap is a route that loses some of its URLs to a
+	// route for a different script.
+	WorkerRouteLintOverlap WorkerRouteLintKind = "overlap"
+	// WorkerRouteLintBroadHost is a host wildcard without a dot, such as
+	// "*example.com", which also matches hosts like "notexample.com".
+	WorkerRouteLintBroadHost WorkerRouteLintKind = "broad_host"
+)
+
+// WorkerRouteLint is a problem found by LintWorkerRoutes.
+type WorkerRouteLint struct {
+	Kind  WorkerRouteLintKind
+	Route WorkerRoute
+	// Other is the route that takes precedence over Route, for shadowed
+	// and overlapping routes.
+	Other   WorkerRoute
+	Message string
+}
+
+// LintWorkerRoutes reports invalid patterns, routes shadowed by an
+// equivalent pattern and routes that share URLs with a route for another
+// script, in the order of routes.
+func LintWorkerRoutes(routes []WorkerRoute) []WorkerRouteLint {
+	found := make([][]WorkerRouteLint, len(routes))
+	var patterns []workerRoutePattern
+	for i, route := range routes {
+		p, err := parseWorkerRoutePattern(route.Pattern)
+		if err != nil {
+			found[i] = append(found[i], WorkerRouteLint{Kind: WorkerRouteLintInvalid, Route: route, Message: err.Error()})
+			continue
+		}
+		p.route, p.index = route, i
+		patterns = append(patterns, p)
+
+		if p.hostWildcard && !strings.HasPrefix(p.host, ".") {
+			found[i] = append(found[i], WorkerRouteLint{
+				Kind:    WorkerRouteLintBroadHost,
+				Route:   route,
+				Message: fmt.Sprintf("%q matches any host ending in %q, not only %q and its subdomains", route.Pattern, p.host, p.host),
+			})
+		}
+	}
+
+	// A shadowed route never handles a URL, so it only takes URLs from
+	// the route shadowing it.
+	shadowed := make(map[int]bool)
+	for _, p := range patterns {
+		for _, q := range patterns {
+			if p.index != q.index && p.equivalent(q) && q.outranks(p) {
+				shadowed[p.index] = true
+				found[p.index] = append(found[p.index], WorkerRouteLint{
+					Kind:    WorkerRouteLintShadowed,
+					Route:   p.route,
+					Other:   q.route,
+					Message: fmt.Sprintf("%q never matches, %q matches the same URLs", p.route.Pattern, q.route.Pattern),
+				})
+				break
+			}
+		}
+	}
+
+	for _, p := range patterns {
+		for _, q := range patterns {
+			if shadowed[p.index] || shadowed[q.index] || p.index == q.index || p.route.Script == q.route.Script {
+				continue
+			}
+			if q.outranks(p) && p.overlaps(q) {
+				found[p.index] = append(found[p.index], WorkerRouteLint{
+					Kind:    WorkerRouteLintOverlap,
+					Route:   p.route,
+					Other:   q.route,
+					Message: fmt.Sprintf("%q takes precedence over %q for the URLs both match", q.route.Pattern, p.route.Pattern),
+				})
+			}
+		}
+	}
+
+	var lints []WorkerRouteLint
+	for _, l := range found {
+		lints = append(lints, l...)
+	}
+	return lints
+}
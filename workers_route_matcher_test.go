@@ -0,0 +1,71 @@
+package cloudflare
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerRouteMatcher(t *testing.T) {
+	routes := []WorkerRoute{
+		{ID: "1", Pattern: "*.example.com/*", Script: "subdomains"},
+		{ID: "2", Pattern: "https://www.example.com/*", Script: "www"},
+		{ID: "3", Pattern: "*example.com/api/*", Script: "api"},
+		{ID: "4", Pattern: "www.example.com/static/*"},
+		{ID: "5", Pattern: "example.com/", Script: "home"},
+	}
+	m, err := NewWorkerRouteMatcher(routes)
+	require.NoError(t, err)
+
+	for rawURL, want := range map[string]string{
+		"https://blog.example.com/post":      "1",
+		"https://www.example.com/":           "2",
+		"http://WWW.example.com:8080/api/v1": "2",
+		"https://example.com/api/v1":         "3",
+		"https://notexample.com/api/v1":      "3",
+		"https://www.example.com/static/app": "4",
+		"https://example.com/":               "5",
+		"https://example.com":                "5",
+		"https://example.com/?utm=1":         "",
+		"https://example.com/about":          "",
+		"https://example.org/":               "",
+	} {
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		route, ok := m.Match(u)
+		assert.Equal(t, want != "", ok, rawURL)
+		assert.Equal(t, want, route.ID, rawURL)
+	}
+
+	for _, pattern := range []string{"example.com/*/foo", "ex*ample.com/*", "example.com/?a=1*", "ftp://example.com/*", "*/foo"} {
+		_, err := NewWorkerRouteMatcher([]WorkerRoute{{Pattern: pattern}})
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestLintWorkerRoutes(t *testing.T) {
+	routes := []WorkerRoute{
+		{ID: "1", Pattern: "*.example.com/*", Script: "subdomains"},
+		{ID: "2", Pattern: "www.example.com/*", Script: "www"},
+		{ID: "3", Pattern: "https://www.example.com/*", Script: "www-v2"},
+		{ID: "4", Pattern: "www.example.com/blog/*", Script: "www"},
+		{ID: "5", Pattern: "*example.com/api/*", Script: "api"},
+		{ID: "6", Pattern: "example.com/*?", Script: "api"},
+	}
+
+	var got []string
+	for _, l := range LintWorkerRoutes(routes) {
+		got = append(got, l.Route.ID+" "+string(l.Kind)+" "+l.Other.ID)
+	}
+	assert.Equal(t, []string{
+		"1 overlap 2",
+		"1 overlap 4",
+		"3 shadowed 2",
+		"5 broad_host ",
+		"5 overlap 1",
+		"5 overlap 2",
+		"6 invalid ",
+	}, got)
+}
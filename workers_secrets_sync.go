@@ -0,0 +1,313 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WorkersSecretProvider supplies the secrets a script should have, by name.
+type WorkersSecretProvider interface {
+	WorkersSecrets(ctx context.Context) (map[string]string, error)
+}
+
+// WorkersSecretMap is a WorkersSecretProvider of a fixed set of secrets.
+type WorkersSecretMap map[string]string
+
+// WorkersSecrets returns the map itself.
+func (m WorkersSecretMap) WorkersSecrets(ctx context.Context) (map[string]string, error) {
+	return m, nil
+}
+
+// WorkersSecretsFromJSON reads secrets from a JSON object of strings.
+func WorkersSecretsFromJSON(r io.Reader) (WorkersSecretMap, error) {
+	var m WorkersSecretMap
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, errors.Wrap(err, "failed to decode secrets")
+	}
+	return m, nil
+}
+
+// WorkersSecretsFromDotEnv reads secrets from a .env file: NAME=value lines,
+// optionally prefixed with "export". Values may be single quoted, taken
+// literally, or double quoted, where \n, \r, \t, \" and \\ are unescaped
+// and newlines are kept. Unquoted values end at " #". Blank lines and lines
+// starting with # are ignored.
+func WorkersSecretsFromDotEnv(r io.Reader) (WorkersSecretMap, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := dotEnvParser{src: strings.ReplaceAll(string(b), "\r\n", "\n"), line: 1}
+	m := make(WorkersSecretMap)
+	for {
+		name, value, ok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return m, nil
+		}
+		m[name] = value
+	}
+}
+
+// dotEnvParser parses .env files. Its errors name lines, never values.
+type dotEnvParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *dotEnvParser) next() (string, string, bool, error) {
+	for p.pos < len(p.src) {
+		lineStart := p.line
+		rest := p.src[p.pos:]
+		end := strings.IndexByte(rest, '\n')
+		if end < 0 {
+			end = len(rest)
+		}
+		text := strings.TrimSpace(rest[:end])
+		if text == "" || text[0] == '#' {
+			p.advance(end + 1)
+			continue
+		}
+
+		eq := strings.IndexByte(rest[:end], '=')
+		if eq < 0 {
+			return "", "", false, errors.Errorf("line %d: expected NAME=value", lineStart)
+		}
+		name := strings.TrimSpace(rest[:eq])
+		if fields := strings.Fields(name); len(fields) == 2 && fields[0] == "export" {
+			name = fields[1]
+		}
+		if !validDotEnvName(name) {
+			return "", "", false, errors.Errorf("line %d: invalid name %q", lineStart, name)
+		}
+		p.advance(eq + 1)
+
+		value, err := p.value()
+		if err != nil {
+			return "", "", false, errors.Wrapf(err, "line %d", lineStart)
+		}
+		return name, value, true, nil
+	}
+	return "", "", false, nil
+}
+
+// value parses the value after "=", consuming the rest of its line.
+func (p *dotEnvParser) value() (string, error) {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	if p.pos == len(p.src) {
+		return "", nil
+	}
+
+	var value string
+	switch quote := p.src[p.pos]; quote {
+	case '\'':
+		end := strings.IndexByte(p.src[p.pos+1:], '\'')
+		if end < 0 {
+			return "", errors.New("unterminated single quoted value")
+		}
+		value = p.src[p.pos+1 : p.pos+1+end]
+		p.advance(end + 2)
+	case '"':
+		var b strings.Builder
+		i := p.pos + 1
+		for ; i < len(p.src) && p.src[i] != '"'; i++ {
+			c := p.src[i]
+			if c == '\\' && i+1 < len(p.src) {
+				i++
+				switch p.src[i] {
+				case 'n':
+					c = '\n'
+				case 'r':
+					c = '\r'
+				case 't':
+					c = '\t'
+				case '"', '\\':
+					c = p.src[i]
+				default:
+					b.WriteByte('\\')
+					c = p.src[i]
+				}
+			}
+			b.WriteByte(c)
+		}
+		if i == len(p.src) {
+			return "", errors.New("unterminated double quoted value")
+		}
+		value = b.String()
+		p.advance(i + 1 - p.pos)
+	default:
+		rest := p.src[p.pos:]
+		end := strings.IndexByte(rest, '\n')
+		if end < 0 {
+			end = len(rest)
+		}
+		value = rest[:end]
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		p.advance(end)
+		return strings.TrimSpace(value), p.endLine()
+	}
+	return value, p.endLine()
+}
+
+// endLine consumes the rest of a line after a quoted value, allowing only
+// whitespace and a comment.
+func (p *dotEnvParser) endLine() error {
+	rest := p.src[p.pos:]
+	end := strings.IndexByte(rest, '\n')
+	if end < 0 {
+		end = len(rest)
+	}
+	if text := strings.TrimSpace(rest[:end]); text != "" && text[0] != '#' {
+		return errors.New("unexpected text after value")
+	}
+	p.advance(end + 1)
+	return nil
+}
+
+// advance moves n bytes forward, counting lines.
+func (p *dotEnvParser) advance(n int) {
+	if p.pos+n > len(p.src) {
+		n = len(p.src) - p.pos
+	}
+	p.line += strings.Count(p.src[p.pos:p.pos+n], "\n")
+	p.pos += n
+}
+
+// validDotEnvName reports whether name is a letter or underscore followed
+// by letters, digits, underscores, dots and dashes.
+func validDotEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case (c >= '0' && c <= '9' || c == '.' || c == '-') && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WorkersSecretsSyncOptions configures SyncWorkersSecrets.
+type WorkersSecretsSyncOptions struct {
+	// Delete removes secrets the script has but the provider doesn't.
+	Delete bool
+	// SkipExisting only creates missing secrets. By default existing
+	// secrets are set again, as their values can't be read back to be
+	// compared.
+	SkipExisting bool
+	// DryRun reports the changes without making them.
+	DryRun bool
+}
+
+// WorkersSecretsSyncResult reports the secrets changed by
+// SyncWorkersSecrets, by name. Values are never included.
+type WorkersSecretsSyncResult struct {
+	Created []string
+	// Overwritten holds existing secrets that were set again. Secret values
+	// can't be read back, so they are overwritten whether or not they
+	// changed.
+	Overwritten []string
+	Deleted     []string
+	// Unchanged holds existing secrets left alone because of SkipExisting.
+	Unchanged []string
+	// Failed holds the secrets that could not be set or deleted.
+	Failed map[string]error
+}
+
+// SyncWorkersSecrets makes a script's secrets match those of provider. It
+// sets the secrets the provider supplies and, with Delete, deletes the
+// others. A failure to set or delete one secret doesn't stop the others
+// from being synced; such failures are in the result's Failed field.
+//
+// Secret values are only ever sent to the API: they are not included in
+// the result or in errors.
+func (api *API) SyncWorkersSecrets(ctx context.Context, script string, provider WorkersSecretProvider, o WorkersSecretsSyncOptions) (WorkersSecretsSyncResult, error) {
+	if err := api.checkAccountID(); err != nil {
+		return WorkersSecretsSyncResult{}, err
+	}
+
+	desired, err := provider.WorkersSecrets(ctx)
+	if err != nil {
+		return WorkersSecretsSyncResult{}, errors.Wrap(err, "failed to get secrets")
+	}
+	if _, ok := desired[""]; ok {
+		return WorkersSecretsSyncResult{}, errors.New("secret names must not be empty")
+	}
+
+	existing, err := api.ListWorkersSecrets(ctx, script)
+	if err != nil {
+		return WorkersSecretsSyncResult{}, errors.Wrap(err, "failed to list secrets")
+	}
+	current := make(map[string]bool, len(existing.Result))
+	for _, s := range existing.Result {
+		current[s.Name] = true
+	}
+
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := WorkersSecretsSyncResult{Failed: make(map[string]error)}
+	for _, name := range names {
+		if current[name] && o.SkipExisting {
+			result.Unchanged = append(result.Unchanged, name)
+			continue
+		}
+		if !o.DryRun {
+			_, err := api.SetWorkersSecret(ctx, script, &WorkersPutSecretRequest{
+				Name: name,
+				Text: desired[name],
+				Type: WorkerSecretTextBindingType,
+			})
+			if err != nil {
+				result.Failed[name] = err
+				continue
+			}
+		}
+		if current[name] {
+			result.Overwritten = append(result.Overwritten, name)
+		} else {
+			result.Created = append(result.Created, name)
+		}
+	}
+
+	if o.Delete {
+		var extra []string
+		for name := range current {
+			if _, ok := desired[name]; !ok {
+				extra = append(extra, name)
+			}
+		}
+		sort.Strings(extra)
+		for _, name := range extra {
+			if !o.DryRun {
+				if _, err := api.DeleteWorkersSecret(ctx, script, name); err != nil {
+					result.Failed[name] = err
+					continue
+				}
+			}
+			result.Deleted = append(result.Deleted, name)
+		}
+	}
+
+	return result, ctx.Err()
+}
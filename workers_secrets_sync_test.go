@@ -0,0 +1,127 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkersSecretsFromDotEnv(t *testing.T) {
+	input := `# database
+DB_URL=postgres://db.example.com/app # primary
+export API_TOKEN = 'abc#123 '
+export	REGION=eu
+EMPTY=
+MULTI="line one
+line two\tend \"quoted\""
+QUOTED_COMMENT="x" # trailing
+`
+	secrets, err := WorkersSecretsFromDotEnv(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, WorkersSecretMap{
+		"DB_URL":         "postgres://db.example.com/app",
+		"API_TOKEN":      "abc#123 ",
+		"EMPTY":          "",
+		"MULTI":          "line one\nline two\tend \"quoted\"",
+		"QUOTED_COMMENT": "x",
+		"REGION":         "eu",
+	}, secrets)
+}
+
+func TestWorkersSecretsFromDotEnv_Errors(t *testing.T) {
+	for input, want := range map[string]string{
+		"A=1\nNOVALUE\n":          "line 2: expected NAME=value",
+		"1A=x":                    `line 1: invalid name "1A"`,
+		"A=1\nB=\"open\nstill\n":  "line 2: unterminated double quoted value",
+		"A='x' y":                 "line 1: unexpected text after value",
+		"A=\"a\nb\"\nC='unclosed": "line 3: unterminated single quoted value",
+	} {
+		_, err := WorkersSecretsFromDotEnv(strings.NewReader(input))
+		assert.EqualError(t, err, want, input)
+	}
+}
+
+func TestWorkersSecretsFromJSON(t *testing.T) {
+	secrets, err := WorkersSecretsFromJSON(strings.NewReader(`{"A": "1", "B": "2"}`))
+	require.NoError(t, err)
+	assert.Equal(t, WorkersSecretMap{"A": "1", "B": "2"}, secrets)
+
+	_, err = WorkersSecretsFromJSON(strings.NewReader(`{"A": 1}`))
+	assert.Error(t, err)
+}
+
+func TestSyncWorkersSecrets(t *testing.T) {
+	setup(UsingAccount("foo"))
+	defer teardown()
+
+	var set, deleted []string
+	mux.HandleFunc("/accounts/foo/workers/scripts/test-script/secrets", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprint(w, `{"result": [{"name": "KEEP", "type": "secret_text"}, {"name": "STALE", "type": "secret_text"}, {"name": "LOCKED", "type": "secret_text"}], "success": true, "errors": [], "messages": []}`)
+		case http.MethodPut:
+			var req WorkersPutSecretRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, WorkerSecretTextBindingType, req.Type)
+			assert.Equal(t, "value-of-"+req.Name, req.Text)
+			if req.Name == "BROKEN" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"result": null, "success": false, "errors": [{"code": 10056, "message": "binding name is invalid"}], "messages": []}`)
+				return
+			}
+			set = append(set, req.Name)
+			fmt.Fprintf(w, `{"result": {"name": %q, "type": "secret_text"}, "success": true, "errors": [], "messages": []}`, req.Name)
+		}
+	})
+	mux.HandleFunc("/accounts/foo/workers/scripts/test-script/secrets/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method, "Expected method 'DELETE', got %s", r.Method)
+		name := strings.TrimPrefix(r.URL.Path, "/accounts/foo/workers/scripts/test-script/secrets/")
+		w.Header().Set("content-type", "application/json")
+		if name == "LOCKED" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"result": null, "success": false, "errors": [{"code": 10000, "message": "Authentication error"}], "messages": []}`)
+			return
+		}
+		deleted = append(deleted, name)
+		fmt.Fprint(w, `{"result": null, "success": true, "errors": [], "messages": []}`)
+	})
+
+	desired := WorkersSecretMap{"KEEP": "value-of-KEEP", "NEW": "value-of-NEW", "BROKEN": "value-of-BROKEN"}
+
+	res, err := client.SyncWorkersSecrets(context.Background(), "test-script", desired, WorkersSecretsSyncOptions{Delete: true, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BROKEN", "NEW"}, res.Created)
+	assert.Equal(t, []string{"KEEP"}, res.Overwritten)
+	assert.Equal(t, []string{"LOCKED", "STALE"}, res.Deleted)
+	assert.Empty(t, set)
+	assert.Empty(t, deleted)
+
+	res, err = client.SyncWorkersSecrets(context.Background(), "test-script", desired, WorkersSecretsSyncOptions{Delete: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"NEW"}, res.Created)
+	assert.Equal(t, []string{"KEEP"}, res.Overwritten)
+	assert.Equal(t, []string{"STALE"}, res.Deleted)
+	assert.Len(t, res.Failed, 2)
+	assert.Contains(t, res.Failed, "BROKEN")
+	assert.Contains(t, res.Failed, "LOCKED")
+	assert.Equal(t, []string{"KEEP", "NEW"}, set)
+	assert.Equal(t, []string{"STALE"}, deleted)
+	for _, err := range res.Failed {
+		assert.NotContains(t, err.Error(), "value-of-")
+	}
+
+	set = nil
+	res, err = client.SyncWorkersSecrets(context.Background(), "test-script", WorkersSecretMap{"KEEP": "value-of-KEEP", "NEW": "value-of-NEW"}, WorkersSecretsSyncOptions{SkipExisting: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"NEW"}, res.Created)
+	assert.Equal(t, []string{"KEEP"}, res.Unchanged)
+	assert.Empty(t, res.Deleted)
+	assert.Equal(t, []string{"NEW"}, set)
+}
@@ -0,0 +1,135 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// WorkersSubdomain is the account's subdomain of workers.dev. Scripts are
+// served at <script>.<subdomain>.workers.dev.
+type WorkersSubdomain struct {
+	Subdomain string `json:"subdomain"`
+}
+
+// WorkersSubdomainResponse is the response received when fetching or
+// updating the account's workers.dev subdomain.
+type WorkersSubdomainResponse struct {
+	Response
+	Result WorkersSubdomain `json:"result"`
+}
+
+// WorkerScriptSubdomain is whether a script is served on workers.dev.
+type WorkerScriptSubdomain struct {
+	Enabled bool `json:"enabled"`
+}
+
+// WorkerScriptSubdomainResponse is the response received when fetching or
+// updating a script's workers.dev setting.
+type WorkerScriptSubdomainResponse struct {
+	Response
+	Result WorkerScriptSubdomain `json:"result"`
+}
+
+// WorkersSubdomain returns the account's workers.dev subdomain.
+//
+// API reference: https://api.cloudflare.com/#worker-subdomain-get-subdomain
+func (api *API) WorkersSubdomain(ctx context.Context) (WorkersSubdomain, error) {
+	if err := api.checkAccountID(); err != nil {
+		return WorkersSubdomain{}, err
+	}
+
+	uri := fmt.Sprintf("/accounts/%s/workers/subdomain", api.AccountID)
+	res, err := api.makeRequestContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return WorkersSubdomain{}, err
+	}
+
+	var r WorkersSubdomainResponse
+	if err := json.Unmarshal(res, &r); err != nil {
+		return WorkersSubdomain{}, errors.Wrap(err, errUnmarshalError)
+	}
+	return r.Result, nil
+}
+
+// UpdateWorkersSubdomain creates or changes the account's workers.dev
+// subdomain. Changing it moves every script served on workers.dev.
+//
+// API reference: https://api.cloudflare.com/#worker-subdomain-create-subdomain
+func (api *API) UpdateWorkersSubdomain(ctx context.Context, subdomain string) (WorkersSubdomain, error) {
+	if err := api.checkAccountID(); err != nil {
+		return WorkersSubdomain{}, err
+	}
+	if subdomain == "" {
+		return WorkersSubdomain{}, errors.New("subdomain must not be empty")
+	}
+
+	uri := fmt.Sprintf("/accounts/%s/workers/subdomain", api.AccountID)
+	res, err := api.makeRequestContext(ctx, http.MethodPut, uri, WorkersSubdomain{Subdomain: subdomain})
+	if err != nil {
+		return WorkersSubdomain{}, err
+	}
+
+	var r WorkersSubdomainResponse
+	if err := json.Unmarshal(res, &r); err != nil {
+		return WorkersSubdomain{}, errors.Wrap(err, errUnmarshalError)
+	}
+	return r.Result, nil
+}
+
+// workerScriptSubdomainURI returns the workers.dev setting endpoint of a
+// script, or of one of its environments when environment is set.
+func (api *API) workerScriptSubdomainURI(scriptName, environment string) string {
+	if environment != "" {
+		return fmt.Sprintf("/accounts/%s/workers/services/%s/environments/%s/subdomain", api.AccountID, scriptName, environment)
+	}
+	return fmt.Sprintf("/accounts/%s/workers/scripts/%s/subdomain", api.AccountID, scriptName)
+}
+
+// WorkerScriptSubdomain returns whether a script is served on the account's
+// workers.dev subdomain. environment selects an environment of the script
+// and may be empty.
+//
+// API reference: https://api.cloudflare.com/#worker-script-get-subdomain
+func (api *API) WorkerScriptSubdomain(ctx context.Context, scriptName, environment string) (WorkerScriptSubdomain, error) {
+	if err := api.checkAccountID(); err != nil {
+		return WorkerScriptSubdomain{}, err
+	}
+
+	res, err := api.makeRequestContext(ctx, http.MethodGet, api.workerScriptSubdomainURI(scriptName, environment), nil)
+	if err != nil {
+		return WorkerScriptSubdomain{}, err
+	}
+
+	var r WorkerScriptSubdomainResponse
+	if err := json.Unmarshal(res, &r); err != nil {
+		return WorkerScriptSubdomain{}, errors.Wrap(err, errUnmarshalError)
+	}
+	return r.Result, nil
+}
+
+// SetWorkerScriptSubdomain enables or disables serving a script on the
+// account's workers.dev subdomain. environment selects an environment of
+// the script and may be empty.
+//
+// API reference: https://api.cloudflare.com/#worker-script-post-subdomain
+func (api *API) SetWorkerScriptSubdomain(ctx context.Context, scriptName, environment string, enabled bool) (WorkerScriptSubdomain, error) {
+	if err := api.checkAccountID(); err != nil {
+		return WorkerScriptSubdomain{}, err
+	}
+
+	uri := api.workerScriptSubdomainURI(scriptName, environment)
+	res, err := api.makeRequestContext(ctx, http.MethodPost, uri, WorkerScriptSubdomain{Enabled: enabled})
+	if err != nil {
+		return WorkerScriptSubdomain{}, err
+	}
+
+	var r WorkerScriptSubdomainResponse
+	if err := json.Unmarshal(res, &r); err != nil {
+		return WorkerScriptSubdomain{}, errors.Wrap(err, errUnmarshalError)
+	}
+	return r.Result, nil
+}
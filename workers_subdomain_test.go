@@ -0,0 +1,85 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkersSubdomain(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/subdomain", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": {"subdomain": "example"}}`)
+	})
+
+	actual, err := client.WorkersSubdomain(context.Background())
+	if assert.NoError(t, err) {
+		assert.Equal(t, WorkersSubdomain{Subdomain: "example"}, actual)
+	}
+}
+
+func TestUpdateWorkersSubdomain(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/subdomain", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method, "Expected method 'PUT', got %s", r.Method)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]interface{}{"subdomain": "renamed"}, body)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": {"subdomain": "renamed"}}`)
+	})
+
+	actual, err := client.UpdateWorkersSubdomain(context.Background(), "renamed")
+	if assert.NoError(t, err) {
+		assert.Equal(t, WorkersSubdomain{Subdomain: "renamed"}, actual)
+	}
+
+	_, err = client.UpdateWorkersSubdomain(context.Background(), "")
+	assert.EqualError(t, err, "subdomain must not be empty")
+}
+
+func TestWorkerScriptSubdomain(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/scripts/api/subdomain", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": {"enabled": true}}`)
+	})
+
+	actual, err := client.WorkerScriptSubdomain(context.Background(), "api", "")
+	if assert.NoError(t, err) {
+		assert.True(t, actual.Enabled)
+	}
+}
+
+func TestSetWorkerScriptSubdomain_Environment(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/services/api/environments/staging/subdomain", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method, "Expected method 'POST', got %s", r.Method)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]interface{}{"enabled": false}, body)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": {"enabled": false}}`)
+	})
+
+	actual, err := client.SetWorkerScriptSubdomain(context.Background(), "api", "staging", false)
+	if assert.NoError(t, err) {
+		assert.False(t, actual.Enabled)
+	}
+}
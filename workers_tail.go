@@ -0,0 +1,430 @@
+package cloudflare
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+)
+
+// workerTailProtocol is the WebSocket subprotocol tail sessions speak.
+const workerTailProtocol = "trace-v1"
+
+// WorkerTail is a tail session of a Worker script.
+type WorkerTail struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// WorkerTailResponse is the response received when creating a tail.
+type WorkerTailResponse struct {
+	Response
+	Result WorkerTail `json:"result"`
+}
+
+// WorkerTailFilter limits the events a tail session receives. The zero
+// value receives every event.
+type WorkerTailFilter struct {
+	// SamplingRate is the fraction of events to receive, between 0 and 1.
+	// Zero means every event.
+	SamplingRate float64
+
+	// Status only receives events with the given outcomes: "ok", "error"
+	// or "canceled".
+	Status []string
+
+	// Method only receives events for requests with the given HTTP
+	// methods.
+	Method []string
+
+	// Query only receives events whose logs, exceptions or request
+	// contain the text.
+	Query string
+
+	// ClientIP only receives events for requests from the given
+	// addresses. "self" matches the address of the tailing client.
+	ClientIP []string
+}
+
+// workerTailOutcomes maps the statuses accepted by WorkerTailFilter to the
+// outcomes reported by the runtime.
+var workerTailOutcomes = map[string][]string{
+	"ok":       {"ok"},
+	"error":    {"exception", "exceededCpu", "exceededMemory", "unknown"},
+	"canceled": {"canceled"},
+}
+
+func (f WorkerTailFilter) message() (map[string]interface{}, error) {
+	filters := []map[string]interface{}{}
+	if f.SamplingRate > 0 {
+		if f.SamplingRate > 1 {
+			return nil, errors.Errorf("sampling rate %v must be between 0 and 1", f.SamplingRate)
+		}
+		filters = append(filters, map[string]interface{}{"sampling_rate": f.SamplingRate})
+	}
+	if len(f.Status) > 0 {
+		var outcomes []string
+		for _, s := range f.Status {
+			o, ok := workerTailOutcomes[s]
+			if !ok {
+				return nil, errors.Errorf("unknown status %q", s)
+			}
+			outcomes = append(outcomes, o...)
+		}
+		filters = append(filters, map[string]interface{}{"outcome": outcomes})
+	}
+	if len(f.Method) > 0 {
+		filters = append(filters, map[string]interface{}{"method": f.Method})
+	}
+	if f.Query != "" {
+		filters = append(filters, map[string]interface{}{"query": f.Query})
+	}
+	if len(f.ClientIP) > 0 {
+		filters = append(filters, map[string]interface{}{"client_ip": f.ClientIP})
+	}
+	return map[string]interface{}{"filters": filters}, nil
+}
+
+// WorkerTailEvent is a single invocation of a Worker received from a tail
+// session. Exactly one of Request and Scheduled is set.
+type WorkerTailEvent struct {
+	ScriptName     string
+	Outcome        string
+	EventTimestamp time.Time
+	Logs           []WorkerTailLog
+	Exceptions     []WorkerTailException
+	Request        *WorkerTailRequest
+	Scheduled      *WorkerTailScheduled
+}
+
+// WorkerTailLog is a message logged through the console API.
+type WorkerTailLog struct {
+	Level     string
+	Message   []interface{}
+	Timestamp time.Time
+}
+
+// WorkerTailException is an uncaught exception thrown by the Worker.
+type WorkerTailException struct {
+	Name      string
+	Message   string
+	Timestamp time.Time
+}
+
+// WorkerTailRequest is the request that triggered a fetch event. Response
+// is nil if the Worker did not respond.
+type WorkerTailRequest struct {
+	URL      string
+	Method   string
+	Headers  map[string]string
+	CF       map[string]interface{}
+	Response *WorkerTailResponseStatus
+}
+
+// WorkerTailResponseStatus is the status of the Worker's response.
+type WorkerTailResponseStatus struct {
+	Status int
+}
+
+// WorkerTailScheduled describes the cron trigger of a scheduled event.
+type WorkerTailScheduled struct {
+	Cron          string
+	ScheduledTime time.Time
+}
+
+// UnmarshalJSON decodes an event from the trace-v1 format, in which
+// timestamps are milliseconds since the epoch.
+func (e *WorkerTailEvent) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		ScriptName     string `json:"scriptName"`
+		Outcome        string `json:"outcome"`
+		EventTimestamp int64  `json:"eventTimestamp"`
+		Logs           []struct {
+			Level     string        `json:"level"`
+			Message   []interface{} `json:"message"`
+			Timestamp int64         `json:"timestamp"`
+		} `json:"logs"`
+		Exceptions []struct {
+			Name      string `json:"name"`
+			Message   string `json:"message"`
+			Timestamp int64  `json:"timestamp"`
+		} `json:"exceptions"`
+		Event struct {
+			Request *struct {
+				URL     string                 `json:"url"`
+				Method  string                 `json:"method"`
+				Headers map[string]string      `json:"headers"`
+				CF      map[string]interface{} `json:"cf"`
+			} `json:"request"`
+			Response *struct {
+				Status int `json:"status"`
+			} `json:"response"`
+			Cron          string `json:"cron"`
+			ScheduledTime int64  `json:"scheduledTime"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*e = WorkerTailEvent{
+		ScriptName:     raw.ScriptName,
+		Outcome:        raw.Outcome,
+		EventTimestamp: unixMilli(raw.EventTimestamp),
+	}
+	for _, l := range raw.Logs {
+		e.Logs = append(e.Logs, WorkerTailLog{Level: l.Level, Message: l.Message, Timestamp: unixMilli(l.Timestamp)})
+	}
+	for _, x := range raw.Exceptions {
+		e.Exceptions = append(e.Exceptions, WorkerTailException{Name: x.Name, Message: x.Message, Timestamp: unixMilli(x.Timestamp)})
+	}
+	switch {
+	case raw.Event.Request != nil:
+		e.Request = &WorkerTailRequest{
+			URL:     raw.Event.Request.URL,
+			Method:  raw.Event.Request.Method,
+			Headers: raw.Event.Request.Headers,
+			CF:      raw.Event.Request.CF,
+		}
+		if raw.Event.Response != nil {
+			e.Request.Response = &WorkerTailResponseStatus{Status: raw.Event.Response.Status}
+		}
+	case raw.Event.Cron != "":
+		e.Scheduled = &WorkerTailScheduled{Cron: raw.Event.Cron, ScheduledTime: unixMilli(raw.Event.ScheduledTime)}
+	}
+	return nil
+}
+
+func unixMilli(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC()
+}
+
+// CreateWorkerTail starts a tail session for a Worker script. The session
+// expires on its own, but should be deleted with DeleteWorkerTail once it
+// is no longer needed.
+//
+// API reference: https://api.cloudflare.com/#worker-tail-logs-start-tail
+func (api *API) CreateWorkerTail(ctx context.Context, scriptName string) (WorkerTail, error) {
+	if err := api.checkAccountID(); err != nil {
+		return WorkerTail{}, err
+	}
+
+	uri := fmt.Sprintf("/accounts/%s/workers/scripts/%s/tails", api.AccountID, scriptName)
+	res, err := api.makeRequestContext(ctx, http.MethodPost, uri, nil)
+	if err != nil {
+		return WorkerTail{}, err
+	}
+
+	var r WorkerTailResponse
+	if err := json.Unmarshal(res, &r); err != nil {
+		return WorkerTail{}, errors.Wrap(err, errUnmarshalError)
+	}
+	return r.Result, nil
+}
+
+// DeleteWorkerTail ends a tail session.
+//
+// API reference: https://api.cloudflare.com/#worker-tail-logs-delete-tail
+func (api *API) DeleteWorkerTail(ctx context.Context, scriptName, tailID string) error {
+	if err := api.checkAccountID(); err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("/accounts/%s/workers/scripts/%s/tails/%s", api.AccountID, scriptName, tailID)
+	_, err := api.makeRequestContext(ctx, http.MethodDelete, uri, nil)
+	return err
+}
+
+// WorkerTailSession streams the events of a tail session.
+type WorkerTailSession struct {
+	Tail WorkerTail
+
+	// Events receives the Worker's events. It is closed when the context
+	// passed to TailWorker is done or the connection fails.
+	Events <-chan WorkerTailEvent
+
+	done chan struct{}
+	mu   sync.Mutex
+	err  error
+}
+
+// Wait blocks until the session has ended and its tail has been deleted,
+// returning the error that ended it or the failure to delete the tail. It
+// returns nil if the session ended because its context was done.
+func (s *WorkerTailSession) Wait() error {
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// TailWorker creates a tail session for a Worker script, connects to it and
+// streams its events until ctx is done, at which point the tail is
+// deleted. Reading from Events must keep up with the Worker; the session
+// blocks while the channel is full.
+func (api *API) TailWorker(ctx context.Context, scriptName string, filter WorkerTailFilter) (*WorkerTailSession, error) {
+	msg, err := filter.message()
+	if err != nil {
+		return nil, err
+	}
+
+	tail, err := api.CreateWorkerTail(ctx, scriptName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create tail")
+	}
+
+	ws, err := api.dialWorkerTail(ctx, tail)
+	if err == nil {
+		err = websocket.JSON.Send(ws, msg)
+	}
+	if err != nil {
+		_ = api.deleteWorkerTail(scriptName, tail.ID)
+		if ws != nil {
+			ws.Close()
+		}
+		return nil, errors.Wrap(err, "failed to connect to tail")
+	}
+
+	events := make(chan WorkerTailEvent, 16)
+	s := &WorkerTailSession{Tail: tail, Events: events, done: make(chan struct{})}
+
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+		ws.Close()
+	}()
+
+	go func() {
+		err := receiveWorkerTailEvents(ctx, ws, events)
+		close(stop)
+		close(events)
+		if derr := api.deleteWorkerTail(scriptName, tail.ID); err == nil && derr != nil {
+			err = errors.Wrap(derr, "failed to delete tail")
+		}
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(s.done)
+	}()
+
+	return s, nil
+}
+
+// receiveWorkerTailEvents decodes events from ws until ctx is done or the
+// connection fails.
+func receiveWorkerTailEvents(ctx context.Context, ws *websocket.Conn, events chan<- WorkerTailEvent) error {
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "tail connection closed")
+		}
+
+		var event WorkerTailEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return errors.Wrap(err, errUnmarshalError)
+		}
+
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// dialWorkerTail connects to a tail's WebSocket. The dial, TLS and
+// WebSocket handshakes are all abandoned once ctx is done.
+func (api *API) dialWorkerTail(ctx context.Context, tail WorkerTail) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig(tail.URL, api.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	config.Protocol = []string{workerTailProtocol}
+	config.Header = api.headers.Clone()
+	if config.Header == nil {
+		config.Header = http.Header{}
+	}
+	config.Header.Set("User-Agent", api.UserAgent)
+
+	host := config.Location.Host
+	if config.Location.Port() == "" {
+		port := "80"
+		if config.Location.Scheme == "wss" {
+			port = "443"
+		}
+		host = net.JoinHostPort(config.Location.Hostname(), port)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	// websocket.NewClient has no context, so close the connection to
+	// interrupt the handshakes if ctx is done first.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if config.Location.Scheme == "wss" {
+		tlsConfig := &tls.Config{ServerName: config.Location.Hostname()}
+		if config.TlsConfig != nil {
+			tlsConfig = config.TlsConfig.Clone()
+			if tlsConfig.ServerName == "" {
+				tlsConfig.ServerName = config.Location.Hostname()
+			}
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return ws, nil
+}
+
+// deleteWorkerTail deletes a tail once its session has ended. The session's
+// context is usually done by then, so a fresh one is used.
+func (api *API) deleteWorkerTail(scriptName, tailID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return api.DeleteWorkerTail(ctx, scriptName, tailID)
+}
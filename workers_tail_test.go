@@ -0,0 +1,186 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+const testWorkerTailEvent = `{
+	"outcome": "exception",
+	"scriptName": "api",
+	"exceptions": [{"name": "TypeError", "message": "x is undefined", "timestamp": 1650000000123}],
+	"logs": [{"message": ["handling", 1], "level": "log", "timestamp": 1650000000100}],
+	"eventTimestamp": 1650000000000,
+	"event": {
+		"request": {"url": "https://example.com/", "method": "GET", "headers": {"accept": "*/*"}, "cf": {"colo": "LHR"}},
+		"response": {"status": 500}
+	}
+}`
+
+func TestWorkerTailEvent_UnmarshalJSON(t *testing.T) {
+	var e WorkerTailEvent
+	require.NoError(t, e.UnmarshalJSON([]byte(testWorkerTailEvent)))
+
+	at := time.Date(2022, 4, 15, 5, 20, 0, 0, time.UTC)
+	assert.Equal(t, WorkerTailEvent{
+		ScriptName:     "api",
+		Outcome:        "exception",
+		EventTimestamp: at,
+		Logs:           []WorkerTailLog{{Level: "log", Message: []interface{}{"handling", float64(1)}, Timestamp: at.Add(100 * time.Millisecond)}},
+		Exceptions:     []WorkerTailException{{Name: "TypeError", Message: "x is undefined", Timestamp: at.Add(123 * time.Millisecond)}},
+		Request: &WorkerTailRequest{
+			URL:      "https://example.com/",
+			Method:   "GET",
+			Headers:  map[string]string{"accept": "*/*"},
+			CF:       map[string]interface{}{"colo": "LHR"},
+			Response: &WorkerTailResponseStatus{Status: 500},
+		},
+	}, e)
+
+	require.NoError(t, e.UnmarshalJSON([]byte(`{"outcome": "ok", "event": {"cron": "*/5 * * * *", "scheduledTime": 1650000000000}}`)))
+	assert.Nil(t, e.Request)
+	assert.Equal(t, &WorkerTailScheduled{Cron: "*/5 * * * *", ScheduledTime: at}, e.Scheduled)
+}
+
+func TestWorkerTailFilter(t *testing.T) {
+	msg, err := WorkerTailFilter{
+		SamplingRate: 0.5,
+		Status:       []string{"ok", "canceled"},
+		Method:       []string{"POST"},
+		Query:        "timeout",
+	}.message()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"filters": []map[string]interface{}{
+		{"sampling_rate": 0.5},
+		{"outcome": []string{"ok", "canceled"}},
+		{"method": []string{"POST"}},
+		{"query": "timeout"},
+	}}, msg)
+
+	_, err = WorkerTailFilter{Status: []string{"failed"}}.message()
+	assert.EqualError(t, err, `unknown status "failed"`)
+	_, err = WorkerTailFilter{SamplingRate: 2}.message()
+	assert.EqualError(t, err, "sampling rate 2 must be between 0 and 1")
+}
+
+func TestTailWorker(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+
+	filters := make(chan map[string]interface{}, 1)
+	mux.Handle("/tail", websocket.Handler(func(ws *websocket.Conn) {
+		assert.Equal(t, []string{"trace-v1"}, ws.Config().Protocol)
+
+		var msg map[string]interface{}
+		require.NoError(t, websocket.JSON.Receive(ws, &msg))
+		filters <- msg
+
+		require.NoError(t, websocket.Message.Send(ws, []byte(testWorkerTailEvent)))
+		// Hold the connection open until the client goes away.
+		var discard []byte
+		_ = websocket.Message.Receive(ws, &discard)
+	}))
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/scripts/api/tails", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method, "Expected method 'POST', got %s", r.Method)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": {
+			"id": "03dc9f77817b488fb26c5861ec18f791",
+			"url": %q,
+			"expires_at": "2022-04-15T11:20:00Z"
+		}}`, "ws"+strings.TrimPrefix(server.URL, "http")+"/tail")
+	})
+
+	deleted := make(chan struct{})
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/scripts/api/tails/03dc9f77817b488fb26c5861ec18f791", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method, "Expected method 'DELETE', got %s", r.Method)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": null}`)
+		close(deleted)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	session, err := client.TailWorker(ctx, "api", WorkerTailFilter{Method: []string{"GET"}})
+	require.NoError(t, err)
+	assert.Equal(t, "03dc9f77817b488fb26c5861ec18f791", session.Tail.ID)
+
+	select {
+	case event := <-session.Events:
+		assert.Equal(t, "exception", event.Outcome)
+		assert.Equal(t, 500, event.Request.Response.Status)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	assert.Equal(t, map[string]interface{}{"filters": []interface{}{
+		map[string]interface{}{"method": []interface{}{"GET"}},
+	}}, <-filters)
+
+	cancel()
+	for range session.Events {
+	}
+	assert.NoError(t, session.Wait())
+	select {
+	case <-deleted:
+	default:
+		t.Fatal("tail was not deleted")
+	}
+}
+
+func TestTailWorker_DialHonorsContext(t *testing.T) {
+	setup(UsingAccount(testAccountID))
+	defer teardown()
+
+	// A listener that accepts connections but never answers the handshake.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/scripts/api/tails", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": {
+			"id": "03dc9f77817b488fb26c5861ec18f791",
+			"url": %q,
+			"expires_at": "2022-04-15T11:20:00Z"
+		}}`, "ws://"+ln.Addr().String()+"/tail")
+	})
+	deleted := make(chan struct{})
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/scripts/api/tails/03dc9f77817b488fb26c5861ec18f791", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method, "Expected method 'DELETE', got %s", r.Method)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": null}`)
+		close(deleted)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.TailWorker(ctx, "api", WorkerTailFilter{})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	select {
+	case <-deleted:
+	default:
+		t.Fatal("tail was not deleted")
+	}
+}
@@ -129,7 +129,40 @@ const (
 			},
 			{
 				"name": "MY_NEW_BINDING",
-				"type": "some_imaginary_new_binding_type"
+				"type": "some_imaginary_new_binding_type",
+				"setting": 1
+			},
+			{
+				"name": "MY_DURABLE_OBJECT",
+				"type": "durable_object_namespace",
+				"class_name": "Counter",
+				"script_name": "counter-worker"
+			},
+			{
+				"name": "MY_SERVICE",
+				"type": "service",
+				"service": "auth",
+				"environment": "production"
+			},
+			{
+				"name": "MY_BUCKET",
+				"type": "r2_bucket",
+				"bucket_name": "assets"
+			},
+			{
+				"name": "MY_QUEUE",
+				"type": "queue",
+				"queue_name": "jobs"
+			},
+			{
+				"name": "MY_DATASET",
+				"type": "analytics_engine",
+				"dataset": "events"
+			},
+			{
+				"name": "MY_JSON",
+				"type": "json",
+				"json": {"debug": true}
 			}
 		],
 		"success": true,
@@ -193,8 +226,11 @@ func getFormValue(r *http.Request, key string) ([]byte, error) {
 }
 
 type multipartUpload = struct {
-	Script      string
-	BindingMeta map[string]workerBindingMeta
+	Script             string
+	BindingMeta        map[string]workerBindingMeta
+	MainModule         string
+	CompatibilityDate  string
+	CompatibilityFlags []string
 }
 
 func parseMultipartUpload(r *http.Request) (multipartUpload, error) {
@@ -205,8 +241,11 @@ func parseMultipartUpload(r *http.Request) (multipartUpload, error) {
 	}
 
 	var metadata struct {
-		BodyPart string              `json:"body_part"`
-		Bindings []workerBindingMeta `json:"bindings"`
+		BodyPart           string              `json:"body_part"`
+		MainModule         string              `json:"main_module"`
+		CompatibilityDate  string              `json:"compatibility_date"`
+		CompatibilityFlags []string            `json:"compatibility_flags"`
+		Bindings           []workerBindingMeta `json:"bindings"`
 	}
 	err = json.Unmarshal(mdBytes, &metadata)
 	if err != nil {
@@ -214,7 +253,11 @@ func parseMultipartUpload(r *http.Request) (multipartUpload, error) {
 	}
 
 	// Get the script
-	script, err := getFormValue(r, metadata.BodyPart)
+	scriptPart := metadata.BodyPart
+	if metadata.MainModule != "" {
+		scriptPart = metadata.MainModule
+	}
+	script, err := getFormValue(r, scriptPart)
 	if err != nil {
 		return multipartUpload{}, err
 	}
@@ -229,8 +272,11 @@ func parseMultipartUpload(r *http.Request) (multipartUpload, error) {
 	}
 
 	return multipartUpload{
-		Script:      string(script),
-		BindingMeta: bindingMeta,
+		Script:             string(script),
+		BindingMeta:        bindingMeta,
+		MainModule:         metadata.MainModule,
+		CompatibilityDate:  metadata.CompatibilityDate,
+		CompatibilityFlags: metadata.CompatibilityFlags,
 	}, nil
 }
 
@@ -936,7 +982,7 @@ func TestWorkers_ListWorkerBindingsMultiScript(t *testing.T) {
 	assert.NoError(t, err)
 
 	assert.Equal(t, successResponse, res.Response)
-	assert.Equal(t, 5, len(res.BindingList))
+	assert.Equal(t, 11, len(res.BindingList))
 
 	assert.Equal(t, res.BindingList[0], WorkerBindingListItem{
 		Name: "MY_KV",
@@ -968,10 +1014,66 @@ func TestWorkers_ListWorkerBindingsMultiScript(t *testing.T) {
 	assert.Equal(t, WorkerSecretTextBindingType, res.BindingList[3].Binding.Type())
 
 	assert.Equal(t, res.BindingList[4], WorkerBindingListItem{
-		Name:    "MY_NEW_BINDING",
-		Binding: WorkerInheritBinding{},
+		Name: "MY_NEW_BINDING",
+		Binding: WorkerUnknownBinding{
+			BindingType: "some_imaginary_new_binding_type",
+			Metadata:    map[string]interface{}{"setting": float64(1)},
+		},
+	})
+	assert.Equal(t, WorkerBindingType("some_imaginary_new_binding_type"), res.BindingList[4].Binding.Type())
+
+	assert.Equal(t, WorkerDurableObjectBinding{ClassName: "Counter", ScriptName: "counter-worker"}, res.BindingList[5].Binding)
+	assert.Equal(t, WorkerServiceBinding{Service: "auth", Environment: "production"}, res.BindingList[6].Binding)
+	assert.Equal(t, WorkerR2BucketBinding{BucketName: "assets"}, res.BindingList[7].Binding)
+	assert.Equal(t, WorkerQueueBinding{QueueName: "jobs"}, res.BindingList[8].Binding)
+	assert.Equal(t, WorkerAnalyticsEngineBinding{Dataset: "events"}, res.BindingList[9].Binding)
+	assert.Equal(t, WorkerJSONBinding{Value: map[string]interface{}{"debug": true}}, res.BindingList[10].Binding)
+}
+
+func TestWorkers_UploadWorkerWithAdditionalBindings(t *testing.T) {
+	setup(UsingAccount("foo"))
+	defer teardown()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mpUpload, err := parseMultipartUpload(r)
+		assert.NoError(t, err)
+
+		expectedBindings := map[string]workerBindingMeta{
+			"DO":      {"name": "DO", "type": "durable_object_namespace", "class_name": "Counter"},
+			"AUTH":    {"name": "AUTH", "type": "service", "service": "auth", "environment": "staging"},
+			"BUCKET":  {"name": "BUCKET", "type": "r2_bucket", "bucket_name": "assets"},
+			"QUEUE":   {"name": "QUEUE", "type": "queue", "queue_name": "jobs"},
+			"EVENTS":  {"name": "EVENTS", "type": "analytics_engine", "dataset": "events"},
+			"CONFIG":  {"name": "CONFIG", "type": "json", "json": map[string]interface{}{"retries": float64(3)}},
+			"UNKNOWN": {"name": "UNKNOWN", "type": "future_binding", "setting": "x"},
+		}
+		assert.Equal(t, expectedBindings, mpUpload.BindingMeta)
+
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, uploadWorkerResponseData) //nolint
+	}
+	mux.HandleFunc("/accounts/foo/workers/scripts/bar", handler)
+
+	scriptParams := WorkerScriptParams{
+		Script: workerScript,
+		Bindings: map[string]WorkerBinding{
+			"DO":      WorkerDurableObjectBinding{ClassName: "Counter"},
+			"AUTH":    WorkerServiceBinding{Service: "auth", Environment: "staging"},
+			"BUCKET":  WorkerR2BucketBinding{BucketName: "assets"},
+			"QUEUE":   WorkerQueueBinding{QueueName: "jobs"},
+			"EVENTS":  WorkerAnalyticsEngineBinding{Dataset: "events"},
+			"CONFIG":  WorkerJSONBinding{Value: map[string]int{"retries": 3}},
+			"UNKNOWN": WorkerUnknownBinding{BindingType: "future_binding", Metadata: map[string]interface{}{"setting": "x"}},
+		},
+	}
+	_, err := client.UploadWorkerWithBindings(context.Background(), &WorkerRequestParams{ScriptName: "bar"}, &scriptParams)
+	assert.NoError(t, err)
+
+	_, err = client.UploadWorkerWithBindings(context.Background(), &WorkerRequestParams{ScriptName: "bar"}, &WorkerScriptParams{
+		Script:   workerScript,
+		Bindings: map[string]WorkerBinding{"DO": WorkerDurableObjectBinding{}},
 	})
-	assert.Equal(t, WorkerInheritBindingType, res.BindingList[4].Binding.Type())
+	assert.EqualError(t, err, `ClassName for binding "DO" cannot be empty`)
 }
 
 func TestWorkers_UpdateWorkerRouteErrorsWhenMixingSingleAndMultiScriptProperties(t *testing.T) {
@@ -996,3 +1098,76 @@ func TestWorkers_UpdateWorkerRouteWithNoScript(t *testing.T) {
 	_, err := client.UpdateWorkerRoute(context.Background(), "foo", "e7a57d8746e74ae49c25994dadb421b1", route)
 	assert.NoError(t, err)
 }
+
+func TestWorkers_UploadWorkerAsModule(t *testing.T) {
+	setup(UsingAccount("foo"))
+	defer teardown()
+
+	moduleScript := "import { greet } from './lib/greet.mjs';\nexport default { fetch() { return new Response(greet()); } };"
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method, "Expected method 'PUT', got %s", r.Method)
+
+		mpUpload, err := parseMultipartUpload(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "worker.mjs", mpUpload.MainModule)
+		assert.Equal(t, moduleScript, mpUpload.Script)
+		assert.Equal(t, "2021-11-02", mpUpload.CompatibilityDate)
+		assert.Equal(t, []string{"formdata_parser_supports_files"}, mpUpload.CompatibilityFlags)
+		assert.Equal(t, map[string]workerBindingMeta{
+			"GREETING": {"name": "GREETING", "type": "plain_text", "text": "hello"},
+		}, mpUpload.BindingMeta)
+
+		contentTypes := make(map[string]string)
+		for name, files := range r.MultipartForm.File {
+			contentTypes[name] = files[0].Header.Get("content-type")
+		}
+		assert.Equal(t, map[string]string{
+			"worker.mjs":    "application/javascript+module",
+			"lib/greet.mjs": "application/javascript+module",
+			"add.wasm":      "application/wasm",
+			"greeting.txt":  "text/plain",
+			"legacy.js":     "application/javascript",
+		}, contentTypes)
+
+		wasm, err := getFormValue(r, "add.wasm")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x00, 0x61, 0x73, 0x6d}, wasm)
+
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, uploadWorkerResponseData) //nolint
+	}
+	mux.HandleFunc("/accounts/foo/workers/scripts/bar", handler)
+
+	scriptParams := WorkerScriptParams{
+		Script:     moduleScript,
+		MainModule: "worker.mjs",
+		Modules: []WorkerModule{
+			{Name: "lib/greet.mjs", Content: []byte("export function greet() { return 'hi'; }")},
+			{Name: "add.wasm", Content: []byte{0x00, 0x61, 0x73, 0x6d}},
+			{Name: "greeting.txt", Content: []byte("hello")},
+			{Name: "legacy.js", Type: WorkerModuleTypeCommonJS, Content: []byte("module.exports = {};")},
+		},
+		CompatibilityDate:  "2021-11-02",
+		CompatibilityFlags: []string{"formdata_parser_supports_files"},
+		Bindings: map[string]WorkerBinding{
+			"GREETING": WorkerPlainTextBinding{Text: "hello"},
+		},
+	}
+	_, err := client.UploadWorkerWithBindings(context.Background(), &WorkerRequestParams{ScriptName: "bar"}, &scriptParams)
+	assert.NoError(t, err)
+}
+
+func TestFormatMultipartBody_ModuleErrors(t *testing.T) {
+	_, _, err := formatMultipartBody(&WorkerScriptParams{Modules: []WorkerModule{{Name: "a.mjs"}}})
+	assert.EqualError(t, err, "MainModule is required when uploading modules")
+
+	_, _, err = formatMultipartBody(&WorkerScriptParams{MainModule: "index.mjs"})
+	assert.EqualError(t, err, `main module "index.mjs" not found in modules`)
+
+	_, _, err = formatMultipartBody(&WorkerScriptParams{
+		MainModule: "index.mjs",
+		Modules:    []WorkerModule{{Name: "index.mjs"}, {Name: "index.mjs"}},
+	})
+	assert.EqualError(t, err, `duplicate module "index.mjs"`)
+}
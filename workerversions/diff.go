@@ -0,0 +1,217 @@
+package workerversions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+type diffOp byte
+
+const (
+	diffEqual  diffOp = ' '
+	diffDelete diffOp = '-'
+	diffInsert diffOp = '+'
+)
+
+type diffEdit struct {
+	op   diffOp
+	line string
+}
+
+// unifiedDiff returns the differences between a and b in unified format,
+// or an empty string if they are equal.
+func unifiedDiff(aName, bName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+
+	// Walk the edits, emitting a hunk for each run of changes that are
+	// closer together than twice the context.
+	aLine, bLine := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	for i, e := range edits {
+		aLine[i+1], bLine[i+1] = aLine[i], bLine[i]
+		if e.op != diffInsert {
+			aLine[i+1]++
+		}
+		if e.op != diffDelete {
+			bLine[i+1]++
+		}
+	}
+
+	for i := 0; i < len(edits); {
+		if edits[i].op == diffEqual {
+			i++
+			continue
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].op != diffEqual {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		end += diffContext
+		if end > len(edits) {
+			end = len(edits)
+		}
+
+		aStart, aLen := aLine[start], aLine[end]-aLine[start]
+		bStart, bLen := bLine[start], bLine[end]-bLine[start]
+		if aLen > 0 {
+			aStart++
+		}
+		if bLen > 0 {
+			bStart++
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+		for _, e := range edits[start:end] {
+			out.WriteByte(byte(e.op))
+			out.WriteString(e.line)
+			out.WriteByte('\n')
+		}
+		i = end
+	}
+	return out.String()
+}
+
+func hunkRange(start, n int) string {
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines computes the shortest edit script turning a into b using the
+// linear space variant of Myers' algorithm, which splits the inputs at the
+// middle of the edit path and diffs both halves in turn.
+func diffLines(a, b []string) []diffEdit {
+	d := &differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b))
+	return d.edits
+}
+
+type differ struct {
+	a, b  []string
+	edits []diffEdit
+
+	// vf and vb hold the furthest reaching forward and backward paths of
+	// middleSnake, reused between calls.
+	vf, vb []int
+}
+
+// compare appends the edits turning a[a0:a1] into b[b0:b1].
+func (d *differ) compare(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.edits = append(d.edits, diffEdit{diffEqual, d.a[a0]})
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a0 < a1-suffix && b0 < b1-suffix && d.a[a1-suffix-1] == d.b[b1-suffix-1] {
+		suffix++
+	}
+	a1 -= suffix
+	b1 -= suffix
+
+	switch {
+	case a0 == a1:
+		for ; b0 < b1; b0++ {
+			d.edits = append(d.edits, diffEdit{diffInsert, d.b[b0]})
+		}
+	case b0 == b1:
+		for ; a0 < a1; a0++ {
+			d.edits = append(d.edits, diffEdit{diffDelete, d.a[a0]})
+		}
+	default:
+		x, y, u, v := d.middleSnake(a0, a1, b0, b1)
+		d.compare(a0, x, b0, y)
+		for ; x < u; x, y = x+1, y+1 {
+			d.edits = append(d.edits, diffEdit{diffEqual, d.a[x]})
+		}
+		d.compare(u, a1, v, b1)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.edits = append(d.edits, diffEdit{diffEqual, d.a[a1+i]})
+	}
+}
+
+// middleSnake finds the run of equal lines, from (x, y) to (u, v), in the
+// middle of a shortest edit path turning a[a0:a1] into b[b0:b1]. Both
+// inputs must be non-empty and start and end with different lines.
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (x, y, u, v int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta&1 != 0
+	max := (n + m + 1) / 2
+	offset := max + 1
+
+	if size := 2*max + 3; len(d.vf) < size {
+		d.vf, d.vb = make([]int, size), make([]int, size)
+	}
+	vf, vb := d.vf, d.vb
+	vf[offset+1], vb[offset+1] = 0, 0
+
+	for D := 0; D <= max; D++ {
+		// Forward paths, in offsets from (a0, b0) along diagonal k = x-y.
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+			if rk := delta - k; odd && rk >= -(D-1) && rk <= D-1 && x+vb[offset+rk] >= n {
+				return a0 + sx, b0 + sy, a0 + x, b0 + y
+			}
+		}
+
+		// Backward paths, in offsets from (a1, b1) along diagonal rk,
+		// which is diagonal delta-rk going forward.
+		for rk := -D; rk <= D; rk += 2 {
+			var x int
+			if rk == -D || (rk != D && vb[offset+rk-1] < vb[offset+rk+1]) {
+				x = vb[offset+rk+1]
+			} else {
+				x = vb[offset+rk-1] + 1
+			}
+			y := x - rk
+			sx, sy := x, y
+			for x < n && y < m && d.a[a1-x-1] == d.b[b1-y-1] {
+				x++
+				y++
+			}
+			vb[offset+rk] = x
+			if k := delta - rk; !odd && k >= -D && k <= D && x+vf[offset+k] >= n {
+				return a1 - x, b1 - y, a1 - sx, b1 - sy
+			}
+		}
+	}
+	panic("workerversions: no middle snake found")
+}
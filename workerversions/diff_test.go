@@ -0,0 +1,111 @@
+package workerversions
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n15\n16\n"
+
+	assert.Equal(t, `--- a/x
++++ b/x
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -11,5 +11,5 @@
+ 11
+ 12
+ 13
+-14
+ 15
++16
+`, unifiedDiff("a/x", "b/x", a, b))
+}
+
+func TestUnifiedDiff_MergesCloseHunks(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\n"
+	b := "A\nb\nc\nd\ne\nf\nG\n"
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,7 +1,7 @@\n-a\n+A\n b\n c\n d\n e\n f\n-g\n+G\n", unifiedDiff("a", "b", a, b))
+}
+
+func TestUnifiedDiff_AddedFile(t *testing.T) {
+	assert.Equal(t, "--- /dev/null\n+++ b/x\n@@ -0,0 +1,2 @@\n+one\n+two\n", unifiedDiff("/dev/null", "b/x", "", "one\ntwo\n"))
+	assert.Equal(t, "", unifiedDiff("a", "b", "same\n", "same\n"))
+}
+
+func TestDiffLines_Large(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 5000; i++ {
+		line := strings.Repeat("x", i%7)
+		a = append(a, line)
+		b = append(b, line)
+	}
+	b[2500] = "changed"
+
+	var changes int
+	for _, e := range diffLines(a, b) {
+		if e.op != diffEqual {
+			changes++
+		}
+	}
+	assert.Equal(t, 2, changes)
+}
+
+func TestDiffLines_Minimal(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, rnd.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rnd.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		var gotA, gotB []string
+		changes := 0
+		for _, e := range diffLines(a, b) {
+			if e.op != diffInsert {
+				gotA = append(gotA, e.line)
+			}
+			if e.op != diffDelete {
+				gotB = append(gotB, e.line)
+			}
+			if e.op != diffEqual {
+				changes++
+			}
+		}
+		assert.Equal(t, strings.Join(a, ","), strings.Join(gotA, ","))
+		assert.Equal(t, strings.Join(b, ","), strings.Join(gotB, ","))
+		assert.Equal(t, len(a)+len(b)-2*lcsLength(a, b), changes, "%v %v", a, b)
+	}
+}
+
+func lcsLength(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,145 @@
+package workerversions
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
+)
+
+// ErrSnapshotNotFound is returned by a Store when a snapshot does not exist.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Store persists snapshots. Snapshot IDs sort in the order they were taken.
+type Store interface {
+	Save(ctx context.Context, s Snapshot) error
+	Load(ctx context.Context, scriptName, id string) (Snapshot, error)
+	// List returns the IDs of a script's snapshots, oldest first.
+	List(ctx context.Context, scriptName string) ([]string, error)
+}
+
+// DirStore keeps snapshots as JSON files in a directory, one
+// subdirectory per script.
+type DirStore struct {
+	Dir string
+}
+
+func (s DirStore) path(scriptName, id string) string {
+	return filepath.Join(s.Dir, scriptName, id+".json")
+}
+
+// Save implements Store.
+func (s DirStore) Save(ctx context.Context, snap Snapshot) error {
+	b, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(s.Dir, snap.ScriptName), 0o755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path(snap.ScriptName, snap.ID), b, 0o644)
+}
+
+// Load implements Store.
+func (s DirStore) Load(ctx context.Context, scriptName, id string) (Snapshot, error) {
+	b, err := ioutil.ReadFile(s.path(scriptName, id))
+	if os.IsNotExist(err) {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return Snapshot{}, errors.Wrapf(err, "could not parse snapshot %s", id)
+	}
+	return snap, nil
+}
+
+// List implements Store.
+func (s DirStore) List(ctx context.Context, scriptName string) ([]string, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(s.Dir, scriptName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(e.Name(), ".json"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// KVStore keeps snapshots in a Workers KV namespace under keys of the form
+// "<Prefix><script>/<id>". API must be scoped to the namespace's account.
+type KVStore struct {
+	API         *cloudflare.API
+	NamespaceID string
+	Prefix      string
+}
+
+func (s KVStore) key(scriptName, id string) string {
+	return s.Prefix + scriptName + "/" + id
+}
+
+// Save implements Store.
+func (s KVStore) Save(ctx context.Context, snap Snapshot) error {
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	_, err = s.API.WriteWorkersKV(ctx, s.NamespaceID, s.key(snap.ScriptName, snap.ID), b)
+	return err
+}
+
+// Load implements Store.
+func (s KVStore) Load(ctx context.Context, scriptName, id string) (Snapshot, error) {
+	b, err := s.API.ReadWorkersKV(ctx, s.NamespaceID, s.key(scriptName, id))
+	if err != nil {
+		var apiErr *cloudflare.APIRequestError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == 404 {
+			return Snapshot{}, ErrSnapshotNotFound
+		}
+		return Snapshot{}, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return Snapshot{}, errors.Wrapf(err, "could not parse snapshot %s", id)
+	}
+	return snap, nil
+}
+
+// List implements Store.
+func (s KVStore) List(ctx context.Context, scriptName string) ([]string, error) {
+	prefix := s.key(scriptName, "")
+	opts := cloudflare.ListWorkersKVsOptions{Prefix: &prefix}
+
+	var ids []string
+	for {
+		res, err := s.API.ListWorkersKVsWithOptions(ctx, s.NamespaceID, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range res.Result {
+			ids = append(ids, strings.TrimPrefix(k.Name, prefix))
+		}
+		if res.Cursor == "" {
+			break
+		}
+		cursor := res.Cursor
+		opts.Cursor = &cursor
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
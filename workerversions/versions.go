@@ -0,0 +1,361 @@
+// Package workerversions keeps a history of Worker scripts so that an
+// upload can be compared with, and rolled back to, any earlier version.
+//
+// Every upload made through Versions first snapshots the deployed script
+// and its bindings into a Store. Secrets cannot be read back from the API,
+// so they are recorded by name only and keep their current value when a
+// snapshot is restored.
+package workerversions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"sort"
+	"strings"
+	"time"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
+)
+
+// Snapshot is a Worker script, its bindings and its compatibility settings
+// at a point in time.
+type Snapshot struct {
+	ID         string    `json:"id"`
+	ScriptName string    `json:"script_name"`
+	TakenAt    time.Time `json:"taken_at"`
+
+	// Script is the body of a service worker script. ES module Workers
+	// have MainModule and Modules instead.
+	Script     string   `json:"script,omitempty"`
+	MainModule string   `json:"main_module,omitempty"`
+	Modules    []Module `json:"modules,omitempty"`
+
+	CompatibilityDate  string   `json:"compatibility_date,omitempty"`
+	CompatibilityFlags []string `json:"compatibility_flags,omitempty"`
+
+	Bindings []Binding `json:"bindings"`
+}
+
+// Module is one module of an ES module Worker.
+type Module struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content []byte `json:"content"`
+}
+
+// Binding is the serializable form of a cloudflare.WorkerBinding. Only the
+// fields of its Type are set.
+type Binding struct {
+	Name string                       `json:"name,omitempty"`
+	Type cloudflare.WorkerBindingType `json:"type,omitempty"`
+
+	Text        string                 `json:"text,omitempty"`
+	NamespaceID string                 `json:"namespace_id,omitempty"`
+	ClassName   string                 `json:"class_name,omitempty"`
+	ScriptName  string                 `json:"script_name,omitempty"`
+	Service     string                 `json:"service,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	BucketName  string                 `json:"bucket_name,omitempty"`
+	QueueName   string                 `json:"queue_name,omitempty"`
+	Dataset     string                 `json:"dataset,omitempty"`
+	JSON        interface{}            `json:"json,omitempty"`
+	Module      []byte                 `json:"module,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+func newBinding(name string, wb cloudflare.WorkerBinding) (Binding, error) {
+	b := Binding{Name: name, Type: wb.Type()}
+	switch wb := wb.(type) {
+	case cloudflare.WorkerPlainTextBinding:
+		b.Text = wb.Text
+	case cloudflare.WorkerSecretTextBinding:
+		// The value is never returned by the API.
+	case cloudflare.WorkerKvNamespaceBinding:
+		b.NamespaceID = wb.NamespaceID
+	case cloudflare.WorkerDurableObjectBinding:
+		b.ClassName, b.ScriptName = wb.ClassName, wb.ScriptName
+	case cloudflare.WorkerServiceBinding:
+		b.Service, b.Environment = wb.Service, wb.Environment
+	case cloudflare.WorkerR2BucketBinding:
+		b.BucketName = wb.BucketName
+	case cloudflare.WorkerQueueBinding:
+		b.QueueName = wb.QueueName
+	case cloudflare.WorkerAnalyticsEngineBinding:
+		b.Dataset = wb.Dataset
+	case cloudflare.WorkerJSONBinding:
+		b.JSON = wb.Value
+	case cloudflare.WorkerWebAssemblyBinding:
+		module, err := ioutil.ReadAll(wb.Module)
+		if err != nil {
+			return b, errors.Wrapf(err, "failed to download WebAssembly module %q", name)
+		}
+		b.Module = module
+	case cloudflare.WorkerUnknownBinding:
+		b.Metadata = wb.Metadata
+	default:
+		return b, errors.Errorf("unsupported binding type %q", wb.Type())
+	}
+	return b, nil
+}
+
+// workerBinding converts the binding back for uploading.
+func (b Binding) workerBinding() cloudflare.WorkerBinding {
+	switch b.Type {
+	case cloudflare.WorkerPlainTextBindingType:
+		return cloudflare.WorkerPlainTextBinding{Text: b.Text}
+	case cloudflare.WorkerSecretTextBindingType:
+		return cloudflare.WorkerInheritBinding{}
+	case cloudflare.WorkerKvNamespaceBindingType:
+		return cloudflare.WorkerKvNamespaceBinding{NamespaceID: b.NamespaceID}
+	case cloudflare.WorkerDurableObjectBindingType:
+		return cloudflare.WorkerDurableObjectBinding{ClassName: b.ClassName, ScriptName: b.ScriptName}
+	case cloudflare.WorkerServiceBindingType:
+		return cloudflare.WorkerServiceBinding{Service: b.Service, Environment: b.Environment}
+	case cloudflare.WorkerR2BucketBindingType:
+		return cloudflare.WorkerR2BucketBinding{BucketName: b.BucketName}
+	case cloudflare.WorkerQueueBindingType:
+		return cloudflare.WorkerQueueBinding{QueueName: b.QueueName}
+	case cloudflare.WorkerAnalyticsEngineBindingType:
+		return cloudflare.WorkerAnalyticsEngineBinding{Dataset: b.Dataset}
+	case cloudflare.WorkerJSONBindingType:
+		return cloudflare.WorkerJSONBinding{Value: b.JSON}
+	case cloudflare.WorkerWebAssemblyBindingType:
+		return cloudflare.WorkerWebAssemblyBinding{Module: strings.NewReader(string(b.Module))}
+	}
+	return cloudflare.WorkerUnknownBinding{BindingType: b.Type, Metadata: b.Metadata}
+}
+
+// String renders the binding on a single line for diffs.
+func (b Binding) String() string {
+	settings := b
+	settings.Name, settings.Type = "", ""
+	raw, _ := json.Marshal(settings)
+	return fmt.Sprintf("%s (%s) %s", b.Name, b.Type, raw)
+}
+
+// params returns the upload that recreates the snapshot.
+func (s Snapshot) params() *cloudflare.WorkerScriptParams {
+	p := &cloudflare.WorkerScriptParams{
+		Script:             s.Script,
+		MainModule:         s.MainModule,
+		CompatibilityDate:  s.CompatibilityDate,
+		CompatibilityFlags: s.CompatibilityFlags,
+		Bindings:           make(map[string]cloudflare.WorkerBinding, len(s.Bindings)),
+	}
+	for _, m := range s.Modules {
+		p.Modules = append(p.Modules, cloudflare.WorkerModule{Name: m.Name, Type: cloudflare.WorkerModuleType(m.Type), Content: m.Content})
+	}
+	for _, b := range s.Bindings {
+		p.Bindings[b.Name] = b.workerBinding()
+	}
+	return p
+}
+
+// Versions uploads Worker scripts, snapshotting the deployed version into
+// Store before each change. API must be scoped to the Workers' account.
+type Versions struct {
+	API   *cloudflare.API
+	Store Store
+}
+
+// Capture returns a snapshot of the deployed script without saving it.
+func (v *Versions) Capture(ctx context.Context, scriptName string) (Snapshot, error) {
+	req := &cloudflare.WorkerRequestParams{ScriptName: scriptName}
+	script, err := v.API.DownloadWorker(ctx, req)
+	if err != nil {
+		return Snapshot{}, errors.Wrap(err, "failed to download Worker")
+	}
+	bindings, err := v.API.ListWorkerBindings(ctx, req)
+	if err != nil {
+		return Snapshot{}, errors.Wrap(err, "failed to list Worker bindings")
+	}
+	// Compatibility settings are only returned with the list of scripts.
+	scripts, err := v.API.ListWorkerScripts(ctx)
+	if err != nil {
+		return Snapshot{}, errors.Wrap(err, "failed to list Worker scripts")
+	}
+
+	now := time.Now().UTC()
+	s := Snapshot{
+		ID:         now.Format("20060102T150405.000000000Z"),
+		ScriptName: scriptName,
+		TakenAt:    now,
+		Bindings:   make([]Binding, 0, len(bindings.BindingList)),
+	}
+	for _, meta := range scripts.WorkerList {
+		if meta.ID == scriptName {
+			s.CompatibilityDate, s.CompatibilityFlags = meta.CompatibilityDate, meta.CompatibilityFlags
+			break
+		}
+	}
+	s.MainModule, s.Modules = parseModules(script.Script)
+	if s.Modules == nil {
+		s.Script = script.Script
+	}
+	for _, item := range bindings.BindingList {
+		b, err := newBinding(item.Name, item.Binding)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		s.Bindings = append(s.Bindings, b)
+	}
+	sort.Slice(s.Bindings, func(i, j int) bool { return s.Bindings[i].Name < s.Bindings[j].Name })
+	return s, nil
+}
+
+// Snapshot captures the deployed script and saves it to the store.
+func (v *Versions) Snapshot(ctx context.Context, scriptName string) (Snapshot, error) {
+	s, err := v.Capture(ctx, scriptName)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if err := v.Store.Save(ctx, s); err != nil {
+		return Snapshot{}, errors.Wrap(err, "failed to save snapshot")
+	}
+	return s, nil
+}
+
+// Upload snapshots the deployed script, if there is one, and then uploads
+// params in its place. It returns the snapshot, which is nil for a new
+// script.
+func (v *Versions) Upload(ctx context.Context, scriptName string, params *cloudflare.WorkerScriptParams) (*Snapshot, error) {
+	previous, err := v.snapshotIfExists(ctx, scriptName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := v.API.UploadWorkerWithBindings(ctx, &cloudflare.WorkerRequestParams{ScriptName: scriptName}, params); err != nil {
+		return previous, errors.Wrap(err, "failed to upload Worker")
+	}
+	return previous, nil
+}
+
+// Rollback restores the snapshot with the given ID. The deployed script is
+// snapshotted first, so a rollback can itself be undone; that snapshot is
+// returned.
+func (v *Versions) Rollback(ctx context.Context, scriptName, id string) (*Snapshot, error) {
+	target, err := v.Store.Load(ctx, scriptName, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load snapshot %s", id)
+	}
+	return v.Upload(ctx, scriptName, target.params())
+}
+
+// DiffDeployed compares the snapshot with the given ID to the deployed
+// script, showing what a rollback to it would change.
+func (v *Versions) DiffDeployed(ctx context.Context, scriptName, id string) (string, error) {
+	old, err := v.Store.Load(ctx, scriptName, id)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load snapshot %s", id)
+	}
+	current, err := v.Capture(ctx, scriptName)
+	if err != nil {
+		return "", err
+	}
+	return Diff(current, old), nil
+}
+
+func (v *Versions) snapshotIfExists(ctx context.Context, scriptName string) (*Snapshot, error) {
+	s, err := v.Snapshot(ctx, scriptName)
+	if err != nil {
+		var apiErr *cloudflare.APIRequestError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == 404 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Diff returns a unified diff of the script, or each module, and the
+// bindings of two snapshots. It is empty if they are the same.
+func Diff(a, b Snapshot) string {
+	aFiles, bFiles := a.files(), b.files()
+
+	var names []string
+	for name := range aFiles {
+		names = append(names, name)
+	}
+	for name := range bFiles {
+		if _, ok := aFiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var out strings.Builder
+	for _, name := range names {
+		aName, bName := "a/"+name, "b/"+name
+		if _, ok := aFiles[name]; !ok {
+			aName = "/dev/null"
+		}
+		if _, ok := bFiles[name]; !ok {
+			bName = "/dev/null"
+		}
+		out.WriteString(unifiedDiff(aName, bName, aFiles[name], bFiles[name]))
+	}
+	return out.String()
+}
+
+// files returns the snapshot as named text files for diffing.
+func (s Snapshot) files() map[string]string {
+	files := make(map[string]string)
+	if s.Modules == nil {
+		files[s.ScriptName+".js"] = s.Script
+	}
+	for _, m := range s.Modules {
+		files[m.Name] = string(m.Content)
+	}
+
+	var bindings strings.Builder
+	for _, b := range s.Bindings {
+		bindings.WriteString(b.String())
+		bindings.WriteByte('\n')
+	}
+	files["bindings"] = bindings.String()
+
+	if s.CompatibilityDate != "" || len(s.CompatibilityFlags) > 0 {
+		flags, _ := json.Marshal(append([]string{}, s.CompatibilityFlags...))
+		files["settings"] = fmt.Sprintf("compatibility_date = %q\ncompatibility_flags = %s\n", s.CompatibilityDate, flags)
+	}
+	return files
+}
+
+// parseModules splits the multipart body that ES module Workers are
+// downloaded as into modules. The first part is the main module. It
+// returns no modules if script is a plain service worker.
+func parseModules(script string) (string, []Module) {
+	firstLine := script
+	if i := strings.IndexByte(script, '\n'); i >= 0 {
+		firstLine = script[:i]
+	}
+	firstLine = strings.TrimSuffix(firstLine, "\r")
+	if !strings.HasPrefix(firstLine, "--") || len(firstLine) < 3 || strings.ContainsAny(firstLine, " \t") {
+		return "", nil
+	}
+
+	mr := multipart.NewReader(strings.NewReader(script), firstLine[2:])
+	var modules []Module
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF && len(modules) > 0 {
+			return modules[0].Name, modules
+		}
+		if err != nil {
+			return "", nil
+		}
+		content, err := ioutil.ReadAll(part)
+		if err != nil {
+			return "", nil
+		}
+		name := part.FileName()
+		if name == "" {
+			name = part.FormName()
+		}
+		modules = append(modules, Module{Name: name, Type: part.Header.Get("Content-Type"), Content: content})
+	}
+}
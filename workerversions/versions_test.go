@@ -0,0 +1,248 @@
+package workerversions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAccountID = "01a7362d577a6c3019a474fd6f485823"
+
+func newTestAPI(t *testing.T, mux *http.ServeMux) *cloudflare.API {
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	api, err := cloudflare.New("deadbeef", "cloudflare@example.org",
+		cloudflare.BaseURL(server.URL),
+		cloudflare.UsingAccount(testAccountID),
+		cloudflare.UsingRateLimit(100000),
+		cloudflare.UsingRetryPolicy(0, 0, 0))
+	require.NoError(t, err)
+	return api
+}
+
+func writeResult(w http.ResponseWriter, result string) {
+	w.Header().Set("content-type", "application/json")
+	fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": %s}`, result)
+}
+
+// fakeWorker serves a single service worker script, keeping whatever is
+// uploaded to it.
+type fakeWorker struct {
+	script             string
+	compatibilityDate  string
+	compatibilityFlags []string
+	bindings           []map[string]interface{}
+	uploaded           []map[string]interface{}
+}
+
+func (f *fakeWorker) register(t *testing.T, mux *http.ServeMux, name string) {
+	path := "/accounts/" + testAccountID + "/workers/scripts/" + name
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if f.script == "" {
+				w.Header().Set("content-type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"success": false, "errors": [{"code": 10007, "message": "workers.api.error.script_not_found"}], "messages": [], "result": null}`)
+				return
+			}
+			fmt.Fprint(w, f.script)
+		case http.MethodPut:
+			require.NoError(t, r.ParseMultipartForm(1<<20))
+			var meta struct {
+				Bindings           []map[string]interface{} `json:"bindings"`
+				CompatibilityDate  string                   `json:"compatibility_date"`
+				CompatibilityFlags []string                 `json:"compatibility_flags"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(r.MultipartForm.Value["metadata"][0]), &meta))
+			f.script = r.MultipartForm.Value["script"][0]
+			f.compatibilityDate, f.compatibilityFlags = meta.CompatibilityDate, meta.CompatibilityFlags
+			f.uploaded = meta.Bindings
+
+			var bindings []map[string]interface{}
+			for _, b := range meta.Bindings {
+				if b["type"] == "inherit" {
+					for _, old := range f.bindings {
+						if old["name"] == b["name"] {
+							b = old
+						}
+					}
+				}
+				bindings = append(bindings, b)
+			}
+			f.bindings = bindings
+			writeResult(w, `{"id": "`+name+`"}`)
+		}
+	})
+	mux.HandleFunc(path+"/bindings", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := json.Marshal(f.bindings)
+		writeResult(w, string(b))
+	})
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/scripts", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := json.Marshal([]cloudflare.WorkerMetaData{{
+			ID:                 name,
+			CompatibilityDate:  f.compatibilityDate,
+			CompatibilityFlags: f.compatibilityFlags,
+		}})
+		writeResult(w, string(b))
+	})
+}
+
+func TestVersions_UploadAndRollback(t *testing.T) {
+	mux := http.NewServeMux()
+	worker := &fakeWorker{
+		script:             "addEventListener('fetch', e => e.respondWith(new Response('v1')))\n",
+		compatibilityDate:  "2021-11-01",
+		compatibilityFlags: []string{"formdata_parser_supports_files"},
+		bindings: []map[string]interface{}{
+			{"name": "GREETING", "type": "plain_text", "text": "hello"},
+			{"name": "TOKEN", "type": "secret_text"},
+		},
+	}
+	worker.register(t, mux, "api")
+
+	store := DirStore{Dir: t.TempDir()}
+	v := &Versions{API: newTestAPI(t, mux), Store: store}
+	ctx := context.Background()
+
+	previous, err := v.Upload(ctx, "api", &cloudflare.WorkerScriptParams{
+		Script:            "addEventListener('fetch', e => e.respondWith(new Response('v2')))\n",
+		CompatibilityDate: "2022-03-21",
+		Bindings: map[string]cloudflare.WorkerBinding{
+			"GREETING": cloudflare.WorkerPlainTextBinding{Text: "hi"},
+			"TOKEN":    cloudflare.WorkerInheritBinding{},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, previous)
+	assert.Contains(t, previous.Script, "'v1'")
+	assert.Contains(t, worker.script, "'v2'")
+
+	ids, err := store.List(ctx, "api")
+	require.NoError(t, err)
+	assert.Equal(t, []string{previous.ID}, ids)
+
+	diff, err := v.DiffDeployed(ctx, "api", previous.ID)
+	require.NoError(t, err)
+	assert.Equal(t, `--- a/api.js
++++ b/api.js
+@@ -1 +1 @@
+-addEventListener('fetch', e => e.respondWith(new Response('v2')))
++addEventListener('fetch', e => e.respondWith(new Response('v1')))
+--- a/bindings
++++ b/bindings
+@@ -1,2 +1,2 @@
+-GREETING (plain_text) {"text":"hi"}
++GREETING (plain_text) {"text":"hello"}
+ TOKEN (secret_text) {}
+--- a/settings
++++ b/settings
+@@ -1,2 +1,2 @@
+-compatibility_date = "2022-03-21"
+-compatibility_flags = []
++compatibility_date = "2021-11-01"
++compatibility_flags = ["formdata_parser_supports_files"]
+`, diff)
+
+	rolledBack, err := v.Rollback(ctx, "api", previous.ID)
+	require.NoError(t, err)
+	assert.Contains(t, rolledBack.Script, "'v2'")
+	assert.Contains(t, worker.script, "'v1'")
+	assert.Equal(t, "2021-11-01", worker.compatibilityDate)
+	assert.Equal(t, []string{"formdata_parser_supports_files"}, worker.compatibilityFlags)
+	assert.ElementsMatch(t, []map[string]interface{}{
+		{"name": "GREETING", "type": "plain_text", "text": "hello"},
+		{"name": "TOKEN", "type": "inherit"},
+	}, worker.uploaded)
+
+	ids, err = store.List(ctx, "api")
+	require.NoError(t, err)
+	assert.Len(t, ids, 2)
+
+	_, err = v.Rollback(ctx, "api", "19700101T000000.000000000Z")
+	assert.EqualError(t, err, "failed to load snapshot 19700101T000000.000000000Z: snapshot not found")
+}
+
+func TestVersions_UploadNewScript(t *testing.T) {
+	mux := http.NewServeMux()
+	worker := &fakeWorker{}
+	worker.register(t, mux, "fresh")
+
+	v := &Versions{API: newTestAPI(t, mux), Store: DirStore{Dir: t.TempDir()}}
+	previous, err := v.Upload(context.Background(), "fresh", &cloudflare.WorkerScriptParams{Script: "// new"})
+	require.NoError(t, err)
+	assert.Nil(t, previous)
+	assert.Equal(t, "// new", worker.script)
+}
+
+func TestKVStore(t *testing.T) {
+	values := make(map[string][]byte)
+	mux := http.NewServeMux()
+	prefix := "/accounts/" + testAccountID + "/storage/kv/namespaces/ns/"
+	mux.HandleFunc(prefix+"values/", func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, prefix+"values/")
+		switch r.Method {
+		case http.MethodPut:
+			values[key], _ = ioutil.ReadAll(r.Body)
+			writeResult(w, `null`)
+		case http.MethodGet:
+			w.Write(values[key]) //nolint
+		}
+	})
+	mux.HandleFunc(prefix+"keys", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "versions/api/", r.URL.Query().Get("prefix"))
+		w.Header().Set("content-type", "application/json")
+		if r.URL.Query().Get("cursor") == "" {
+			fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [{"name": "versions/api/2"}], "result_info": {"count": 1, "cursor": "next"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [{"name": "versions/api/1"}], "result_info": {"count": 1, "cursor": ""}}`)
+	})
+
+	store := KVStore{API: newTestAPI(t, mux), NamespaceID: "ns", Prefix: "versions/"}
+	ctx := context.Background()
+	snap := Snapshot{ID: "1", ScriptName: "api", Script: "// v1", Bindings: []Binding{{Name: "X", Type: cloudflare.WorkerJSONBindingType, JSON: []interface{}{"a"}}}}
+	require.NoError(t, store.Save(ctx, snap))
+	assert.Contains(t, values, "versions/api/1")
+
+	loaded, err := store.Load(ctx, "api", "1")
+	require.NoError(t, err)
+	assert.Equal(t, snap, loaded)
+
+	ids, err := store.List(ctx, "api")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, ids)
+}
+
+func TestParseModules(t *testing.T) {
+	body := "--abc123\r\n" +
+		"Content-Disposition: form-data; name=\"index.mjs\"; filename=\"index.mjs\"\r\n" +
+		"Content-Type: application/javascript+module\r\n\r\n" +
+		"import { x } from './lib.mjs'\r\n" +
+		"--abc123\r\n" +
+		"Content-Disposition: form-data; name=\"lib.mjs\"; filename=\"lib.mjs\"\r\n" +
+		"Content-Type: application/javascript+module\r\n\r\n" +
+		"export const x = 1\r\n" +
+		"--abc123--\r\n"
+
+	main, modules := parseModules(body)
+	assert.Equal(t, "index.mjs", main)
+	assert.Equal(t, []Module{
+		{Name: "index.mjs", Type: "application/javascript+module", Content: []byte("import { x } from './lib.mjs'")},
+		{Name: "lib.mjs", Type: "application/javascript+module", Content: []byte("export const x = 1")},
+	}, modules)
+
+	main, modules = parseModules("-- a comment that is not a boundary\naddEventListener()")
+	assert.Empty(t, main)
+	assert.Nil(t, modules)
+}
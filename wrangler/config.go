@@ -0,0 +1,429 @@
+// Package wrangler deploys Workers described by wrangler.toml configuration
+// files through the Cloudflare API.
+//
+// Only the deployment related parts of the configuration are understood:
+// the script, compatibility settings, workers.dev, routes, cron triggers,
+// vars, KV, Durable Object, R2 and service bindings and Durable Object
+// migrations, along with their per-environment overrides. No bundling is
+// done; the file named by main is uploaded as is.
+package wrangler
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Config is a Worker described by a wrangler.toml file.
+type Config struct {
+	Name               string
+	Main               string
+	AccountID          string
+	CompatibilityDate  string
+	CompatibilityFlags []string
+
+	// WorkersDev is nil when workers_dev is not set.
+	WorkersDev *bool
+
+	// ZoneID is the zone routes are created in when they do not name one.
+	ZoneID string
+	Routes []Route
+
+	// Triggers is nil when the configuration has no [triggers] table, in
+	// which case existing cron triggers are left alone.
+	Triggers *Triggers
+
+	// Vars holds plain text variables as strings and JSON variables as any
+	// other TOML value.
+	Vars           map[string]interface{}
+	KVNamespaces   []KVNamespace
+	DurableObjects []DurableObject
+	R2Buckets      []R2Bucket
+	Services       []Service
+
+	// Migrations are the Durable Object migrations of the Worker, oldest
+	// first.
+	Migrations []Migration
+
+	// Dir is the directory Main is relative to. Load sets it to the
+	// directory of the configuration file.
+	Dir string
+
+	env map[string]map[string]interface{}
+}
+
+// Route is a route pattern the Worker is deployed to. ZoneID and ZoneName
+// are optional; without them the zone is inferred from the pattern.
+type Route struct {
+	Pattern  string
+	ZoneID   string
+	ZoneName string
+}
+
+// Triggers holds the Worker's scheduled triggers.
+type Triggers struct {
+	Crons []string
+}
+
+// KVNamespace binds a Workers KV namespace.
+type KVNamespace struct {
+	Binding string
+	ID      string
+}
+
+// DurableObject binds a Durable Object namespace.
+type DurableObject struct {
+	Name       string
+	ClassName  string
+	ScriptName string
+}
+
+// Migration creates, renames and deletes Durable Object classes. Tag names
+// the migration; the tag of the last one applied is kept with the script.
+type Migration struct {
+	Tag            string
+	NewClasses     []string
+	RenamedClasses []RenamedClass
+	DeletedClasses []string
+}
+
+// RenamedClass renames a Durable Object class.
+type RenamedClass struct {
+	From string
+	To   string
+}
+
+// R2Bucket binds an R2 bucket.
+type R2Bucket struct {
+	Binding    string
+	BucketName string
+}
+
+// Service binds another Worker.
+type Service struct {
+	Binding     string
+	Service     string
+	Environment string
+}
+
+// Load reads and parses a wrangler.toml file.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+	c.Dir = filepath.Dir(path)
+	return c, nil
+}
+
+// Parse parses the contents of a wrangler.toml file.
+func Parse(data []byte) (*Config, error) {
+	doc, err := parseTOML(string(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid TOML")
+	}
+
+	c := &Config{}
+	if err := c.decode(doc, true); err != nil {
+		return nil, err
+	}
+
+	if v, ok := doc["env"]; ok {
+		envs, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("env: expected a table")
+		}
+		c.env = make(map[string]map[string]interface{}, len(envs))
+		for name, v := range envs {
+			table, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.Errorf("env.%s: expected a table", name)
+			}
+			c.env[name] = table
+		}
+	}
+	return c, nil
+}
+
+// Environments returns the names of the environments defined in the
+// configuration.
+func (c *Config) Environments() []string {
+	names := make([]string, 0, len(c.env))
+	for name := range c.env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Environment returns the configuration of a named environment. As with
+// wrangler, the Worker is named "<name>-<env>" unless the environment sets
+// its own name. Settings such as routes, triggers and compatibility flags
+// are inherited from the top level, while vars and bindings are not.
+func (c *Config) Environment(name string) (*Config, error) {
+	if name == "" {
+		return c, nil
+	}
+	table, ok := c.env[name]
+	if !ok {
+		return nil, errors.Errorf("environment %q is not defined", name)
+	}
+
+	env := &Config{
+		Name:               c.Name + "-" + name,
+		Main:               c.Main,
+		AccountID:          c.AccountID,
+		CompatibilityDate:  c.CompatibilityDate,
+		CompatibilityFlags: c.CompatibilityFlags,
+		WorkersDev:         c.WorkersDev,
+		ZoneID:             c.ZoneID,
+		Routes:             c.Routes,
+		Triggers:           c.Triggers,
+		Migrations:         c.Migrations,
+		Dir:                c.Dir,
+	}
+	if err := env.decode(table, false); err != nil {
+		return nil, errors.Wrapf(err, "env.%s", name)
+	}
+	return env, nil
+}
+
+// decode sets the fields present in table. Keys that do not affect
+// deployments are ignored.
+func (c *Config) decode(table map[string]interface{}, topLevel bool) error {
+	d := decoder{values: table, err: new(error)}
+
+	d.string("name", &c.Name)
+	d.string("main", &c.Main)
+	d.string("account_id", &c.AccountID)
+	d.string("compatibility_date", &c.CompatibilityDate)
+	d.strings("compatibility_flags", &c.CompatibilityFlags)
+	d.string("zone_id", &c.ZoneID)
+
+	if v, ok := table["workers_dev"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			d.fail("workers_dev", "a boolean")
+		}
+		c.WorkersDev = &b
+	}
+
+	_, hasRoute := table["route"]
+	_, hasRoutes := table["routes"]
+	switch {
+	case hasRoute && hasRoutes:
+		return errors.New("only one of route and routes may be set")
+	case hasRoute:
+		var pattern string
+		d.string("route", &pattern)
+		c.Routes = []Route{{Pattern: pattern}}
+	case hasRoutes:
+		c.Routes = nil
+		d.each("routes", func(d decoder, v interface{}) {
+			if pattern, ok := v.(string); ok {
+				c.Routes = append(c.Routes, Route{Pattern: pattern})
+				return
+			}
+			var r Route
+			d.table("", v, func(d decoder) {
+				d.string("pattern", &r.Pattern)
+				d.string("zone_id", &r.ZoneID)
+				d.string("zone_name", &r.ZoneName)
+			})
+			c.Routes = append(c.Routes, r)
+		})
+	}
+
+	if v, ok := table["triggers"]; ok {
+		c.Triggers = &Triggers{}
+		d.table("triggers", v, func(d decoder) {
+			d.strings("crons", &c.Triggers.Crons)
+		})
+		if c.Triggers.Crons == nil {
+			c.Triggers.Crons = []string{}
+		}
+	}
+
+	if v, ok := table["vars"]; ok {
+		vars, ok := v.(map[string]interface{})
+		if !ok {
+			d.fail("vars", "a table")
+		}
+		c.Vars = vars
+	}
+
+	d.each("kv_namespaces", func(d decoder, v interface{}) {
+		var kv KVNamespace
+		d.table("", v, func(d decoder) {
+			d.string("binding", &kv.Binding)
+			d.string("id", &kv.ID)
+		})
+		c.KVNamespaces = append(c.KVNamespaces, kv)
+	})
+
+	if v, ok := table["durable_objects"]; ok {
+		d.table("durable_objects", v, func(d decoder) {
+			d.each("bindings", func(d decoder, v interface{}) {
+				var do DurableObject
+				d.table("", v, func(d decoder) {
+					d.string("name", &do.Name)
+					d.string("class_name", &do.ClassName)
+					d.string("script_name", &do.ScriptName)
+				})
+				c.DurableObjects = append(c.DurableObjects, do)
+			})
+		})
+	}
+
+	d.each("r2_buckets", func(d decoder, v interface{}) {
+		var r2 R2Bucket
+		d.table("", v, func(d decoder) {
+			d.string("binding", &r2.Binding)
+			d.string("bucket_name", &r2.BucketName)
+		})
+		c.R2Buckets = append(c.R2Buckets, r2)
+	})
+
+	// As with wrangler, migrations are shared by all environments.
+	if topLevel {
+		d.each("migrations", func(d decoder, v interface{}) {
+			var m Migration
+			d.table("", v, func(d decoder) {
+				d.string("tag", &m.Tag)
+				d.strings("new_classes", &m.NewClasses)
+				d.each("renamed_classes", func(d decoder, v interface{}) {
+					var r RenamedClass
+					d.table("", v, func(d decoder) {
+						d.string("from", &r.From)
+						d.string("to", &r.To)
+					})
+					m.RenamedClasses = append(m.RenamedClasses, r)
+				})
+				d.strings("deleted_classes", &m.DeletedClasses)
+			})
+			if m.Tag == "" && *d.err == nil {
+				*d.err = errors.Errorf("%stag is required", d.prefix)
+			}
+			c.Migrations = append(c.Migrations, m)
+		})
+	}
+
+	d.each("services", func(d decoder, v interface{}) {
+		var s Service
+		d.table("", v, func(d decoder) {
+			d.string("binding", &s.Binding)
+			d.string("service", &s.Service)
+			d.string("environment", &s.Environment)
+		})
+		c.Services = append(c.Services, s)
+	})
+
+	if *d.err != nil {
+		return *d.err
+	}
+	if topLevel && c.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// decoder copies typed values out of a TOML table, remembering the first
+// error so callers can check once at the end.
+type decoder struct {
+	values map[string]interface{}
+	prefix string
+	err    *error
+}
+
+func (d decoder) fail(key, want string) {
+	if *d.err == nil {
+		*d.err = errors.Errorf("%s: expected %s", strings.TrimSuffix(d.prefix+key, "."), want)
+	}
+}
+
+func (d decoder) string(key string, dst *string) {
+	v, ok := d.values[key]
+	if !ok {
+		return
+	}
+	s, ok := v.(string)
+	if !ok {
+		d.fail(key, "a string")
+		return
+	}
+	*dst = s
+}
+
+func (d decoder) strings(key string, dst *[]string) {
+	v, ok := d.values[key]
+	if !ok {
+		return
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		d.fail(key, "an array of strings")
+		return
+	}
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			d.fail(key, "an array of strings")
+			return
+		}
+		out = append(out, s)
+	}
+	*dst = out
+}
+
+// each calls fn with every element of the array or array of tables at key.
+func (d decoder) each(key string, fn func(decoder, interface{})) {
+	v, ok := d.values[key]
+	if !ok {
+		return
+	}
+	var items []interface{}
+	switch v := v.(type) {
+	case []interface{}:
+		items = v
+	case []map[string]interface{}:
+		for _, t := range v {
+			items = append(items, t)
+		}
+	default:
+		d.fail(key, "an array")
+		return
+	}
+	for i, item := range items {
+		fn(d.sub(key+"["+strconv.Itoa(i)+"]"), item)
+	}
+}
+
+// table calls fn with a decoder for v, the value of key, which must be a
+// table.
+func (d decoder) table(key string, v interface{}, fn func(decoder)) {
+	t, ok := v.(map[string]interface{})
+	if !ok {
+		d.fail(key, "a table")
+		return
+	}
+	sub := d.sub(key)
+	sub.values = t
+	fn(sub)
+}
+
+func (d decoder) sub(key string) decoder {
+	sub := d
+	if key != "" {
+		sub.prefix = d.prefix + key + "."
+	}
+	return sub
+}
@@ -0,0 +1,144 @@
+package wrangler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+name = "api"
+main = "src/index.mjs"
+account_id = "01a7362d577a6c3019a474fd6f485823"
+compatibility_date = "2022-03-21"
+compatibility_flags = ["formdata_parser_supports_files"]
+workers_dev = false
+routes = [
+  "api.example.com/*",
+  { pattern = "example.com/api/*", zone_name = "example.com" },
+]
+
+kv_namespaces = [
+  { binding = "CACHE", id = "0f2ac74b498b48028cb68387c421e279" },
+]
+
+[vars]
+ENVIRONMENT = "production"
+LIMITS = { requests = 100 }
+
+[triggers]
+crons = ["*/5 * * * *"]
+
+[[durable_objects.bindings]]
+name = "COUNTER"
+class_name = "Counter"
+
+[[migrations]]
+tag = "v1"
+new_classes = ["Counter"]
+
+[[migrations]]
+tag = "v2"
+renamed_classes = [{ from = "Counter", to = "Tally" }]
+deleted_classes = ["Legacy"]
+
+[[r2_buckets]]
+binding = "ASSETS"
+bucket_name = "assets"
+
+[[services]]
+binding = "AUTH"
+service = "auth"
+environment = "production"
+
+[env.staging]
+route = "staging.example.com/*"
+zone_id = "023e105f4ecef8ad9ca31a8372d0c353"
+
+[env.staging.vars]
+ENVIRONMENT = "staging"
+
+[env.preview]
+name = "api-preview"
+workers_dev = true
+`
+
+func TestParse(t *testing.T) {
+	c, err := Parse([]byte(testConfig))
+	require.NoError(t, err)
+
+	f := false
+	assert.Equal(t, "api", c.Name)
+	assert.Equal(t, "src/index.mjs", c.Main)
+	assert.Equal(t, "01a7362d577a6c3019a474fd6f485823", c.AccountID)
+	assert.Equal(t, "2022-03-21", c.CompatibilityDate)
+	assert.Equal(t, []string{"formdata_parser_supports_files"}, c.CompatibilityFlags)
+	assert.Equal(t, &f, c.WorkersDev)
+	assert.Equal(t, []Route{
+		{Pattern: "api.example.com/*"},
+		{Pattern: "example.com/api/*", ZoneName: "example.com"},
+	}, c.Routes)
+	assert.Equal(t, &Triggers{Crons: []string{"*/5 * * * *"}}, c.Triggers)
+	assert.Equal(t, map[string]interface{}{
+		"ENVIRONMENT": "production",
+		"LIMITS":      map[string]interface{}{"requests": int64(100)},
+	}, c.Vars)
+	assert.Equal(t, []KVNamespace{{Binding: "CACHE", ID: "0f2ac74b498b48028cb68387c421e279"}}, c.KVNamespaces)
+	assert.Equal(t, []DurableObject{{Name: "COUNTER", ClassName: "Counter"}}, c.DurableObjects)
+	assert.Equal(t, []R2Bucket{{Binding: "ASSETS", BucketName: "assets"}}, c.R2Buckets)
+	assert.Equal(t, []Migration{
+		{Tag: "v1", NewClasses: []string{"Counter"}},
+		{Tag: "v2", RenamedClasses: []RenamedClass{{From: "Counter", To: "Tally"}}, DeletedClasses: []string{"Legacy"}},
+	}, c.Migrations)
+	assert.Equal(t, []Service{{Binding: "AUTH", Service: "auth", Environment: "production"}}, c.Services)
+	assert.Equal(t, []string{"preview", "staging"}, c.Environments())
+}
+
+func TestConfig_Environment(t *testing.T) {
+	c, err := Parse([]byte(testConfig))
+	require.NoError(t, err)
+
+	staging, err := c.Environment("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "api-staging", staging.Name)
+	assert.Equal(t, c.Main, staging.Main)
+	assert.Equal(t, c.CompatibilityDate, staging.CompatibilityDate)
+	assert.Equal(t, c.Triggers, staging.Triggers)
+	assert.Equal(t, "023e105f4ecef8ad9ca31a8372d0c353", staging.ZoneID)
+	assert.Equal(t, []Route{{Pattern: "staging.example.com/*"}}, staging.Routes)
+	assert.Equal(t, map[string]interface{}{"ENVIRONMENT": "staging"}, staging.Vars)
+	assert.Nil(t, staging.KVNamespaces, "bindings are not inherited")
+	assert.Nil(t, staging.DurableObjects)
+	assert.Equal(t, c.Migrations, staging.Migrations, "migrations are shared")
+
+	preview, err := c.Environment("preview")
+	require.NoError(t, err)
+	assert.Equal(t, "api-preview", preview.Name)
+	assert.True(t, *preview.WorkersDev)
+	assert.Equal(t, c.Routes, preview.Routes)
+
+	_, err = c.Environment("production")
+	assert.EqualError(t, err, `environment "production" is not defined`)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		`main = "index.js"`:                                   "name is required",
+		"name = \"a\"\nroute = \"a/*\"\nroutes = []":          "only one of route and routes may be set",
+		"name = \"a\"\ncompatibility_flags = [1]":             "compatibility_flags: expected an array of strings",
+		"name = \"a\"\nkv_namespaces = [{ binding = 1 }]":     "kv_namespaces[0].binding: expected a string",
+		"name = \"a\"\n[triggers]\ncrons = \"* * * * *\"":     "triggers.crons: expected an array of strings",
+		"name = \"a\"\n[[migrations]]\nnew_classes = [\"A\"]": "migrations[0].tag is required",
+	}
+	for input, want := range tests {
+		_, err := Parse([]byte(input))
+		assert.EqualError(t, err, want, input)
+	}
+
+	// Environments are only decoded when they are selected.
+	c, err := Parse([]byte("name = \"a\"\n[env.dev]\nworkers_dev = \"yes\""))
+	require.NoError(t, err)
+	_, err = c.Environment("dev")
+	assert.EqualError(t, err, "env.dev: workers_dev: expected a boolean")
+}
@@ -0,0 +1,543 @@
+package wrangler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
+)
+
+// Deployment is everything that is uploaded or configured for a Worker.
+type Deployment struct {
+	ScriptName string
+	Script     cloudflare.WorkerScriptParams
+	Routes     []Route
+
+	// Crons replaces the Worker's cron triggers. A nil slice leaves them
+	// unchanged while an empty one removes them all.
+	Crons []string
+
+	// WorkersDev enables or disables the Worker on the account's
+	// workers.dev subdomain. Nil leaves the setting unchanged.
+	WorkersDev *bool
+
+	// Migrations are all of the Worker's Durable Object migrations, oldest
+	// first. Only those after the last one applied are uploaded.
+	Migrations []Migration
+}
+
+var exportDefault = regexp.MustCompile(`(?m)^\s*export\s+default\b`)
+
+// Deployment reads the Worker's script and converts the configuration into
+// a Deployment. Scripts with a default export are uploaded as ES modules.
+func (c *Config) Deployment() (*Deployment, error) {
+	if c.Main == "" {
+		return nil, errors.New("main is required")
+	}
+	script, err := ioutil.ReadFile(filepath.Join(c.Dir, c.Main))
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Deployment{
+		ScriptName: c.Name,
+		Script: cloudflare.WorkerScriptParams{
+			Script:             string(script),
+			CompatibilityDate:  c.CompatibilityDate,
+			CompatibilityFlags: c.CompatibilityFlags,
+			Bindings:           make(map[string]cloudflare.WorkerBinding),
+		},
+	}
+	if exportDefault.Match(script) {
+		d.Script.MainModule = filepath.Base(c.Main)
+	}
+
+	for _, r := range c.Routes {
+		if r.ZoneID == "" && r.ZoneName == "" {
+			r.ZoneID = c.ZoneID
+		}
+		d.Routes = append(d.Routes, r)
+	}
+	if c.Triggers != nil {
+		d.Crons = c.Triggers.Crons
+	}
+	d.WorkersDev = c.WorkersDev
+	d.Migrations = c.Migrations
+
+	bind := func(name string, b cloudflare.WorkerBinding) error {
+		if name == "" {
+			return errors.Errorf("%s binding is missing a name", b.Type())
+		}
+		if _, ok := d.Script.Bindings[name]; ok {
+			return errors.Errorf("binding %q is defined more than once", name)
+		}
+		d.Script.Bindings[name] = b
+		return nil
+	}
+	for name, v := range c.Vars {
+		var b cloudflare.WorkerBinding = cloudflare.WorkerJSONBinding{Value: v}
+		if s, ok := v.(string); ok {
+			b = cloudflare.WorkerPlainTextBinding{Text: s}
+		}
+		if err := bind(name, b); err != nil {
+			return nil, err
+		}
+	}
+	for _, kv := range c.KVNamespaces {
+		if err := bind(kv.Binding, cloudflare.WorkerKvNamespaceBinding{NamespaceID: kv.ID}); err != nil {
+			return nil, err
+		}
+	}
+	for _, do := range c.DurableObjects {
+		if err := bind(do.Name, cloudflare.WorkerDurableObjectBinding{ClassName: do.ClassName, ScriptName: do.ScriptName}); err != nil {
+			return nil, err
+		}
+	}
+	for _, r2 := range c.R2Buckets {
+		if err := bind(r2.Binding, cloudflare.WorkerR2BucketBinding{BucketName: r2.BucketName}); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range c.Services {
+		if err := bind(s.Binding, cloudflare.WorkerServiceBinding{Service: s.Service, Environment: s.Environment}); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Action is what a deployment does to a resource.
+type Action string
+
+// Actions in a Plan.
+const (
+	Create Action = "create"
+	Update Action = "update"
+	Delete Action = "delete"
+)
+
+// Resource kinds in a Plan.
+const (
+	ResourceScript     = "script"
+	ResourceBinding    = "binding"
+	ResourceRoute      = "route"
+	ResourceCron       = "cron"
+	ResourceWorkersDev = "workers_dev"
+	ResourceMigration  = "migration"
+)
+
+// Change is a single difference between the deployed Worker and the
+// Deployment.
+type Change struct {
+	Action   Action
+	Resource string
+	Name     string
+	// Before and After describe the resource, when that is meaningful.
+	Before string
+	After  string
+}
+
+func (c Change) String() string {
+	sign := map[Action]string{Create: "+", Update: "~", Delete: "-"}[c.Action]
+	s := fmt.Sprintf("%s %s %s", sign, c.Resource, c.Name)
+	switch c.Action {
+	case Create:
+		if c.After != "" {
+			s += " (" + c.After + ")"
+		}
+	case Update:
+		s += fmt.Sprintf(" (%s -> %s)", c.Before, c.After)
+	case Delete:
+		if c.Before != "" {
+			s += " (" + c.Before + ")"
+		}
+	}
+	return s
+}
+
+// Plan is the set of changes needed to deploy a Deployment. The script
+// itself is always uploaded, so Changes always includes it.
+type Plan struct {
+	Deployment *Deployment
+	Changes    []Change
+
+	routes     []routeChange
+	secrets    []string
+	workersDev bool
+	migrations *cloudflare.WorkerMigrations
+}
+
+type routeChange struct {
+	action Action
+	zoneID string
+	route  cloudflare.WorkerRoute
+}
+
+// String lists the changes, one per line.
+func (p *Plan) String() string {
+	var b strings.Builder
+	for _, c := range p.Changes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// NewPlan compares a Deployment with what is currently deployed. api must be
+// scoped to the Worker's account, e.g. with cloudflare.UsingAccount.
+//
+// Routes of the Worker that are not part of the Deployment are deleted, but
+// only in the zones the Deployment routes to. Secrets are kept.
+func NewPlan(ctx context.Context, api *cloudflare.API, d *Deployment) (*Plan, error) {
+	if api.AccountID == "" {
+		return nil, errors.New("account ID required")
+	}
+	p := &Plan{Deployment: d}
+
+	scripts, err := api.ListWorkerScripts(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list Worker scripts")
+	}
+	exists, migrationTag := false, ""
+	for _, s := range scripts.WorkerList {
+		if s.ID == d.ScriptName {
+			exists, migrationTag = true, s.MigrationTag
+			break
+		}
+	}
+	if exists {
+		p.Changes = append(p.Changes, Change{Action: Update, Resource: ResourceScript, Name: d.ScriptName, Before: "deployed", After: "uploaded"})
+	} else {
+		p.Changes = append(p.Changes, Change{Action: Create, Resource: ResourceScript, Name: d.ScriptName})
+	}
+
+	if err := p.planMigrations(migrationTag); err != nil {
+		return nil, err
+	}
+	if err := p.planBindings(ctx, api, exists); err != nil {
+		return nil, err
+	}
+	if err := p.planRoutes(ctx, api); err != nil {
+		return nil, err
+	}
+	if err := p.planCrons(ctx, api, exists); err != nil {
+		return nil, err
+	}
+	if err := p.planWorkersDev(ctx, api, exists); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// planMigrations selects the migrations after the one tagged current, the
+// tag of the last migration applied to the script.
+func (p *Plan) planMigrations(current string) error {
+	pending := p.Deployment.Migrations
+	if current != "" {
+		found := false
+		for i, m := range pending {
+			if m.Tag == current {
+				pending, found = pending[i+1:], true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf("the script's migration tag %q is not one of the configured migrations", current)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	p.migrations = &cloudflare.WorkerMigrations{OldTag: current, NewTag: pending[len(pending)-1].Tag}
+	for _, m := range pending {
+		step := cloudflare.WorkerMigrationStep{NewClasses: m.NewClasses, DeletedClasses: m.DeletedClasses}
+		for _, r := range m.RenamedClasses {
+			step.RenamedClasses = append(step.RenamedClasses, cloudflare.WorkerRenamedClass{From: r.From, To: r.To})
+		}
+		p.migrations.Steps = append(p.migrations.Steps, step)
+		p.Changes = append(p.Changes, Change{Action: Create, Resource: ResourceMigration, Name: m.Tag})
+	}
+	return nil
+}
+
+func (p *Plan) planWorkersDev(ctx context.Context, api *cloudflare.API, exists bool) error {
+	want := p.Deployment.WorkersDev
+	if want == nil {
+		return nil
+	}
+
+	enabled := false
+	if exists {
+		res, err := api.WorkerScriptSubdomain(ctx, p.Deployment.ScriptName, "")
+		if err != nil {
+			return errors.Wrap(err, "failed to get workers.dev setting")
+		}
+		enabled = res.Enabled
+	}
+	if enabled != *want {
+		p.workersDev = true
+		p.Changes = append(p.Changes, Change{Action: Update, Resource: ResourceWorkersDev, Name: p.Deployment.ScriptName, Before: enabledString(enabled), After: enabledString(*want)})
+	}
+	return nil
+}
+
+func enabledString(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+func (p *Plan) planBindings(ctx context.Context, api *cloudflare.API, exists bool) error {
+	current := make(map[string]cloudflare.WorkerBinding)
+	if exists {
+		res, err := api.ListWorkerBindings(ctx, &cloudflare.WorkerRequestParams{ScriptName: p.Deployment.ScriptName})
+		if err != nil {
+			return errors.Wrap(err, "failed to list Worker bindings")
+		}
+		for _, b := range res.BindingList {
+			current[b.Name] = b.Binding
+		}
+	}
+
+	desired := p.Deployment.Script.Bindings
+	for _, name := range sortedBindingNames(desired) {
+		after := describeBinding(desired[name])
+		before, ok := current[name]
+		switch {
+		case !ok:
+			p.Changes = append(p.Changes, Change{Action: Create, Resource: ResourceBinding, Name: name, After: after})
+		case describeBinding(before) != after:
+			p.Changes = append(p.Changes, Change{Action: Update, Resource: ResourceBinding, Name: name, Before: describeBinding(before), After: after})
+		}
+	}
+	for _, name := range sortedBindingNames(current) {
+		if _, ok := desired[name]; ok {
+			continue
+		}
+		if current[name].Type() == cloudflare.WorkerSecretTextBindingType {
+			p.secrets = append(p.secrets, name)
+			continue
+		}
+		p.Changes = append(p.Changes, Change{Action: Delete, Resource: ResourceBinding, Name: name, Before: describeBinding(current[name])})
+	}
+	return nil
+}
+
+// describeBinding renders a binding's type and settings so that bindings
+// read back from the API compare equal to the ones that were uploaded.
+func describeBinding(b cloudflare.WorkerBinding) string {
+	switch b := b.(type) {
+	case cloudflare.WorkerSecretTextBinding:
+		return string(b.Type())
+	case cloudflare.WorkerWebAssemblyBinding:
+		return string(b.Type())
+	}
+	settings, err := json.Marshal(b)
+	if err != nil {
+		return string(b.Type())
+	}
+	return fmt.Sprintf("%s %s", b.Type(), settings)
+}
+
+func sortedBindingNames(bindings map[string]cloudflare.WorkerBinding) []string {
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p *Plan) planRoutes(ctx context.Context, api *cloudflare.API) error {
+	script := p.Deployment.ScriptName
+	byZone := make(map[string][]cloudflare.WorkerRoute)
+	var zones []string
+	desired := make(map[string]bool)
+
+	for _, r := range p.Deployment.Routes {
+		zoneID, err := resolveZone(api, r)
+		if err != nil {
+			return errors.Wrapf(err, "route %s", r.Pattern)
+		}
+		existing, ok := byZone[zoneID]
+		if !ok {
+			res, err := api.ListWorkerRoutes(ctx, zoneID)
+			if err != nil {
+				return errors.Wrapf(err, "failed to list routes of zone %s", zoneID)
+			}
+			existing = res.Routes
+			byZone[zoneID] = existing
+			zones = append(zones, zoneID)
+		}
+		desired[zoneID+" "+r.Pattern] = true
+
+		change := routeChange{action: Create, zoneID: zoneID, route: cloudflare.WorkerRoute{Pattern: r.Pattern, Script: script}}
+		for _, e := range existing {
+			if e.Pattern != r.Pattern {
+				continue
+			}
+			change.action = Update
+			change.route.ID = e.ID
+			if e.Script != script {
+				p.Changes = append(p.Changes, Change{Action: Update, Resource: ResourceRoute, Name: r.Pattern, Before: routeScript(e.Script), After: script})
+				p.routes = append(p.routes, change)
+			}
+			break
+		}
+		if change.action == Create {
+			p.Changes = append(p.Changes, Change{Action: Create, Resource: ResourceRoute, Name: r.Pattern, After: script})
+			p.routes = append(p.routes, change)
+		}
+	}
+
+	for _, zoneID := range zones {
+		for _, e := range byZone[zoneID] {
+			if e.Script == script && !desired[zoneID+" "+e.Pattern] {
+				p.Changes = append(p.Changes, Change{Action: Delete, Resource: ResourceRoute, Name: e.Pattern, Before: script})
+				p.routes = append(p.routes, routeChange{action: Delete, zoneID: zoneID, route: e})
+			}
+		}
+	}
+	return nil
+}
+
+func routeScript(script string) string {
+	if script == "" {
+		return "no script"
+	}
+	return script
+}
+
+// resolveZone finds the zone a route belongs to. Without an explicit zone
+// it tries the host of the pattern and each of its parent domains.
+func resolveZone(api *cloudflare.API, r Route) (string, error) {
+	if r.ZoneID != "" {
+		return r.ZoneID, nil
+	}
+	if r.ZoneName != "" {
+		return api.ZoneIDByName(r.ZoneName)
+	}
+
+	host := r.Pattern
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexByte(host, '/'); i >= 0 {
+		host = host[:i]
+	}
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "*"), ".")
+
+	labels := strings.Split(host, ".")
+	for i := 0; i < len(labels)-1; i++ {
+		if id, err := api.ZoneIDByName(strings.Join(labels[i:], ".")); err == nil {
+			return id, nil
+		}
+	}
+	return "", errors.New("could not find a zone for the route; set zone_id or zone_name")
+}
+
+func (p *Plan) planCrons(ctx context.Context, api *cloudflare.API, exists bool) error {
+	desired := p.Deployment.Crons
+	if desired == nil {
+		return nil
+	}
+
+	var current []string
+	if exists {
+		triggers, err := api.ListWorkerCronTriggers(ctx, p.Deployment.ScriptName)
+		if err != nil {
+			return errors.Wrap(err, "failed to list cron triggers")
+		}
+		for _, t := range triggers {
+			current = append(current, t.Cron)
+		}
+	}
+
+	want := make(map[string]bool, len(desired))
+	for _, cron := range desired {
+		want[cron] = true
+	}
+	have := make(map[string]bool, len(current))
+	for _, cron := range current {
+		have[cron] = true
+		if !want[cron] {
+			p.Changes = append(p.Changes, Change{Action: Delete, Resource: ResourceCron, Name: cron})
+		}
+	}
+	for _, cron := range desired {
+		if !have[cron] {
+			p.Changes = append(p.Changes, Change{Action: Create, Resource: ResourceCron, Name: cron})
+		}
+	}
+	return nil
+}
+
+// Apply uploads the script, bindings and pending migrations, then updates
+// routes, cron triggers and the workers.dev setting. It stops at the first error.
+func (p *Plan) Apply(ctx context.Context, api *cloudflare.API) error {
+	d := p.Deployment
+
+	params := d.Script
+	params.Bindings = make(map[string]cloudflare.WorkerBinding, len(d.Script.Bindings)+len(p.secrets))
+	for name, b := range d.Script.Bindings {
+		params.Bindings[name] = b
+	}
+	for _, name := range p.secrets {
+		params.Bindings[name] = cloudflare.WorkerInheritBinding{}
+	}
+	params.Migrations = p.migrations
+	if _, err := api.UploadWorkerWithBindings(ctx, &cloudflare.WorkerRequestParams{ScriptName: d.ScriptName}, &params); err != nil {
+		return errors.Wrap(err, "failed to upload Worker")
+	}
+
+	for _, r := range p.routes {
+		var err error
+		switch r.action {
+		case Create:
+			_, err = api.CreateWorkerRoute(ctx, r.zoneID, r.route)
+		case Update:
+			_, err = api.UpdateWorkerRoute(ctx, r.zoneID, r.route.ID, r.route)
+		case Delete:
+			_, err = api.DeleteWorkerRoute(ctx, r.zoneID, r.route.ID)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to %s route %s", r.action, r.route.Pattern)
+		}
+	}
+
+	if d.Crons != nil {
+		triggers := make([]cloudflare.WorkerCronTrigger, 0, len(d.Crons))
+		for _, cron := range d.Crons {
+			triggers = append(triggers, cloudflare.WorkerCronTrigger{Cron: cron})
+		}
+		if _, err := api.UpdateWorkerCronTriggers(ctx, d.ScriptName, triggers); err != nil {
+			return errors.Wrap(err, "failed to update cron triggers")
+		}
+	}
+
+	if p.workersDev {
+		if _, err := api.SetWorkerScriptSubdomain(ctx, d.ScriptName, "", *d.WorkersDev); err != nil {
+			return errors.Wrap(err, "failed to update workers.dev setting")
+		}
+	}
+	return nil
+}
+
+// Deploy plans and applies a Deployment, returning the plan that was
+// applied.
+func Deploy(ctx context.Context, api *cloudflare.API, d *Deployment) (*Plan, error) {
+	p, err := NewPlan(ctx, api, d)
+	if err != nil {
+		return nil, err
+	}
+	return p, p.Apply(ctx, api)
+}
@@ -0,0 +1,243 @@
+package wrangler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAccountID = "01a7362d577a6c3019a474fd6f485823"
+	testZoneID    = "023e105f4ecef8ad9ca31a8372d0c353"
+)
+
+func newTestAPI(t *testing.T, mux *http.ServeMux) *cloudflare.API {
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	api, err := cloudflare.New("deadbeef", "cloudflare@example.org",
+		cloudflare.BaseURL(server.URL),
+		cloudflare.UsingAccount(testAccountID),
+		cloudflare.UsingRateLimit(100000),
+		cloudflare.UsingRetryPolicy(0, 0, 0))
+	require.NoError(t, err)
+	return api
+}
+
+func writeResult(w http.ResponseWriter, result string) {
+	w.Header().Set("content-type", "application/json")
+	fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": %s}`, result)
+}
+
+func loadTestDeployment(t *testing.T) *Deployment {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "src"), 0o755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "src", "index.mjs"), []byte("export default {\n  fetch() { return new Response('ok') }\n}\n"), 0o644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "wrangler.toml"), []byte(testConfig), 0o644))
+
+	c, err := Load(filepath.Join(dir, "wrangler.toml"))
+	require.NoError(t, err)
+	d, err := c.Deployment()
+	require.NoError(t, err)
+	return d
+}
+
+func TestConfig_Deployment(t *testing.T) {
+	d := loadTestDeployment(t)
+
+	assert.Equal(t, "api", d.ScriptName)
+	assert.Equal(t, "index.mjs", d.Script.MainModule)
+	assert.Contains(t, d.Script.Script, "export default")
+	assert.Equal(t, "2022-03-21", d.Script.CompatibilityDate)
+	assert.Equal(t, []string{"*/5 * * * *"}, d.Crons)
+	assert.Equal(t, map[string]cloudflare.WorkerBinding{
+		"ENVIRONMENT": cloudflare.WorkerPlainTextBinding{Text: "production"},
+		"LIMITS":      cloudflare.WorkerJSONBinding{Value: map[string]interface{}{"requests": int64(100)}},
+		"CACHE":       cloudflare.WorkerKvNamespaceBinding{NamespaceID: "0f2ac74b498b48028cb68387c421e279"},
+		"COUNTER":     cloudflare.WorkerDurableObjectBinding{ClassName: "Counter"},
+		"ASSETS":      cloudflare.WorkerR2BucketBinding{BucketName: "assets"},
+		"AUTH":        cloudflare.WorkerServiceBinding{Service: "auth", Environment: "production"},
+	}, d.Script.Bindings)
+}
+
+func TestDeploy(t *testing.T) {
+	d := loadTestDeployment(t)
+
+	mux := http.NewServeMux()
+	scriptPath := "/accounts/" + testAccountID + "/workers/scripts/api"
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/scripts", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, `[{"id": "api", "migration_tag": "v1"}, {"id": "auth"}]`)
+	})
+	mux.HandleFunc(scriptPath+"/bindings", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, `[
+			{"name": "ENVIRONMENT", "type": "plain_text", "text": "staging"},
+			{"name": "LIMITS", "type": "json", "json": {"requests": 100}},
+			{"name": "CACHE", "type": "kv_namespace", "namespace_id": "0f2ac74b498b48028cb68387c421e279"},
+			{"name": "LEGACY", "type": "plain_text", "text": "1"},
+			{"name": "TOKEN", "type": "secret_text"}
+		]`)
+	})
+	mux.HandleFunc("/zones", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") != "example.com" {
+			writeResult(w, `[]`)
+			return
+		}
+		writeResult(w, `[{"id": "`+testZoneID+`", "name": "example.com"}]`)
+	})
+
+	var created, updated, deleted []string
+	mux.HandleFunc("/zones/"+testZoneID+"/workers/routes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var route cloudflare.WorkerRoute
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&route))
+			assert.Equal(t, "api", route.Script)
+			created = append(created, route.Pattern)
+			writeResult(w, `{"id": "new"}`)
+			return
+		}
+		writeResult(w, `[
+			{"id": "1", "pattern": "example.com/api/*", "script": "legacy"},
+			{"id": "2", "pattern": "api.example.com/*", "script": "api"},
+			{"id": "3", "pattern": "old.example.com/*", "script": "api"},
+			{"id": "4", "pattern": "example.com/*", "script": "site"}
+		]`)
+	})
+	mux.HandleFunc("/zones/"+testZoneID+"/workers/routes/", func(w http.ResponseWriter, r *http.Request) {
+		id := filepath.Base(r.URL.Path)
+		switch r.Method {
+		case http.MethodPut:
+			updated = append(updated, id)
+		case http.MethodDelete:
+			deleted = append(deleted, id)
+		}
+		writeResult(w, `{"id": "`+id+`"}`)
+	})
+
+	var crons []cloudflare.WorkerCronTrigger
+	mux.HandleFunc(scriptPath+"/schedules", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&crons))
+		}
+		writeResult(w, `{"schedules": [{"cron": "0 * * * *"}]}`)
+	})
+
+	var workersDev *bool
+	mux.HandleFunc(scriptPath+"/subdomain", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var body cloudflare.WorkerScriptSubdomain
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			workersDev = &body.Enabled
+		}
+		writeResult(w, `{"enabled": true}`)
+	})
+
+	var metadata map[string]interface{}
+	mux.HandleFunc(scriptPath, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		require.NoError(t, json.Unmarshal([]byte(r.MultipartForm.Value["metadata"][0]), &metadata))
+		writeResult(w, `{"id": "api"}`)
+	})
+
+	api := newTestAPI(t, mux)
+	plan, err := NewPlan(context.Background(), api, d)
+	require.NoError(t, err)
+	assert.Equal(t, `~ script api (deployed -> uploaded)
++ migration v2
++ binding ASSETS (r2_bucket {"BucketName":"assets"})
++ binding AUTH (service {"Service":"auth","Environment":"production"})
++ binding COUNTER (durable_object_namespace {"ClassName":"Counter","ScriptName":""})
+~ binding ENVIRONMENT (plain_text {"Text":"staging"} -> plain_text {"Text":"production"})
+- binding LEGACY (plain_text {"Text":"1"})
+~ route example.com/api/* (legacy -> api)
+- route old.example.com/* (api)
+- cron 0 * * * *
++ cron */5 * * * *
+~ workers_dev api (enabled -> disabled)
+`, plan.String())
+
+	require.NoError(t, plan.Apply(context.Background(), api))
+
+	assert.Equal(t, "index.mjs", metadata["main_module"])
+	assert.Equal(t, map[string]interface{}{
+		"old_tag": "v1",
+		"new_tag": "v2",
+		"steps": []interface{}{map[string]interface{}{
+			"renamed_classes": []interface{}{map[string]interface{}{"from": "Counter", "to": "Tally"}},
+			"deleted_classes": []interface{}{"Legacy"},
+		}},
+	}, metadata["migrations"])
+	var bindings []string
+	for _, b := range metadata["bindings"].([]interface{}) {
+		b := b.(map[string]interface{})
+		bindings = append(bindings, fmt.Sprintf("%s:%s", b["name"], b["type"]))
+	}
+	assert.ElementsMatch(t, []string{
+		"ENVIRONMENT:plain_text", "LIMITS:json", "CACHE:kv_namespace", "COUNTER:durable_object_namespace",
+		"ASSETS:r2_bucket", "AUTH:service", "TOKEN:inherit",
+	}, bindings)
+
+	assert.Empty(t, created)
+	assert.Equal(t, []string{"1"}, updated)
+	assert.Equal(t, []string{"3"}, deleted)
+	assert.Equal(t, []cloudflare.WorkerCronTrigger{{Cron: "*/5 * * * *"}}, crons)
+	require.NotNil(t, workersDev)
+	assert.False(t, *workersDev)
+}
+
+func TestDeploy_NewScript(t *testing.T) {
+	d := &Deployment{
+		ScriptName: "fresh",
+		Script:     cloudflare.WorkerScriptParams{Script: "addEventListener('fetch', () => {})"},
+		Routes:     []Route{{Pattern: "fresh.example.com/*", ZoneID: testZoneID}},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/scripts", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, `[]`)
+	})
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/scripts/fresh", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, `{"id": "fresh"}`)
+	})
+	var created []string
+	mux.HandleFunc("/zones/"+testZoneID+"/workers/routes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var route cloudflare.WorkerRoute
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&route))
+			created = append(created, route.Pattern)
+			writeResult(w, `{"id": "new"}`)
+			return
+		}
+		writeResult(w, `[]`)
+	})
+
+	plan, err := Deploy(context.Background(), newTestAPI(t, mux), d)
+	require.NoError(t, err)
+	assert.Equal(t, "+ script fresh\n+ route fresh.example.com/* (fresh)\n", plan.String())
+	assert.Equal(t, []string{"fresh.example.com/*"}, created)
+}
+
+func TestNewPlan_UnknownMigrationTag(t *testing.T) {
+	d := &Deployment{
+		ScriptName: "counter",
+		Migrations: []Migration{{Tag: "v1", NewClasses: []string{"Counter"}}},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/accounts/"+testAccountID+"/workers/scripts", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, `[{"id": "counter", "migration_tag": "v3"}]`)
+	})
+
+	_, err := NewPlan(context.Background(), newTestAPI(t, mux), d)
+	assert.EqualError(t, err, `the script's migration tag "v3" is not one of the configured migrations`)
+}
@@ -0,0 +1,540 @@
+package wrangler
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// parseTOML decodes the subset of TOML used by wrangler configuration files:
+// tables, arrays of tables, dotted keys, strings of every kind, integers,
+// floats, booleans, dates and times, arrays and inline tables. Dates and
+// times are returned as time.Time; local ones, which have no offset, are in
+// UTC.
+func parseTOML(data string) (map[string]interface{}, error) {
+	p := &tomlParser{data: data, line: 1, defined: make(map[uintptr]bool)}
+	root, err := p.parse()
+	if err != nil {
+		return nil, errors.Wrapf(err, "line %d", p.line)
+	}
+	return root, nil
+}
+
+type tomlParser struct {
+	data string
+	pos  int
+	line int
+	// defined holds the tables that may not be defined again by a table
+	// header: those with a header of their own, inline tables and tables
+	// created by dotted keys.
+	defined map[uintptr]bool
+}
+
+func (p *tomlParser) define(table map[string]interface{}) bool {
+	ptr := reflect.ValueOf(table).Pointer()
+	if p.defined[ptr] {
+		return false
+	}
+	p.defined[ptr] = true
+	return true
+}
+
+func (p *tomlParser) parse() (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	current := root
+
+	for {
+		p.skipBlank(true)
+		if p.eof() {
+			return root, nil
+		}
+
+		switch {
+		case strings.HasPrefix(p.data[p.pos:], "[["):
+			p.pos += 2
+			path, err := p.parseKey()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]]"); err != nil {
+				return nil, err
+			}
+			parent, err := tableAt(root, path[:len(path)-1])
+			if err != nil {
+				return nil, err
+			}
+			last := path[len(path)-1]
+			var list []map[string]interface{}
+			if existing, ok := parent[last]; ok {
+				if list, ok = existing.([]map[string]interface{}); !ok {
+					return nil, errors.Errorf("key %q is already defined", strings.Join(path, "."))
+				}
+			}
+			current = make(map[string]interface{})
+			parent[last] = append(list, current)
+
+		case p.peek() == '[':
+			p.pos++
+			path, err := p.parseKey()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			parent, err := tableAt(root, path[:len(path)-1])
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := parent[path[len(path)-1]].([]map[string]interface{}); ok {
+				return nil, errors.Errorf("key %q is an array of tables", strings.Join(path, "."))
+			}
+			if current, err = tableAt(root, path); err != nil {
+				return nil, err
+			}
+			if !p.define(current) {
+				return nil, errors.Errorf("table %q is already defined", strings.Join(path, "."))
+			}
+
+		default:
+			if err := p.parseKeyValue(current); err != nil {
+				return nil, err
+			}
+		}
+
+		p.skipBlank(false)
+		if !p.eof() && p.peek() != '\n' && p.peek() != '\r' {
+			return nil, errors.Errorf("unexpected %q after value", p.peek())
+		}
+	}
+}
+
+// tableAt returns the table at path, creating tables as needed. A path
+// through an array of tables refers to its last element.
+func tableAt(root map[string]interface{}, path []string) (map[string]interface{}, error) {
+	t := root
+	for i, key := range path {
+		switch v := t[key].(type) {
+		case nil:
+			next := make(map[string]interface{})
+			t[key] = next
+			t = next
+		case map[string]interface{}:
+			t = v
+		case []map[string]interface{}:
+			t = v[len(v)-1]
+		default:
+			return nil, errors.Errorf("key %q is not a table", strings.Join(path[:i+1], "."))
+		}
+	}
+	return t, nil
+}
+
+func (p *tomlParser) parseKeyValue(table map[string]interface{}) error {
+	path, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	p.skipBlank(false)
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	parent := table
+	for i := range path[:len(path)-1] {
+		if parent, err = tableAt(parent, path[i:i+1]); err != nil {
+			return errors.Errorf("key %q is not a table", strings.Join(path[:i+1], "."))
+		}
+		p.define(parent)
+	}
+	last := path[len(path)-1]
+	if _, ok := parent[last]; ok {
+		return errors.Errorf("key %q is already defined", strings.Join(path, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// parseKey reads a possibly dotted key such as a."b.c".d.
+func (p *tomlParser) parseKey() ([]string, error) {
+	var path []string
+	for {
+		p.skipBlank(false)
+		var key string
+		var err error
+		switch p.peek() {
+		case '"':
+			key, err = p.parseBasicString()
+		case '\'':
+			key, err = p.parseLiteralString()
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, errors.Errorf("expected a key, found %q", p.peek())
+			}
+			key = p.data[start:p.pos]
+		}
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, key)
+
+		p.skipBlank(false)
+		if p.peek() != '.' {
+			return path, nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() (interface{}, error) {
+	switch {
+	case strings.HasPrefix(p.data[p.pos:], `"""`):
+		return p.parseMultilineString(`"""`, true)
+	case strings.HasPrefix(p.data[p.pos:], `'''`):
+		return p.parseMultilineString(`'''`, false)
+	case p.peek() == '"':
+		return p.parseBasicString()
+	case p.peek() == '\'':
+		return p.parseLiteralString()
+	case p.peek() == '[':
+		return p.parseArray()
+	case p.peek() == '{':
+		return p.parseInlineTable()
+	}
+
+	if token := tomlDateTime.FindString(p.data[p.pos:]); token != "" {
+		p.pos += len(token)
+		return parseTOMLDateTime(token)
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.peek())) {
+		p.pos++
+	}
+	token := p.data[start:p.pos]
+
+	switch token {
+	case "":
+		return nil, errors.New("expected a value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf", "-inf", "nan", "+nan", "-nan":
+		f, _ := strconv.ParseFloat(token, 64)
+		return f, nil
+	}
+	if v, ok, err := parseTOMLNumber(token); ok {
+		return v, err
+	}
+	return nil, errors.Errorf("invalid value %q", token)
+}
+
+// tomlDateTime matches an offset or local date-time, whose date and time
+// may be separated by a space, a local date or a local time.
+var tomlDateTime = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?([Zz]|[+-]\d{2}:\d{2})?)?|\d{2}:\d{2}:\d{2}(\.\d+)?)`)
+
+// parseTOMLDateTime parses a token matched by tomlDateTime.
+func parseTOMLDateTime(token string) (time.Time, error) {
+	s := strings.ToUpper(token)
+	if len(s) > 10 && s[10] == ' ' {
+		s = s[:10] + "T" + s[11:]
+	}
+
+	layout := "15:04:05"
+	switch {
+	case len(s) > 10 && (strings.HasSuffix(s, "Z") || strings.ContainsAny(s[19:], "+-")):
+		layout = time.RFC3339
+	case len(s) > 10:
+		layout = "2006-01-02T15:04:05"
+	case strings.Contains(s, "-"):
+		layout = "2006-01-02"
+	}
+	// Fractional seconds are accepted after the seconds without being in
+	// the layout.
+	t, err := time.ParseInLocation(layout, s, time.UTC)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid date or time %q", token)
+	}
+	return t, nil
+}
+
+var (
+	tomlDecimal  = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`)
+	tomlFloat    = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?$`)
+	tomlPrefixed = map[string]struct {
+		base   int
+		digits *regexp.Regexp
+	}{
+		"0x": {16, regexp.MustCompile(`^[0-9A-Fa-f](_?[0-9A-Fa-f])*$`)},
+		"0o": {8, regexp.MustCompile(`^[0-7](_?[0-7])*$`)},
+		"0b": {2, regexp.MustCompile(`^[01](_?[01])*$`)},
+	}
+)
+
+// parseTOMLNumber parses an integer or float as TOML defines them: decimal
+// integers without leading zeros, unsigned hexadecimal, octal and binary
+// integers with a lowercase prefix, and underscores only between digits.
+// It reports false for tokens that aren't numbers.
+func parseTOMLNumber(token string) (interface{}, bool, error) {
+	if len(token) > 2 {
+		if prefixed, ok := tomlPrefixed[token[:2]]; ok {
+			if !prefixed.digits.MatchString(token[2:]) {
+				return nil, true, errors.Errorf("invalid integer %q", token)
+			}
+			i, err := strconv.ParseInt(strings.ReplaceAll(token[2:], "_", ""), prefixed.base, 64)
+			if err != nil {
+				return nil, true, errors.Errorf("integer %q is out of range", token)
+			}
+			return i, true, nil
+		}
+	}
+
+	switch {
+	case tomlDecimal.MatchString(token):
+		i, err := strconv.ParseInt(strings.ReplaceAll(token, "_", ""), 10, 64)
+		if err != nil {
+			return nil, true, errors.Errorf("integer %q is out of range", token)
+		}
+		return i, true, nil
+	case tomlFloat.MatchString(token):
+		f, err := strconv.ParseFloat(strings.ReplaceAll(token, "_", ""), 64)
+		if err != nil {
+			return nil, true, errors.Errorf("float %q is out of range", token)
+		}
+		return f, true, nil
+	}
+	return nil, false, nil
+}
+
+func (p *tomlParser) parseArray() ([]interface{}, error) {
+	p.pos++ // [
+	values := []interface{}{}
+	for {
+		p.skipBlank(true)
+		if p.peek() == ']' {
+			p.pos++
+			return values, nil
+		}
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		p.skipBlank(true)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, errors.Errorf("expected ',' or ']' in array, found %q", p.peek())
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (map[string]interface{}, error) {
+	p.pos++ // {
+	table := make(map[string]interface{})
+	p.define(table)
+	p.skipBlank(false)
+	if p.peek() == '}' {
+		p.pos++
+		return table, nil
+	}
+	for {
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipBlank(false)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, errors.Errorf("expected ',' or '}' in inline table, found %q", p.peek())
+		}
+	}
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.pos++ // "
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", errors.New("unterminated string")
+		}
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.pos++ // '
+	end := strings.IndexAny(p.data[p.pos:], "'\n")
+	if end < 0 || p.data[p.pos+end] != '\'' {
+		return "", errors.New("unterminated string")
+	}
+	s := p.data[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+func (p *tomlParser) parseMultilineString(delim string, escapes bool) (string, error) {
+	p.pos += len(delim)
+	// A newline immediately after the opening delimiter is trimmed.
+	if strings.HasPrefix(p.data[p.pos:], "\r\n") {
+		p.pos += 2
+		p.line++
+	} else if p.peek() == '\n' {
+		p.pos++
+		p.line++
+	}
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", errors.New("unterminated string")
+		}
+		if strings.HasPrefix(p.data[p.pos:], delim) {
+			p.pos += len(delim)
+			return b.String(), nil
+		}
+		c := p.data[p.pos]
+		p.pos++
+		switch {
+		case c == '\n':
+			p.line++
+			b.WriteByte(c)
+		case c == '\\' && escapes:
+			// A backslash at the end of a line trims the following whitespace.
+			rest := strings.TrimLeft(p.data[p.pos:], " \t")
+			if strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+				for !p.eof() && strings.ContainsRune(" \t\r\n", rune(p.peek())) {
+					if p.peek() == '\n' {
+						p.line++
+					}
+					p.pos++
+				}
+				continue
+			}
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (p *tomlParser) parseEscape(b *strings.Builder) error {
+	if p.eof() {
+		return errors.New("unterminated escape sequence")
+	}
+	c := p.data[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.data) {
+			return errors.New("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.data[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return errors.Errorf("invalid unicode escape %q", p.data[p.pos:p.pos+n])
+		}
+		b.WriteRune(rune(code))
+		p.pos += n
+	default:
+		return errors.Errorf("invalid escape sequence \\%c", c)
+	}
+	return nil
+}
+
+// skipBlank skips spaces, tabs and comments, and newlines too if newlines is
+// set.
+func (p *tomlParser) skipBlank(newlines bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		case newlines && c == '\r':
+			p.pos++
+		case newlines && c == '\n':
+			p.pos++
+			p.line++
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) expect(s string) error {
+	p.skipBlank(false)
+	if !strings.HasPrefix(p.data[p.pos:], s) {
+		return errors.Errorf("expected %q", s)
+	}
+	p.pos += len(s)
+	return nil
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.data)
+}
@@ -0,0 +1,118 @@
+package wrangler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTOML(t *testing.T) {
+	doc, err := parseTOML(`
+# comment
+title = "basic \"quoted\" \u00e9"   # trailing comment
+literal = 'C:\path'
+count = 1_000
+hex = 0xff
+octal = 0o17
+binary = 0b1_01
+negative = -17
+exponent = 1e3
+ratio = 0.5
+enabled = true
+date = 2021-11-01
+offset = 1979-05-27 07:32:00Z
+shifted = 1979-05-27T00:32:00.5-07:00
+local = 1979-05-27t07:32:00
+clock = 07:32:00
+dotted.key = "x"
+"quoted key" = 1
+multi = """
+first \
+  second"""
+raw = '''
+line\n'''
+list = [
+  "a",
+  'b', # comment
+]
+inline = { a = 1, b = { c = "d" } }
+
+[table.sub]
+k = "v"
+
+[table]
+other.k = "w"
+
+[table.other.deeper]
+k = "x"
+
+[[items]]
+name = "one"
+
+[[items]]
+name = "two"
+`)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"title":      `basic "quoted" é`,
+		"literal":    `C:\path`,
+		"count":      int64(1000),
+		"hex":        int64(255),
+		"octal":      int64(15),
+		"binary":     int64(5),
+		"negative":   int64(-17),
+		"exponent":   1000.0,
+		"ratio":      0.5,
+		"enabled":    true,
+		"date":       time.Date(2021, time.November, 1, 0, 0, 0, 0, time.UTC),
+		"offset":     time.Date(1979, time.May, 27, 7, 32, 0, 0, time.UTC),
+		"shifted":    time.Date(1979, time.May, 27, 0, 32, 0, 5e8, time.FixedZone("", -7*60*60)),
+		"local":      time.Date(1979, time.May, 27, 7, 32, 0, 0, time.UTC),
+		"clock":      time.Date(0, time.January, 1, 7, 32, 0, 0, time.UTC),
+		"dotted":     map[string]interface{}{"key": "x"},
+		"quoted key": int64(1),
+		"multi":      "first second",
+		"raw":        `line\n`,
+		"list":       []interface{}{"a", "b"},
+		"inline":     map[string]interface{}{"a": int64(1), "b": map[string]interface{}{"c": "d"}},
+		"table": map[string]interface{}{
+			"sub":   map[string]interface{}{"k": "v"},
+			"other": map[string]interface{}{"k": "w", "deeper": map[string]interface{}{"k": "x"}},
+		},
+		"items": []map[string]interface{}{
+			{"name": "one"},
+			{"name": "two"},
+		},
+	}, doc)
+}
+
+func TestParseTOML_Errors(t *testing.T) {
+	tests := map[string]string{
+		"a = 1\na = 2":                         `line 2: key "a" is already defined`,
+		"a = \"open":                           "line 1: unterminated string",
+		"a = [1 2]":                            `line 1: expected ',' or ']' in array, found '2'`,
+		"a = 1 b = 2":                          `line 1: unexpected 'b' after value`,
+		"a = nope":                             `line 1: invalid value "nope"`,
+		"a = 010":                              `line 1: invalid value "010"`,
+		"a = 0x_ff":                            `line 1: invalid integer "0x_ff"`,
+		"a = -0xff":                            `line 1: invalid value "-0xff"`,
+		"a = 1__000":                           `line 1: invalid value "1__000"`,
+		"a = 01.5":                             `line 1: invalid value "01.5"`,
+		"a = 1.":                               `line 1: invalid value "1."`,
+		"a = 1\n[a]":                           `line 2: key "a" is not a table`,
+		"\n\nx = \"\\q\"":                      `line 3: invalid escape sequence \q`,
+		"[[t]]\nk = 1\n[t]\n=":                 `line 3: key "t" is an array of tables`,
+		"[vars]\nX = \"1\"\n[vars]\nY = \"2\"": `line 3: table "vars" is already defined`,
+		"a.b.c = 1\n[a.b]":                     `line 2: table "a.b" is already defined`,
+		"a = { b = 1 }\n[a]":                   `line 2: table "a" is already defined`,
+		"d = 2021-13-01":                       `line 1: invalid date or time "2021-13-01"`,
+		"d = 2021-11-01 x":                     `line 1: unexpected 'x' after value`,
+	}
+	for input, want := range tests {
+		_, err := parseTOML(input)
+		assert.EqualError(t, err, want, input)
+	}
+}
@@ -0,0 +1,192 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Activation checks may be triggered once an hour on the Free plan and
+// every five minutes on paid plans.
+const (
+	freeZoneActivationCheckInterval = time.Hour
+	paidZoneActivationCheckInterval = 5 * time.Minute
+	defaultZoneActivationPoll       = 30 * time.Second
+)
+
+// NSResolver looks up the nameservers of a domain. *net.Resolver satisfies
+// it.
+type NSResolver interface {
+	LookupNS(ctx context.Context, name string) ([]*net.NS, error)
+}
+
+// WaitForZoneActiveOptions configures WaitForZoneActive.
+type WaitForZoneActiveOptions struct {
+	// PollInterval is how often the zone status is fetched. Defaults to 30
+	// seconds.
+	PollInterval time.Duration
+
+	// ActivationCheckInterval is how often a new activation check is
+	// requested. Defaults to the fastest cadence the zone's plan allows.
+	ActivationCheckInterval time.Duration
+
+	// Resolver, if set, is used to look up the nameservers the zone is
+	// currently delegated to so progress reports can explain why it is not
+	// active yet.
+	Resolver NSResolver
+
+	// OnProgress is called after every poll while the zone is pending, and
+	// once more when it becomes active.
+	OnProgress func(ZoneActivationProgress)
+}
+
+// ZoneActivationProgress describes the state of a zone while waiting for it
+// to become active.
+type ZoneActivationProgress struct {
+	Zone    Zone
+	Attempt int
+
+	// ActivationCheck is true if an activation check was requested during
+	// this attempt. ActivationCheckError holds the reason it failed, which
+	// does not stop the wait.
+	ActivationCheck      bool
+	ActivationCheckError error
+
+	// Delegated holds the nameservers found in DNS when a Resolver is
+	// configured. Missing are assigned nameservers that are not delegated
+	// to and Unexpected are delegated nameservers that were not assigned.
+	Delegated     []string
+	Missing       []string
+	Unexpected    []string
+	ResolverError error
+}
+
+// Active reports whether the zone has been activated.
+func (p ZoneActivationProgress) Active() bool {
+	return p.Zone.Status == "active"
+}
+
+// Reason returns a human readable explanation of the zone's state.
+func (p ZoneActivationProgress) Reason() string {
+	switch {
+	case p.Active():
+		return "zone is active"
+	case p.ResolverError != nil:
+		return fmt.Sprintf("zone is %s; could not look up nameservers: %s", p.Zone.Status, p.ResolverError)
+	case len(p.Missing) > 0 || len(p.Unexpected) > 0:
+		return fmt.Sprintf("zone is %s; delegated to %s but assigned %s",
+			p.Zone.Status, strings.Join(p.Delegated, ", "), strings.Join(zoneAssignedNameServers(p.Zone), ", "))
+	case p.Delegated != nil:
+		return fmt.Sprintf("zone is %s; nameservers match, waiting for Cloudflare to notice", p.Zone.Status)
+	}
+	return fmt.Sprintf("zone is %s", p.Zone.Status)
+}
+
+// WaitForZoneActive polls a zone until its status becomes active, requesting
+// activation checks as often as the zone's plan allows. It returns the
+// active zone, or the context's error if it is done first.
+func (api *API) WaitForZoneActive(ctx context.Context, zoneID string, opts WaitForZoneActiveOptions) (Zone, error) {
+	poll := opts.PollInterval
+	if poll <= 0 {
+		poll = defaultZoneActivationPoll
+	}
+
+	var lastCheck time.Time
+	for attempt := 1; ; attempt++ {
+		zone, err := api.ZoneDetails(ctx, zoneID)
+		if err != nil {
+			return Zone{}, err
+		}
+
+		progress := ZoneActivationProgress{Zone: zone, Attempt: attempt}
+		if progress.Active() {
+			if opts.OnProgress != nil {
+				opts.OnProgress(progress)
+			}
+			return zone, nil
+		}
+
+		interval := opts.ActivationCheckInterval
+		if interval <= 0 {
+			interval = zoneActivationCheckInterval(zone)
+		}
+		if lastCheck.IsZero() || time.Since(lastCheck) >= interval {
+			lastCheck = time.Now()
+			progress.ActivationCheck = true
+			_, progress.ActivationCheckError = api.ZoneActivationCheck(ctx, zoneID)
+		}
+
+		if opts.Resolver != nil && zone.Type != "partial" {
+			progress.checkDelegation(ctx, opts.Resolver)
+		}
+
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+
+		timer := time.NewTimer(poll)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Zone{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// checkDelegation compares the nameservers a zone is delegated to with the
+// ones Cloudflare assigned to it.
+func (p *ZoneActivationProgress) checkDelegation(ctx context.Context, resolver NSResolver) {
+	records, err := resolver.LookupNS(ctx, p.Zone.Name)
+	if err != nil {
+		p.ResolverError = err
+		return
+	}
+
+	p.Delegated = make([]string, 0, len(records))
+	delegated := make(map[string]bool, len(records))
+	for _, ns := range records {
+		host := strings.ToLower(strings.TrimSuffix(ns.Host, "."))
+		p.Delegated = append(p.Delegated, host)
+		delegated[host] = true
+	}
+	sort.Strings(p.Delegated)
+
+	assigned := make(map[string]bool)
+	for _, ns := range zoneAssignedNameServers(p.Zone) {
+		assigned[ns] = true
+		if !delegated[ns] {
+			p.Missing = append(p.Missing, ns)
+		}
+	}
+	for _, ns := range p.Delegated {
+		if !assigned[ns] {
+			p.Unexpected = append(p.Unexpected, ns)
+		}
+	}
+}
+
+// zoneAssignedNameServers returns the nameservers a zone must be delegated
+// to, preferring vanity nameservers when the zone has them.
+func zoneAssignedNameServers(zone Zone) []string {
+	ns := zone.NameServers
+	if len(zone.VanityNS) > 0 {
+		ns = zone.VanityNS
+	}
+	out := make([]string, 0, len(ns))
+	for _, n := range ns {
+		out = append(out, strings.ToLower(strings.TrimSuffix(n, ".")))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func zoneActivationCheckInterval(zone Zone) time.Duration {
+	if zone.Plan.Price == 0 {
+		return freeZoneActivationCheckInterval
+	}
+	return paidZoneActivationCheckInterval
+}
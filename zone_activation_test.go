@@ -0,0 +1,155 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// serveNS answers NS queries for any name with nameservers until the
+// returned function is called.
+func serveNS(t *testing.T, nameservers ...string) (addr string, stop func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var p dnsmessage.Parser
+			header, err := p.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			q, err := p.Question()
+			if err != nil {
+				continue
+			}
+
+			b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true})
+			b.EnableCompression()
+			_ = b.StartQuestions()
+			_ = b.Question(q)
+			_ = b.StartAnswers()
+			if q.Type == dnsmessage.TypeNS {
+				for _, ns := range nameservers {
+					_ = b.NSResource(
+						dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 300},
+						dnsmessage.NSResource{NS: dnsmessage.MustNewName(ns + ".")},
+					)
+				}
+			}
+			msg, err := b.Finish()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(msg, from)
+		}
+	}()
+
+	return conn.LocalAddr().String(), func() { conn.Close() }
+}
+
+func TestWaitForZoneActive(t *testing.T) {
+	setup()
+	defer teardown()
+
+	dnsAddr, stop := serveNS(t, "ns1.registrar.example", "ns2.registrar.example")
+	defer stop()
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", dnsAddr)
+		},
+	}
+
+	polls := 0
+	mux.HandleFunc("/zones/"+testZoneID, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		polls++
+		status := "pending"
+		if polls == 3 {
+			status = "active"
+		}
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": {
+			"id": %q, "name": "example.com", "status": %q, "type": "full",
+			"name_servers": ["ns1.registrar.example", "bob.ns.cloudflare.com"],
+			"plan": {"id": "0feeeeeeeeeeeeeeeeeeeeeeeeeeeeee", "name": "Free Website", "price": 0}
+		}}`, testZoneID, status)
+	})
+
+	checks := 0
+	mux.HandleFunc("/zones/"+testZoneID+"/activation_check", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method, "Expected method 'PUT', got %s", r.Method)
+		checks++
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": {"id": "`+testZoneID+`"}}`)
+	})
+
+	var progress []ZoneActivationProgress
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	zone, err := client.WaitForZoneActive(ctx, testZoneID, WaitForZoneActiveOptions{
+		PollInterval: time.Millisecond,
+		Resolver:     resolver,
+		OnProgress:   func(p ZoneActivationProgress) { progress = append(progress, p) },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "active", zone.Status)
+
+	// Free zones may only be checked once an hour.
+	assert.Equal(t, 1, checks)
+
+	require.Len(t, progress, 3)
+	first := progress[0]
+	assert.True(t, first.ActivationCheck)
+	assert.NoError(t, first.ActivationCheckError)
+	assert.Equal(t, []string{"ns1.registrar.example", "ns2.registrar.example"}, first.Delegated)
+	assert.Equal(t, []string{"bob.ns.cloudflare.com"}, first.Missing)
+	assert.Equal(t, []string{"ns2.registrar.example"}, first.Unexpected)
+	assert.Equal(t, "zone is pending; delegated to ns1.registrar.example, ns2.registrar.example but assigned bob.ns.cloudflare.com, ns1.registrar.example", first.Reason())
+	assert.False(t, progress[1].ActivationCheck)
+	assert.True(t, progress[2].Active())
+}
+
+func TestWaitForZoneActive_ContextDone(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/zones/"+testZoneID, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": {"id": "`+testZoneID+`", "status": "pending", "plan": {"price": 20}}}`)
+	})
+	mux.HandleFunc("/zones/"+testZoneID+"/activation_check", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"success": false, "errors": [{"code": 1224, "message": "You may only perform this action once per hour."}], "messages": [], "result": null}`)
+	})
+
+	var last ZoneActivationProgress
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.WaitForZoneActive(ctx, testZoneID, WaitForZoneActiveOptions{
+		PollInterval: 5 * time.Millisecond,
+		OnProgress:   func(p ZoneActivationProgress) { last = p },
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, "zone is pending", last.Reason())
+	assert.Nil(t, last.Delegated)
+}
@@ -0,0 +1,645 @@
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ZoneCloneComponent is a part of a zone's configuration that CloneZone can
+// copy.
+type ZoneCloneComponent string
+
+// Components supported by CloneZone.
+const (
+	ZoneCloneSettings      ZoneCloneComponent = "settings"
+	ZoneClonePageRules     ZoneCloneComponent = "page_rules"
+	ZoneCloneFirewallRules ZoneCloneComponent = "firewall_rules"
+	ZoneCloneRulesets      ZoneCloneComponent = "rulesets"
+	ZoneCloneDNSRecords    ZoneCloneComponent = "dns_records"
+	ZoneCloneArgo          ZoneCloneComponent = "argo"
+)
+
+// Operations recorded in a ZoneCloneReport.
+const (
+	ZoneCloneCreate    = "create"
+	ZoneCloneUpdate    = "update"
+	ZoneCloneUnchanged = "unchanged"
+)
+
+// ZoneCloneOptions selects what CloneZone copies. Nothing is copied unless
+// it is listed in Components.
+type ZoneCloneOptions struct {
+	Components []ZoneCloneComponent
+
+	// RulesetPhases limits which ruleset entry points are copied. If empty,
+	// every zone entry point ruleset of the source zone is copied.
+	RulesetPhases []RulesetPhase
+
+	// SkipSettings lists zone setting IDs that are never copied, in
+	// addition to settings that are not editable on the source zone.
+	SkipSettings []string
+
+	// DryRun computes the report without changing the target zone.
+	DryRun bool
+}
+
+// ZoneCloneAction describes a single change made, or that would be made, to
+// the target zone.
+type ZoneCloneAction struct {
+	Component ZoneCloneComponent `json:"component"`
+	Operation string             `json:"operation"`
+	Resource  string             `json:"resource"`
+}
+
+// ZoneCloneReport lists every resource CloneZone considered.
+type ZoneCloneReport struct {
+	SourceZone string            `json:"source_zone"`
+	TargetZone string            `json:"target_zone"`
+	DryRun     bool              `json:"dry_run"`
+	Actions    []ZoneCloneAction `json:"actions"`
+}
+
+// Changes returns the actions that create or update a resource.
+func (r ZoneCloneReport) Changes() []ZoneCloneAction {
+	var changes []ZoneCloneAction
+	for _, a := range r.Actions {
+		if a.Operation != ZoneCloneUnchanged {
+			changes = append(changes, a)
+		}
+	}
+	return changes
+}
+
+// zoneCloner holds the state of a single CloneZone call.
+type zoneCloner struct {
+	api     *API
+	source  Zone
+	target  Zone
+	opts    ZoneCloneOptions
+	report  *ZoneCloneReport
+	rewrite func(string) string
+}
+
+// CloneZone copies the selected components of the source zone's
+// configuration onto the target zone. Hostnames in the source zone are
+// rewritten to the target zone, so "www.example.com" becomes
+// "www.example.net" when cloning example.com to example.net.
+//
+// Cloning is idempotent: resources already matching the source are left
+// alone and are reported as unchanged, so it is safe to re-run. Resources on
+// the target that have no counterpart in the source are never removed.
+func (api *API) CloneZone(ctx context.Context, sourceZoneID, targetZoneID string, opts ZoneCloneOptions) (ZoneCloneReport, error) {
+	source, err := api.ZoneDetails(ctx, sourceZoneID)
+	if err != nil {
+		return ZoneCloneReport{}, errors.Wrap(err, "could not fetch source zone")
+	}
+	target, err := api.ZoneDetails(ctx, targetZoneID)
+	if err != nil {
+		return ZoneCloneReport{}, errors.Wrap(err, "could not fetch target zone")
+	}
+
+	report := ZoneCloneReport{SourceZone: source.Name, TargetZone: target.Name, DryRun: opts.DryRun}
+	c := &zoneCloner{
+		api:    api,
+		source: source,
+		target: target,
+		opts:   opts,
+		report: &report,
+		rewrite: func(s string) string {
+			return rewriteZoneHostnames(s, source.Name, target.Name)
+		},
+	}
+
+	steps := []struct {
+		component ZoneCloneComponent
+		run       func(context.Context) error
+	}{
+		{ZoneCloneSettings, c.settings},
+		{ZoneCloneDNSRecords, c.dnsRecords},
+		{ZoneClonePageRules, c.pageRules},
+		{ZoneCloneFirewallRules, c.firewallRules},
+		{ZoneCloneRulesets, c.rulesets},
+		{ZoneCloneArgo, c.argo},
+	}
+	for _, step := range steps {
+		if !c.enabled(step.component) {
+			continue
+		}
+		if err := step.run(ctx); err != nil {
+			return report, errors.Wrapf(err, "could not clone %s", step.component)
+		}
+	}
+
+	return report, nil
+}
+
+func (c *zoneCloner) enabled(component ZoneCloneComponent) bool {
+	for _, v := range c.opts.Components {
+		if v == component {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *zoneCloner) record(component ZoneCloneComponent, operation, format string, a ...interface{}) {
+	c.report.Actions = append(c.report.Actions, ZoneCloneAction{
+		Component: component,
+		Operation: operation,
+		Resource:  fmt.Sprintf(format, a...),
+	})
+}
+
+func (c *zoneCloner) settings(ctx context.Context) error {
+	source, err := c.api.ZoneSettings(ctx, c.source.ID)
+	if err != nil {
+		return err
+	}
+	target, err := c.api.ZoneSettings(ctx, c.target.ID)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]interface{}, len(target.Result))
+	for _, s := range target.Result {
+		current[s.ID] = s.Value
+	}
+
+	var changes []ZoneSetting
+	for _, s := range source.Result {
+		if !s.Editable || containsString(c.opts.SkipSettings, s.ID) {
+			continue
+		}
+		if existing, ok := current[s.ID]; ok && jsonEqual(existing, s.Value) {
+			c.record(ZoneCloneSettings, ZoneCloneUnchanged, "%s", s.ID)
+			continue
+		}
+		c.record(ZoneCloneSettings, ZoneCloneUpdate, "%s", s.ID)
+		changes = append(changes, ZoneSetting{ID: s.ID, Value: s.Value})
+	}
+
+	if len(changes) == 0 || c.opts.DryRun {
+		return nil
+	}
+	_, err = c.api.UpdateZoneSettings(ctx, c.target.ID, changes)
+	return err
+}
+
+func (c *zoneCloner) dnsRecords(ctx context.Context) error {
+	source, err := c.api.DNSRecords(ctx, c.source.ID, DNSRecord{})
+	if err != nil {
+		return err
+	}
+	target, err := c.api.DNSRecords(ctx, c.target.ID, DNSRecord{})
+	if err != nil {
+		return err
+	}
+
+	var wants []DNSRecord
+	for _, r := range source {
+		// The apex NS records belong to the zone, not its configuration.
+		if r.Type == "NS" && strings.EqualFold(r.Name, c.source.Name) {
+			continue
+		}
+
+		want := DNSRecord{
+			Type:     r.Type,
+			Name:     c.rewrite(r.Name),
+			Content:  c.rewrite(r.Content),
+			Proxied:  r.Proxied,
+			TTL:      r.TTL,
+			Priority: r.Priority,
+		}
+		if data := dnsRecordData(r); data != nil {
+			want.Data = rewriteJSONValue(data, c.rewrite)
+		}
+		wants = append(wants, want)
+	}
+
+	claimed := make(map[string]bool)
+	for _, want := range wants {
+		resource := want.Type + " " + want.Name
+
+		existing := findClonedDNSRecord(target, wants, want, claimed)
+		if existing != nil {
+			claimed[existing.ID] = true
+		}
+		switch {
+		case existing == nil:
+			c.record(ZoneCloneDNSRecords, ZoneCloneCreate, "%s", resource)
+			if !c.opts.DryRun {
+				if _, err := c.api.CreateDNSRecord(ctx, c.target.ID, want); err != nil {
+					return err
+				}
+			}
+		case existing.Content == want.Content && existing.TTL == want.TTL &&
+			jsonEqual(existing.Proxied, want.Proxied) && jsonEqual(existing.Priority, want.Priority):
+			c.record(ZoneCloneDNSRecords, ZoneCloneUnchanged, "%s", resource)
+		default:
+			c.record(ZoneCloneDNSRecords, ZoneCloneUpdate, "%s", resource)
+			if !c.opts.DryRun {
+				if err := c.api.UpdateDNSRecord(ctx, c.target.ID, existing.ID, want); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// findClonedDNSRecord returns the record in existing that want should
+// replace, skipping records already claimed by another wanted record. A
+// record with identical content is preferred; otherwise a record of the
+// same name and type is reused as long as no wanted record has its content,
+// so a record whose content changed is updated rather than duplicated.
+func findClonedDNSRecord(existing, wants []DNSRecord, want DNSRecord, claimed map[string]bool) *DNSRecord {
+	sameNameType := func(r DNSRecord) bool {
+		return r.Type == want.Type && strings.EqualFold(r.Name, want.Name)
+	}
+
+	for i, r := range existing {
+		if !claimed[r.ID] && sameNameType(r) && r.Content == want.Content {
+			return &existing[i]
+		}
+	}
+	for i, r := range existing {
+		if claimed[r.ID] || !sameNameType(r) {
+			continue
+		}
+		stale := true
+		for _, w := range wants {
+			if sameNameType(w) && w.Content == r.Content {
+				stale = false
+				break
+			}
+		}
+		if stale {
+			return &existing[i]
+		}
+	}
+	return nil
+}
+
+func (c *zoneCloner) pageRules(ctx context.Context) error {
+	source, err := c.api.ListPageRules(ctx, c.source.ID)
+	if err != nil {
+		return err
+	}
+	target, err := c.api.ListPageRules(ctx, c.target.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range source {
+		want := PageRule{
+			Targets:  make([]PageRuleTarget, len(r.Targets)),
+			Actions:  make([]PageRuleAction, len(r.Actions)),
+			Priority: r.Priority,
+			Status:   r.Status,
+		}
+		for i, t := range r.Targets {
+			want.Targets[i] = t
+			want.Targets[i].Constraint.Value = c.rewrite(t.Constraint.Value)
+		}
+		for i, a := range r.Actions {
+			want.Actions[i] = PageRuleAction{ID: a.ID, Value: rewriteJSONValue(a.Value, c.rewrite)}
+		}
+
+		var existing *PageRule
+		for i, t := range target {
+			if jsonEqual(t.Targets, want.Targets) {
+				existing = &target[i]
+				break
+			}
+		}
+		resource := pageRuleURL(want)
+
+		switch {
+		case existing == nil:
+			c.record(ZoneClonePageRules, ZoneCloneCreate, "%s", resource)
+			if !c.opts.DryRun {
+				if _, err := c.api.CreatePageRule(ctx, c.target.ID, want); err != nil {
+					return err
+				}
+			}
+		case jsonEqual(existing.Actions, want.Actions) && existing.Status == want.Status && existing.Priority == want.Priority:
+			c.record(ZoneClonePageRules, ZoneCloneUnchanged, "%s", resource)
+		default:
+			c.record(ZoneClonePageRules, ZoneCloneUpdate, "%s", resource)
+			if !c.opts.DryRun {
+				if err := c.api.UpdatePageRule(ctx, c.target.ID, existing.ID, want); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func pageRuleURL(r PageRule) string {
+	if len(r.Targets) == 0 {
+		return ""
+	}
+	return r.Targets[0].Constraint.Value
+}
+
+func (c *zoneCloner) firewallRules(ctx context.Context) error {
+	source, err := c.api.allFirewallRules(ctx, c.source.ID)
+	if err != nil {
+		return err
+	}
+	target, err := c.api.allFirewallRules(ctx, c.target.ID)
+	if err != nil {
+		return err
+	}
+
+	claimed := make(map[string]bool)
+	for _, r := range source {
+		want := FirewallRule{
+			Paused:      r.Paused,
+			Description: r.Description,
+			Action:      r.Action,
+			Priority:    r.Priority,
+			Products:    r.Products,
+			Filter: Filter{
+				Expression:  c.rewrite(r.Filter.Expression),
+				Paused:      r.Filter.Paused,
+				Description: r.Filter.Description,
+				Ref:         r.Filter.Ref,
+			},
+		}
+
+		existing := findClonedFirewallRule(target, want, claimed)
+		if existing != nil {
+			claimed[existing.ID] = true
+		}
+		resource := want.Action + " " + want.Filter.Expression
+
+		switch {
+		case existing == nil:
+			c.record(ZoneCloneFirewallRules, ZoneCloneCreate, "%s", resource)
+			if !c.opts.DryRun {
+				if _, err := c.api.CreateFirewallRules(ctx, c.target.ID, []FirewallRule{want}); err != nil {
+					return err
+				}
+			}
+		case existing.Action == want.Action && existing.Paused == want.Paused && existing.Description == want.Description &&
+			jsonEqual(existing.Priority, want.Priority) && jsonEqual(existing.Products, want.Products):
+			c.record(ZoneCloneFirewallRules, ZoneCloneUnchanged, "%s", resource)
+		default:
+			c.record(ZoneCloneFirewallRules, ZoneCloneUpdate, "%s", resource)
+			if !c.opts.DryRun {
+				want.ID = existing.ID
+				want.Filter.ID = existing.Filter.ID
+				if _, err := c.api.UpdateFirewallRule(ctx, c.target.ID, want); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// findClonedFirewallRule returns the unclaimed rule in existing with the
+// same filter expression as want, preferring one that also has the same
+// action, so a rule whose action changed is updated rather than duplicated.
+func findClonedFirewallRule(existing []FirewallRule, want FirewallRule, claimed map[string]bool) *FirewallRule {
+	var sameExpression *FirewallRule
+	for i, r := range existing {
+		if claimed[r.ID] || r.Filter.Expression != want.Filter.Expression {
+			continue
+		}
+		if r.Action == want.Action {
+			return &existing[i]
+		}
+		if sameExpression == nil {
+			sameExpression = &existing[i]
+		}
+	}
+	return sameExpression
+}
+
+// allFirewallRules pages through every firewall rule of a zone.
+func (api *API) allFirewallRules(ctx context.Context, zoneID string) ([]FirewallRule, error) {
+	const perPage = 100
+	var rules []FirewallRule
+	for page := 1; ; page++ {
+		res, err := api.FirewallRules(ctx, zoneID, PaginationOptions{Page: page, PerPage: perPage})
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, res...)
+		if len(res) < perPage {
+			return rules, nil
+		}
+	}
+}
+
+func (c *zoneCloner) rulesets(ctx context.Context) error {
+	phases := c.opts.RulesetPhases
+	if len(phases) == 0 {
+		rulesets, err := c.api.ListZoneRulesets(ctx, c.source.ID)
+		if err != nil {
+			return err
+		}
+		for _, rs := range rulesets {
+			if rs.Kind == string(RulesetKindZone) {
+				phases = append(phases, RulesetPhase(rs.Phase))
+			}
+		}
+	}
+
+	for _, phase := range phases {
+		source, err := c.api.GetZoneRulesetPhase(ctx, c.source.ID, string(phase))
+		if err != nil {
+			return err
+		}
+
+		rules := make([]RulesetRule, len(source.Rules))
+		for i, r := range source.Rules {
+			r.ID = ""
+			r.Version = ""
+			r.LastUpdated = nil
+			r.Expression = c.rewrite(r.Expression)
+			rules[i] = r
+		}
+
+		target, err := c.api.GetZoneRulesetPhase(ctx, c.target.ID, string(phase))
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+
+		existing := make([]RulesetRule, len(target.Rules))
+		for i, r := range target.Rules {
+			r.ID = ""
+			r.Version = ""
+			r.LastUpdated = nil
+			existing[i] = r
+		}
+
+		switch {
+		case target.ID == "":
+			c.record(ZoneCloneRulesets, ZoneCloneCreate, "%s", phase)
+		case jsonEqual(existing, rules) && target.Description == source.Description:
+			c.record(ZoneCloneRulesets, ZoneCloneUnchanged, "%s", phase)
+			continue
+		default:
+			c.record(ZoneCloneRulesets, ZoneCloneUpdate, "%s", phase)
+		}
+
+		if c.opts.DryRun {
+			continue
+		}
+		_, err = c.api.UpdateZoneRulesetPhase(ctx, c.target.ID, string(phase), Ruleset{
+			Name:        source.Name,
+			Description: source.Description,
+			Kind:        source.Kind,
+			Phase:       source.Phase,
+			Rules:       rules,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *zoneCloner) argo(ctx context.Context) error {
+	settings := []struct {
+		name   string
+		get    func(context.Context, string) (ArgoFeatureSetting, error)
+		update func(context.Context, string, string) (ArgoFeatureSetting, error)
+	}{
+		{"smart_routing", c.api.ArgoSmartRouting, c.api.UpdateArgoSmartRouting},
+		{"tiered_caching", c.api.ArgoTieredCaching, c.api.UpdateArgoTieredCaching},
+	}
+
+	for _, s := range settings {
+		source, err := s.get(ctx, c.source.ID)
+		if err != nil {
+			return err
+		}
+		target, err := s.get(ctx, c.target.ID)
+		if err != nil {
+			return err
+		}
+
+		if source.Value == target.Value {
+			c.record(ZoneCloneArgo, ZoneCloneUnchanged, "%s", s.name)
+			continue
+		}
+		c.record(ZoneCloneArgo, ZoneCloneUpdate, "%s", s.name)
+		if !c.opts.DryRun {
+			if _, err := s.update(ctx, c.target.ID, source.Value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// rewriteZoneHostnames replaces every hostname in s that is from, or a
+// subdomain of from, with the same name under to. Matching is case
+// insensitive and respects label boundaries, so rewriting example.com leaves
+// myexample.com and example.com.au alone.
+func rewriteZoneHostnames(s, from, to string) string {
+	if from == "" || from == to {
+		return s
+	}
+
+	lower := strings.ToLower(s)
+	from = strings.ToLower(from)
+
+	var b strings.Builder
+	last := 0
+	for i := 0; i+len(from) <= len(s); {
+		j := strings.Index(lower[i:], from)
+		if j < 0 {
+			break
+		}
+		start, end := i+j, i+j+len(from)
+		i = start + 1
+
+		if start > 0 && isHostnameChar(lower[start-1]) {
+			continue
+		}
+		if end < len(s) {
+			next := lower[end]
+			if next == '.' {
+				// A trailing dot is fine, a further label is not.
+				if end+1 < len(s) && isHostnameChar(lower[end+1]) {
+					continue
+				}
+			} else if isHostnameChar(next) {
+				continue
+			}
+		}
+
+		b.WriteString(s[last:start])
+		b.WriteString(to)
+		last = end
+		i = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func isHostnameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-'
+}
+
+// rewriteJSONValue applies rewrite to every string inside a decoded JSON
+// value.
+func rewriteJSONValue(v interface{}, rewrite func(string) string) interface{} {
+	switch v := v.(type) {
+	case string:
+		return rewrite(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = rewriteJSONValue(e, rewrite)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = rewriteJSONValue(e, rewrite)
+		}
+		return out
+	}
+	return v
+}
+
+// jsonEqual reports whether a and b have the same JSON encoding, which
+// sidesteps differences such as int versus float64 after decoding.
+func jsonEqual(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotFound reports whether err is an API error with a 404 status.
+func isNotFound(err error) bool {
+	var apiErr *APIRequestError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,221 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteZoneHostnames(t *testing.T) {
+	tests := map[string]string{
+		"example.com":                      "example.net",
+		"www.example.com":                  "www.example.net",
+		"WWW.Example.COM.":                 "WWW.example.net.",
+		"https://example.com/*":            "https://example.net/*",
+		"myexample.com":                    "myexample.com",
+		"example.com.au":                   "example.com.au",
+		`http.host eq "api.example.com"`:   `http.host eq "api.example.net"`,
+		"a.example.com and b.example.com.": "a.example.net and b.example.net.",
+	}
+
+	for in, want := range tests {
+		assert.Equal(t, want, rewriteZoneHostnames(in, "example.com", "example.net"), in)
+	}
+}
+
+func TestCloneZone(t *testing.T) {
+	setup()
+	defer teardown()
+
+	const targetZoneID = "b58c24c2b6a2e0f6a3c9e5e8f7d6c5b4"
+
+	zoneHandler := func(id, name string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("content-type", "application/json")
+			fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": {"id": %q, "name": %q}}`, id, name)
+		}
+	}
+	mux.HandleFunc("/zones/"+testZoneID, zoneHandler(testZoneID, "example.com"))
+	mux.HandleFunc("/zones/"+targetZoneID, zoneHandler(targetZoneID, "example.net"))
+
+	mux.HandleFunc("/zones/"+testZoneID+"/settings", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [
+			{"id": "always_online", "value": "on", "editable": true},
+			{"id": "ssl", "value": "strict", "editable": true},
+			{"id": "advanced_ddos", "value": "on", "editable": false}
+		]}`)
+	})
+	var settingsUpdated bool
+	mux.HandleFunc("/zones/"+targetZoneID+"/settings", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		if r.Method == http.MethodPatch {
+			settingsUpdated = true
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"items": [{"id": "ssl", "value": "strict", "editable": false, "time_remaining": 0}]}`, string(body))
+			fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [{"id": "ssl", "value": "strict", "editable": true}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [
+			{"id": "always_online", "value": "on", "editable": true},
+			{"id": "ssl", "value": "full", "editable": true}
+		]}`)
+	})
+
+	mux.HandleFunc("/zones/"+testZoneID+"/dns_records", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [
+			{"id": "1", "type": "NS", "name": "example.com", "content": "ns1.cloudflare.com", "ttl": 1},
+			{"id": "2", "type": "A", "name": "example.com", "content": "198.51.100.4", "ttl": 1, "proxied": true},
+			{"id": "3", "type": "CNAME", "name": "www.example.com", "content": "example.com", "ttl": 1, "proxied": true},
+			{"id": "4", "type": "TXT", "name": "example.com", "content": "v=spf1 -all", "ttl": 3600}
+		], "result_info": {"page": 1, "total_pages": 1}}`)
+	})
+	var created, updated []string
+	mux.HandleFunc("/zones/"+targetZoneID+"/dns_records", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		if r.Method == http.MethodPost {
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			created = append(created, string(body))
+			fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": {"id": "new"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [
+			{"id": "a", "type": "A", "name": "example.net", "content": "198.51.100.4", "ttl": 1, "proxied": true},
+			{"id": "b", "type": "CNAME", "name": "www.example.net", "content": "elsewhere.example.org", "ttl": 1, "proxied": true}
+		], "result_info": {"page": 1, "total_pages": 1}}`)
+	})
+	mux.HandleFunc("/zones/"+targetZoneID+"/dns_records/b", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		if r.Method == http.MethodPatch {
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			updated = append(updated, string(body))
+		}
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": {"id": "b", "type": "CNAME", "name": "www.example.net"}}`)
+	})
+
+	report, err := client.CloneZone(context.Background(), testZoneID, targetZoneID, ZoneCloneOptions{
+		Components: []ZoneCloneComponent{ZoneCloneSettings, ZoneCloneDNSRecords},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "example.com", report.SourceZone)
+	assert.Equal(t, "example.net", report.TargetZone)
+	assert.Equal(t, []ZoneCloneAction{
+		{Component: ZoneCloneSettings, Operation: ZoneCloneUpdate, Resource: "ssl"},
+		{Component: ZoneCloneDNSRecords, Operation: ZoneCloneUpdate, Resource: "CNAME www.example.net"},
+		{Component: ZoneCloneDNSRecords, Operation: ZoneCloneCreate, Resource: "TXT example.net"},
+	}, report.Changes())
+	assert.Len(t, report.Actions, 5)
+
+	assert.True(t, settingsUpdated)
+	require.Len(t, created, 1)
+	assert.Contains(t, created[0], `"content":"v=spf1 -all"`)
+	require.Len(t, updated, 1)
+	assert.Contains(t, updated[0], `"content":"example.net"`)
+}
+
+func TestCloneZone_DryRun(t *testing.T) {
+	setup()
+	defer teardown()
+
+	const targetZoneID = "b58c24c2b6a2e0f6a3c9e5e8f7d6c5b4"
+
+	zoneHandler := func(id, name string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("content-type", "application/json")
+			fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": {"id": %q, "name": %q}}`, id, name)
+		}
+	}
+	mux.HandleFunc("/zones/"+testZoneID, zoneHandler(testZoneID, "example.com"))
+	mux.HandleFunc("/zones/"+targetZoneID, zoneHandler(targetZoneID, "example.net"))
+
+	argoHandler := func(value string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+			w.Header().Set("content-type", "application/json")
+			fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": {"id": "smart_routing", "value": %q, "editable": true}}`, value)
+		}
+	}
+	mux.HandleFunc("/zones/"+testZoneID+"/argo/smart_routing", argoHandler("on"))
+	mux.HandleFunc("/zones/"+targetZoneID+"/argo/smart_routing", argoHandler("off"))
+	mux.HandleFunc("/zones/"+testZoneID+"/argo/tiered_caching", argoHandler("on"))
+	mux.HandleFunc("/zones/"+targetZoneID+"/argo/tiered_caching", argoHandler("on"))
+
+	report, err := client.CloneZone(context.Background(), testZoneID, targetZoneID, ZoneCloneOptions{
+		Components: []ZoneCloneComponent{ZoneCloneArgo},
+		DryRun:     true,
+	})
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, []ZoneCloneAction{
+		{Component: ZoneCloneArgo, Operation: ZoneCloneUpdate, Resource: "smart_routing"},
+		{Component: ZoneCloneArgo, Operation: ZoneCloneUnchanged, Resource: "tiered_caching"},
+	}, report.Actions)
+}
+
+func TestCloneZone_ChangedRecordsAndRules(t *testing.T) {
+	setup()
+	defer teardown()
+
+	const targetZoneID = "b58c24c2b6a2e0f6a3c9e5e8f7d6c5b4"
+
+	zoneHandler := func(id, name string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("content-type", "application/json")
+			fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": {"id": %q, "name": %q}}`, id, name)
+		}
+	}
+	mux.HandleFunc("/zones/"+testZoneID, zoneHandler(testZoneID, "example.com"))
+	mux.HandleFunc("/zones/"+targetZoneID, zoneHandler(targetZoneID, "example.net"))
+
+	mux.HandleFunc("/zones/"+testZoneID+"/dns_records", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [
+			{"id": "1", "type": "A", "name": "example.com", "content": "198.51.100.4", "ttl": 1},
+			{"id": "2", "type": "A", "name": "example.com", "content": "198.51.100.5", "ttl": 1},
+			{"id": "3", "type": "A", "name": "example.com", "content": "198.51.100.6", "ttl": 1}
+		], "result_info": {"page": 1, "total_pages": 1}}`)
+	})
+	mux.HandleFunc("/zones/"+targetZoneID+"/dns_records", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [
+			{"id": "a", "type": "A", "name": "example.net", "content": "198.51.100.5", "ttl": 1},
+			{"id": "b", "type": "A", "name": "example.net", "content": "203.0.113.9", "ttl": 1}
+		], "result_info": {"page": 1, "total_pages": 1}}`)
+	})
+
+	mux.HandleFunc("/zones/"+testZoneID+"/firewall/rules", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [
+			{"id": "1", "action": "block", "filter": {"id": "f1", "expression": "http.host eq \"example.com\""}}
+		]}`)
+	})
+	mux.HandleFunc("/zones/"+targetZoneID+"/firewall/rules", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [
+			{"id": "a", "action": "challenge", "filter": {"id": "fa", "expression": "http.host eq \"example.net\""}}
+		]}`)
+	})
+
+	report, err := client.CloneZone(context.Background(), testZoneID, targetZoneID, ZoneCloneOptions{
+		Components: []ZoneCloneComponent{ZoneCloneDNSRecords, ZoneCloneFirewallRules},
+		DryRun:     true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []ZoneCloneAction{
+		{Component: ZoneCloneDNSRecords, Operation: ZoneCloneUpdate, Resource: "A example.net"},
+		{Component: ZoneCloneDNSRecords, Operation: ZoneCloneUnchanged, Resource: "A example.net"},
+		{Component: ZoneCloneDNSRecords, Operation: ZoneCloneCreate, Resource: "A example.net"},
+		{Component: ZoneCloneFirewallRules, Operation: ZoneCloneUpdate, Resource: `block http.host eq "example.net"`},
+	}, report.Actions)
+}
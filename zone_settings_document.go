@@ -0,0 +1,519 @@
+package cloudflare
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ZoneSSLMode is the value of the "ssl" zone setting.
+type ZoneSSLMode string
+
+// Available SSL modes.
+const (
+	ZoneSSLOff        ZoneSSLMode = "off"
+	ZoneSSLFlexible   ZoneSSLMode = "flexible"
+	ZoneSSLFull       ZoneSSLMode = "full"
+	ZoneSSLStrict     ZoneSSLMode = "strict"
+	ZoneSSLOriginPull ZoneSSLMode = "origin_pull"
+)
+
+// ZoneSecurityLevel is the value of the "security_level" zone setting.
+type ZoneSecurityLevel string
+
+// Available security levels.
+const (
+	ZoneSecurityEssentiallyOff ZoneSecurityLevel = "essentially_off"
+	ZoneSecurityLow            ZoneSecurityLevel = "low"
+	ZoneSecurityMedium         ZoneSecurityLevel = "medium"
+	ZoneSecurityHigh           ZoneSecurityLevel = "high"
+	ZoneSecurityUnderAttack    ZoneSecurityLevel = "under_attack"
+)
+
+// ZoneCacheLevel is the value of the "cache_level" zone setting.
+type ZoneCacheLevel string
+
+// Available cache levels.
+const (
+	ZoneCacheAggressive ZoneCacheLevel = "aggressive"
+	ZoneCacheBasic      ZoneCacheLevel = "basic"
+	ZoneCacheSimplified ZoneCacheLevel = "simplified"
+)
+
+// ZonePolish is the value of the "polish" zone setting.
+type ZonePolish string
+
+// Available Polish modes.
+const (
+	ZonePolishOff      ZonePolish = "off"
+	ZonePolishLossless ZonePolish = "lossless"
+	ZonePolishLossy    ZonePolish = "lossy"
+)
+
+// ZoneTLSVersion is the value of the "min_tls_version" zone setting.
+type ZoneTLSVersion string
+
+// Available minimum TLS versions.
+const (
+	ZoneTLS10 ZoneTLSVersion = "1.0"
+	ZoneTLS11 ZoneTLSVersion = "1.1"
+	ZoneTLS12 ZoneTLSVersion = "1.2"
+	ZoneTLS13 ZoneTLSVersion = "1.3"
+)
+
+var zoneSettingEnums = map[string][]string{
+	"ssl":             {"off", "flexible", "full", "strict", "origin_pull"},
+	"security_level":  {"essentially_off", "low", "medium", "high", "under_attack"},
+	"cache_level":     {"aggressive", "basic", "simplified"},
+	"polish":          {"off", "lossless", "lossy"},
+	"min_tls_version": {"1.0", "1.1", "1.2", "1.3"},
+	"tls_1_3":         {"on", "off", "zrt"},
+}
+
+// ZoneMinifySetting is the value of the "minify" zone setting.
+type ZoneMinifySetting struct {
+	CSS  bool `json:"css"`
+	HTML bool `json:"html"`
+	JS   bool `json:"js"`
+}
+
+// ZoneMobileRedirectSetting is the value of the "mobile_redirect" zone
+// setting. Keys without a field are kept in Unknown.
+type ZoneMobileRedirectSetting struct {
+	Enabled         bool                   `json:"enabled"`
+	MobileSubdomain string                 `json:"mobile_subdomain"`
+	StripURI        bool                   `json:"strip_uri"`
+	Unknown         map[string]interface{} `json:"unknown,omitempty"`
+}
+
+// ZoneSecurityHeaderSetting is the value of the "security_header" zone
+// setting. Keys without a field are kept in Unknown.
+type ZoneSecurityHeaderSetting struct {
+	StrictTransportSecurity ZoneStrictTransportSecurity `json:"strict_transport_security"`
+	Unknown                 map[string]interface{}      `json:"unknown,omitempty"`
+}
+
+// ZoneStrictTransportSecurity is the HSTS part of the "security_header"
+// zone setting. Keys without a field are kept in Unknown.
+type ZoneStrictTransportSecurity struct {
+	Enabled           bool                   `json:"enabled"`
+	MaxAge            time.Duration          `json:"max_age"`
+	IncludeSubdomains bool                   `json:"include_subdomains"`
+	Preload           bool                   `json:"preload"`
+	NoSniff           bool                   `json:"nosniff"`
+	Unknown           map[string]interface{} `json:"unknown,omitempty"`
+}
+
+// ZoneSettingsDocument is a typed view of all settings of a zone. A nil field
+// means the setting is not part of the document and is left untouched when
+// the document is applied.
+//
+// Settings the document has no field for, or whose value could not be
+// decoded into its field, are kept verbatim in Unknown.
+type ZoneSettingsDocument struct {
+	AlwaysOnline            *bool `json:"always_online,omitempty"`
+	AlwaysUseHTTPS          *bool `json:"always_use_https,omitempty"`
+	AutomaticHTTPSRewrites  *bool `json:"automatic_https_rewrites,omitempty"`
+	Brotli                  *bool `json:"brotli,omitempty"`
+	BrowserCheck            *bool `json:"browser_check,omitempty"`
+	DevelopmentMode         *bool `json:"development_mode,omitempty"`
+	EarlyHints              *bool `json:"early_hints,omitempty"`
+	EmailObfuscation        *bool `json:"email_obfuscation,omitempty"`
+	HotlinkProtection       *bool `json:"hotlink_protection,omitempty"`
+	HTTP2                   *bool `json:"http2,omitempty"`
+	HTTP3                   *bool `json:"http3,omitempty"`
+	IPGeolocation           *bool `json:"ip_geolocation,omitempty"`
+	IPv6                    *bool `json:"ipv6,omitempty"`
+	Mirage                  *bool `json:"mirage,omitempty"`
+	OpportunisticEncryption *bool `json:"opportunistic_encryption,omitempty"`
+	OpportunisticOnion      *bool `json:"opportunistic_onion,omitempty"`
+	OriginErrorPagePassThru *bool `json:"origin_error_page_pass_thru,omitempty"`
+	PrefetchPreload         *bool `json:"prefetch_preload,omitempty"`
+	PrivacyPass             *bool `json:"privacy_pass,omitempty"`
+	ResponseBuffering       *bool `json:"response_buffering,omitempty"`
+	RocketLoader            *bool `json:"rocket_loader,omitempty"`
+	ServerSideExclude       *bool `json:"server_side_exclude,omitempty"`
+	SortQueryStringForCache *bool `json:"sort_query_string_for_cache,omitempty"`
+	TLSClientAuth           *bool `json:"tls_client_auth,omitempty"`
+	TrueClientIPHeader      *bool `json:"true_client_ip_header,omitempty"`
+	WAF                     *bool `json:"waf,omitempty"`
+	WebP                    *bool `json:"webp,omitempty"`
+	WebSockets              *bool `json:"websockets,omitempty"`
+	ZeroRTT                 *bool `json:"0rtt,omitempty"`
+
+	SSL           *ZoneSSLMode       `json:"ssl,omitempty"`
+	SecurityLevel *ZoneSecurityLevel `json:"security_level,omitempty"`
+	CacheLevel    *ZoneCacheLevel    `json:"cache_level,omitempty"`
+	Polish        *ZonePolish        `json:"polish,omitempty"`
+	MinTLSVersion *ZoneTLSVersion    `json:"min_tls_version,omitempty"`
+	// TLS13 is "on", "off" or "zrt" (0-RTT).
+	TLS13 *string `json:"tls_1_3,omitempty"`
+
+	// BrowserCacheTTL of zero means the origin's cache headers are
+	// respected. The API works in whole seconds.
+	BrowserCacheTTL *time.Duration `json:"browser_cache_ttl,omitempty"`
+	ChallengeTTL    *time.Duration `json:"challenge_ttl,omitempty"`
+	EdgeCacheTTL    *time.Duration `json:"edge_cache_ttl,omitempty"`
+	// MaxUpload is the maximum request body size in megabytes.
+	MaxUpload *int `json:"max_upload,omitempty"`
+
+	Ciphers        *[]string                  `json:"ciphers,omitempty"`
+	Minify         *ZoneMinifySetting         `json:"minify,omitempty"`
+	MobileRedirect *ZoneMobileRedirectSetting `json:"mobile_redirect,omitempty"`
+	SecurityHeader *ZoneSecurityHeaderSetting `json:"security_header,omitempty"`
+
+	Unknown map[string]interface{} `json:"unknown,omitempty"`
+}
+
+// zoneSettingsOnOff maps the "on"/"off" settings to their document fields.
+var zoneSettingsOnOff = map[string]func(*ZoneSettingsDocument) **bool{
+	"always_online":               func(d *ZoneSettingsDocument) **bool { return &d.AlwaysOnline },
+	"always_use_https":            func(d *ZoneSettingsDocument) **bool { return &d.AlwaysUseHTTPS },
+	"automatic_https_rewrites":    func(d *ZoneSettingsDocument) **bool { return &d.AutomaticHTTPSRewrites },
+	"brotli":                      func(d *ZoneSettingsDocument) **bool { return &d.Brotli },
+	"browser_check":               func(d *ZoneSettingsDocument) **bool { return &d.BrowserCheck },
+	"development_mode":            func(d *ZoneSettingsDocument) **bool { return &d.DevelopmentMode },
+	"early_hints":                 func(d *ZoneSettingsDocument) **bool { return &d.EarlyHints },
+	"email_obfuscation":           func(d *ZoneSettingsDocument) **bool { return &d.EmailObfuscation },
+	"hotlink_protection":          func(d *ZoneSettingsDocument) **bool { return &d.HotlinkProtection },
+	"http2":                       func(d *ZoneSettingsDocument) **bool { return &d.HTTP2 },
+	"http3":                       func(d *ZoneSettingsDocument) **bool { return &d.HTTP3 },
+	"ip_geolocation":              func(d *ZoneSettingsDocument) **bool { return &d.IPGeolocation },
+	"ipv6":                        func(d *ZoneSettingsDocument) **bool { return &d.IPv6 },
+	"mirage":                      func(d *ZoneSettingsDocument) **bool { return &d.Mirage },
+	"opportunistic_encryption":    func(d *ZoneSettingsDocument) **bool { return &d.OpportunisticEncryption },
+	"opportunistic_onion":         func(d *ZoneSettingsDocument) **bool { return &d.OpportunisticOnion },
+	"origin_error_page_pass_thru": func(d *ZoneSettingsDocument) **bool { return &d.OriginErrorPagePassThru },
+	"prefetch_preload":            func(d *ZoneSettingsDocument) **bool { return &d.PrefetchPreload },
+	"privacy_pass":                func(d *ZoneSettingsDocument) **bool { return &d.PrivacyPass },
+	"response_buffering":          func(d *ZoneSettingsDocument) **bool { return &d.ResponseBuffering },
+	"rocket_loader":               func(d *ZoneSettingsDocument) **bool { return &d.RocketLoader },
+	"server_side_exclude":         func(d *ZoneSettingsDocument) **bool { return &d.ServerSideExclude },
+	"sort_query_string_for_cache": func(d *ZoneSettingsDocument) **bool { return &d.SortQueryStringForCache },
+	"tls_client_auth":             func(d *ZoneSettingsDocument) **bool { return &d.TLSClientAuth },
+	"true_client_ip_header":       func(d *ZoneSettingsDocument) **bool { return &d.TrueClientIPHeader },
+	"waf":                         func(d *ZoneSettingsDocument) **bool { return &d.WAF },
+	"webp":                        func(d *ZoneSettingsDocument) **bool { return &d.WebP },
+	"websockets":                  func(d *ZoneSettingsDocument) **bool { return &d.WebSockets },
+	"0rtt":                        func(d *ZoneSettingsDocument) **bool { return &d.ZeroRTT },
+}
+
+// zoneSettingsTTL maps the settings expressed in seconds to their document
+// fields.
+var zoneSettingsTTL = map[string]func(*ZoneSettingsDocument) **time.Duration{
+	"browser_cache_ttl": func(d *ZoneSettingsDocument) **time.Duration { return &d.BrowserCacheTTL },
+	"challenge_ttl":     func(d *ZoneSettingsDocument) **time.Duration { return &d.ChallengeTTL },
+	"edge_cache_ttl":    func(d *ZoneSettingsDocument) **time.Duration { return &d.EdgeCacheTTL },
+}
+
+// NewZoneSettingsDocument builds a typed document from the list returned by
+// ZoneSettings.
+func NewZoneSettingsDocument(settings []ZoneSetting) ZoneSettingsDocument {
+	var d ZoneSettingsDocument
+	for _, s := range settings {
+		if !d.set(s.ID, s.Value) {
+			if d.Unknown == nil {
+				d.Unknown = make(map[string]interface{})
+			}
+			d.Unknown[s.ID] = s.Value
+		}
+	}
+	return d
+}
+
+// set decodes value into the field for id and reports whether it could.
+func (d *ZoneSettingsDocument) set(id string, value interface{}) bool {
+	if field, ok := zoneSettingsOnOff[id]; ok {
+		b, ok := onOffToBool(value)
+		if ok {
+			*field(d) = &b
+		}
+		return ok
+	}
+	if field, ok := zoneSettingsTTL[id]; ok {
+		n, ok := value.(float64)
+		if ok {
+			ttl := time.Duration(n) * time.Second
+			*field(d) = &ttl
+		}
+		return ok
+	}
+
+	switch id {
+	case "ssl", "security_level", "cache_level", "polish", "min_tls_version", "tls_1_3":
+		s, ok := value.(string)
+		if !ok || !isZoneSettingEnumValue(id, s) {
+			return false
+		}
+		switch id {
+		case "ssl":
+			v := ZoneSSLMode(s)
+			d.SSL = &v
+		case "security_level":
+			v := ZoneSecurityLevel(s)
+			d.SecurityLevel = &v
+		case "cache_level":
+			v := ZoneCacheLevel(s)
+			d.CacheLevel = &v
+		case "polish":
+			v := ZonePolish(s)
+			d.Polish = &v
+		case "min_tls_version":
+			v := ZoneTLSVersion(s)
+			d.MinTLSVersion = &v
+		case "tls_1_3":
+			d.TLS13 = &s
+		}
+		return true
+
+	case "max_upload":
+		n, ok := value.(float64)
+		if ok {
+			v := int(n)
+			d.MaxUpload = &v
+		}
+		return ok
+
+	case "ciphers":
+		list, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		ciphers := make([]string, 0, len(list))
+		for _, c := range list {
+			s, ok := c.(string)
+			if !ok {
+				return false
+			}
+			ciphers = append(ciphers, s)
+		}
+		d.Ciphers = &ciphers
+		return true
+
+	case "minify":
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		css, okCSS := onOffToBool(m["css"])
+		html, okHTML := onOffToBool(m["html"])
+		js, okJS := onOffToBool(m["js"])
+		if !okCSS || !okHTML || !okJS {
+			return false
+		}
+		d.Minify = &ZoneMinifySetting{CSS: css, HTML: html, JS: js}
+		return true
+
+	case "mobile_redirect":
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		enabled, ok := onOffToBool(m["status"])
+		if !ok {
+			return false
+		}
+		subdomain, _ := m["mobile_subdomain"].(string)
+		strip, _ := m["strip_uri"].(bool)
+		d.MobileRedirect = &ZoneMobileRedirectSetting{
+			Enabled:         enabled,
+			MobileSubdomain: subdomain,
+			StripURI:        strip,
+			Unknown:         zoneSettingLeftovers(m, "status", "mobile_subdomain", "strip_uri"),
+		}
+		return true
+
+	case "security_header":
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		sts, ok := m["strict_transport_security"].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		maxAge, _ := sts["max_age"].(float64)
+		enabled, _ := sts["enabled"].(bool)
+		include, _ := sts["include_subdomains"].(bool)
+		preload, _ := sts["preload"].(bool)
+		nosniff, _ := sts["nosniff"].(bool)
+		d.SecurityHeader = &ZoneSecurityHeaderSetting{
+			StrictTransportSecurity: ZoneStrictTransportSecurity{
+				Enabled:           enabled,
+				MaxAge:            time.Duration(maxAge) * time.Second,
+				IncludeSubdomains: include,
+				Preload:           preload,
+				NoSniff:           nosniff,
+				Unknown:           zoneSettingLeftovers(sts, "enabled", "max_age", "include_subdomains", "preload", "nosniff"),
+			},
+			Unknown: zoneSettingLeftovers(m, "strict_transport_security"),
+		}
+		return true
+	}
+
+	return false
+}
+
+// ZoneSettings converts the document back into the list form accepted by
+// UpdateZoneSettings. Only settings present in the document are returned,
+// ordered by ID.
+func (d ZoneSettingsDocument) ZoneSettings() ([]ZoneSetting, error) {
+	values := make(map[string]interface{})
+
+	for id, v := range d.Unknown {
+		values[id] = v
+	}
+	for id, field := range zoneSettingsOnOff {
+		if b := *field(&d); b != nil {
+			values[id] = boolToOnOff(*b)
+		}
+	}
+	for id, field := range zoneSettingsTTL {
+		if ttl := *field(&d); ttl != nil {
+			values[id] = int(*ttl / time.Second)
+		}
+	}
+
+	if d.SSL != nil {
+		values["ssl"] = string(*d.SSL)
+	}
+	if d.SecurityLevel != nil {
+		values["security_level"] = string(*d.SecurityLevel)
+	}
+	if d.CacheLevel != nil {
+		values["cache_level"] = string(*d.CacheLevel)
+	}
+	if d.Polish != nil {
+		values["polish"] = string(*d.Polish)
+	}
+	if d.MinTLSVersion != nil {
+		values["min_tls_version"] = string(*d.MinTLSVersion)
+	}
+	if d.TLS13 != nil {
+		values["tls_1_3"] = *d.TLS13
+	}
+	for id := range zoneSettingEnums {
+		if s, ok := values[id].(string); ok && !isZoneSettingEnumValue(id, s) {
+			return nil, errors.Errorf("invalid value %q for zone setting %s", s, id)
+		}
+	}
+
+	if d.MaxUpload != nil {
+		values["max_upload"] = *d.MaxUpload
+	}
+	if d.Ciphers != nil {
+		values["ciphers"] = *d.Ciphers
+	}
+	if d.Minify != nil {
+		values["minify"] = map[string]interface{}{
+			"css":  boolToOnOff(d.Minify.CSS),
+			"html": boolToOnOff(d.Minify.HTML),
+			"js":   boolToOnOff(d.Minify.JS),
+		}
+	}
+	if d.MobileRedirect != nil {
+		m := zoneSettingLeftovers(d.MobileRedirect.Unknown)
+		m["status"] = boolToOnOff(d.MobileRedirect.Enabled)
+		m["mobile_subdomain"] = d.MobileRedirect.MobileSubdomain
+		m["strip_uri"] = d.MobileRedirect.StripURI
+		values["mobile_redirect"] = m
+	}
+	if d.SecurityHeader != nil {
+		hsts := d.SecurityHeader.StrictTransportSecurity
+		sts := zoneSettingLeftovers(hsts.Unknown)
+		sts["enabled"] = hsts.Enabled
+		sts["max_age"] = int(hsts.MaxAge / time.Second)
+		sts["include_subdomains"] = hsts.IncludeSubdomains
+		sts["preload"] = hsts.Preload
+		sts["nosniff"] = hsts.NoSniff
+		m := zoneSettingLeftovers(d.SecurityHeader.Unknown)
+		m["strict_transport_security"] = sts
+		values["security_header"] = m
+	}
+
+	ids := make([]string, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	settings := make([]ZoneSetting, 0, len(ids))
+	for _, id := range ids {
+		settings = append(settings, ZoneSetting{ID: id, Value: values[id]})
+	}
+	return settings, nil
+}
+
+// zoneSettingLeftovers returns a copy of m without the known keys. When
+// keys are given and nothing is left it returns nil, so a decoded setting
+// has no Unknown map.
+func zoneSettingLeftovers(m map[string]interface{}, known ...string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	for _, k := range known {
+		delete(out, k)
+	}
+	if len(out) == 0 && len(known) > 0 {
+		return nil
+	}
+	return out
+}
+
+func isZoneSettingEnumValue(id, value string) bool {
+	for _, v := range zoneSettingEnums[id] {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func onOffToBool(v interface{}) (bool, bool) {
+	switch v {
+	case "on":
+		return true, true
+	case "off":
+		return false, true
+	}
+	return false, false
+}
+
+func boolToOnOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// ZoneSettingsDocument returns all of the settings for a given zone as a
+// typed document.
+//
+// API reference: https://api.cloudflare.com/#zone-settings-get-all-zone-settings
+func (api *API) ZoneSettingsDocument(ctx context.Context, zoneID string) (ZoneSettingsDocument, error) {
+	res, err := api.ZoneSettings(ctx, zoneID)
+	if err != nil {
+		return ZoneSettingsDocument{}, err
+	}
+	return NewZoneSettingsDocument(res.Result), nil
+}
+
+// UpdateZoneSettingsDocument applies every setting present in doc to the
+// zone and returns the settings as stored by the API.
+//
+// API reference: https://api.cloudflare.com/#zone-settings-edit-zone-settings-info
+func (api *API) UpdateZoneSettingsDocument(ctx context.Context, zoneID string, doc ZoneSettingsDocument) (ZoneSettingsDocument, error) {
+	settings, err := doc.ZoneSettings()
+	if err != nil {
+		return ZoneSettingsDocument{}, err
+	}
+
+	res, err := api.UpdateZoneSettings(ctx, zoneID, settings)
+	if err != nil {
+		return ZoneSettingsDocument{}, err
+	}
+	return NewZoneSettingsDocument(res.Result), nil
+}
@@ -0,0 +1,157 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const zoneSettingsDocumentFixture = `[
+	{"id": "always_online", "value": "on", "editable": true},
+	{"id": "ssl", "value": "strict", "editable": true},
+	{"id": "security_level", "value": "under_attack", "editable": true},
+	{"id": "cache_level", "value": "aggressive", "editable": true},
+	{"id": "min_tls_version", "value": "1.2", "editable": true},
+	{"id": "browser_cache_ttl", "value": 14400, "editable": true},
+	{"id": "max_upload", "value": 100, "editable": true},
+	{"id": "minify", "value": {"css": "on", "html": "off", "js": "on"}, "editable": true},
+	{"id": "mobile_redirect", "value": {"status": "on", "mobile_subdomain": "m", "strip_uri": true, "redirect_tablets": false}, "editable": true},
+	{"id": "security_header", "value": {"strict_transport_security": {"enabled": true, "max_age": 86400, "include_subdomains": true, "preload": false, "nosniff": true, "report_uri": "/hsts"}, "x_frame_options": "deny"}, "editable": true},
+	{"id": "ciphers", "value": ["ECDHE-RSA-AES128-GCM-SHA256"], "editable": true},
+	{"id": "image_resizing", "value": "on", "editable": true},
+	{"id": "ipv6", "value": "unexpected", "editable": true}
+]`
+
+func TestNewZoneSettingsDocument(t *testing.T) {
+	var settings []ZoneSetting
+	require.NoError(t, json.Unmarshal([]byte(zoneSettingsDocumentFixture), &settings))
+
+	doc := NewZoneSettingsDocument(settings)
+
+	require.NotNil(t, doc.AlwaysOnline)
+	assert.True(t, *doc.AlwaysOnline)
+	assert.Equal(t, ZoneSSLStrict, *doc.SSL)
+	assert.Equal(t, ZoneSecurityUnderAttack, *doc.SecurityLevel)
+	assert.Equal(t, ZoneCacheAggressive, *doc.CacheLevel)
+	assert.Equal(t, ZoneTLS12, *doc.MinTLSVersion)
+	assert.Equal(t, 4*time.Hour, *doc.BrowserCacheTTL)
+	assert.Equal(t, 100, *doc.MaxUpload)
+	assert.Equal(t, &ZoneMinifySetting{CSS: true, JS: true}, doc.Minify)
+	assert.Equal(t, &ZoneMobileRedirectSetting{
+		Enabled:         true,
+		MobileSubdomain: "m",
+		StripURI:        true,
+		Unknown:         map[string]interface{}{"redirect_tablets": false},
+	}, doc.MobileRedirect)
+	assert.Equal(t, &ZoneSecurityHeaderSetting{
+		StrictTransportSecurity: ZoneStrictTransportSecurity{
+			Enabled:           true,
+			MaxAge:            24 * time.Hour,
+			IncludeSubdomains: true,
+			NoSniff:           true,
+			Unknown:           map[string]interface{}{"report_uri": "/hsts"},
+		},
+		Unknown: map[string]interface{}{"x_frame_options": "deny"},
+	}, doc.SecurityHeader)
+	assert.Equal(t, &[]string{"ECDHE-RSA-AES128-GCM-SHA256"}, doc.Ciphers)
+	assert.Nil(t, doc.IPv6)
+	assert.Nil(t, doc.Brotli)
+	assert.Equal(t, map[string]interface{}{"image_resizing": "on", "ipv6": "unexpected"}, doc.Unknown)
+}
+
+func TestZoneSettingsDocument_RoundTrip(t *testing.T) {
+	var settings []ZoneSetting
+	require.NoError(t, json.Unmarshal([]byte(zoneSettingsDocumentFixture), &settings))
+
+	out, err := NewZoneSettingsDocument(settings).ZoneSettings()
+	require.NoError(t, err)
+
+	// Compare through JSON so numeric types don't matter.
+	got, err := json.Marshal(out)
+	require.NoError(t, err)
+	var roundTripped []ZoneSetting
+	require.NoError(t, json.Unmarshal(got, &roundTripped))
+
+	values := make(map[string]interface{})
+	for _, s := range settings {
+		values[s.ID] = s.Value
+	}
+	assert.Len(t, roundTripped, len(settings))
+	for i, s := range roundTripped {
+		if i > 0 {
+			assert.Less(t, roundTripped[i-1].ID, s.ID)
+		}
+		assert.Equal(t, values[s.ID], s.Value, s.ID)
+	}
+}
+
+func TestZoneSettingsDocument_Durations(t *testing.T) {
+	ttl, respectOrigin := time.Hour, time.Duration(0)
+	out, err := ZoneSettingsDocument{
+		BrowserCacheTTL: &respectOrigin,
+		EdgeCacheTTL:    &ttl,
+		SecurityHeader:  &ZoneSecurityHeaderSetting{StrictTransportSecurity: ZoneStrictTransportSecurity{Enabled: true, MaxAge: 24 * time.Hour}},
+	}.ZoneSettings()
+	require.NoError(t, err)
+
+	got, err := json.Marshal(out)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"id": "browser_cache_ttl", "value": 0, "editable": false, "time_remaining": 0},
+		{"id": "edge_cache_ttl", "value": 3600, "editable": false, "time_remaining": 0},
+		{"id": "security_header", "value": {"strict_transport_security": {"enabled": true, "max_age": 86400, "include_subdomains": false, "preload": false, "nosniff": false}}, "editable": false, "time_remaining": 0}
+	]`, string(got))
+}
+
+func TestZoneSettingsDocument_InvalidEnum(t *testing.T) {
+	ssl := ZoneSSLMode("super-strict")
+	_, err := ZoneSettingsDocument{SSL: &ssl}.ZoneSettings()
+	assert.EqualError(t, err, `invalid value "super-strict" for zone setting ssl`)
+}
+
+func TestUpdateZoneSettingsDocument(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method, "Expected method 'PATCH', got %s", r.Method)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"items": [
+			{"id": "always_use_https", "value": "on", "editable": false, "time_remaining": 0},
+			{"id": "edge_cache_ttl", "value": 7200, "editable": false, "time_remaining": 0},
+			{"id": "security_level", "value": "high", "editable": false, "time_remaining": 0}
+		]}`, string(body))
+
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{
+			"success": true,
+			"errors": [],
+			"messages": [],
+			"result": [
+				{"id": "always_use_https", "value": "on", "editable": true},
+				{"id": "edge_cache_ttl", "value": 7200, "editable": true},
+				{"id": "security_level", "value": "high", "editable": true}
+			]
+		}`)
+	}
+	mux.HandleFunc("/zones/"+testZoneID+"/settings", handler)
+
+	on := true
+	ttl := 2 * time.Hour
+	level := ZoneSecurityHigh
+	doc, err := client.UpdateZoneSettingsDocument(context.Background(), testZoneID, ZoneSettingsDocument{
+		AlwaysUseHTTPS: &on,
+		EdgeCacheTTL:   &ttl,
+		SecurityLevel:  &level,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ZoneSettingsDocument{AlwaysUseHTTPS: &on, EdgeCacheTTL: &ttl, SecurityLevel: &level}, doc)
+}
@@ -0,0 +1,353 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ZoneSnapshotVersion is the version of the ZoneSnapshot document format.
+// It changes whenever a snapshot written by an older version could no
+// longer be compared reliably with a newer one.
+const ZoneSnapshotVersion = 1
+
+// ZoneSnapshot is a point in time copy of a zone's configuration.
+//
+// Timestamps and revision numbers the API attaches to individual resources
+// are cleared so that two snapshots of an unchanged zone are identical.
+type ZoneSnapshot struct {
+	Version  int       `json:"version"`
+	ZoneID   string    `json:"zone_id"`
+	ZoneName string    `json:"zone_name"`
+	TakenAt  time.Time `json:"taken_at"`
+	// Settings holds every zone setting except ssl, which is in SSLMode.
+	Settings       ZoneSettingsDocument `json:"settings"`
+	SSLMode        string               `json:"ssl_mode"`
+	DNSSEC         string               `json:"dnssec"`
+	PageRules      []PageRule           `json:"page_rules"`
+	Filters        []Filter             `json:"filters"`
+	FirewallRules  []FirewallRule       `json:"firewall_rules"`
+	RateLimits     []RateLimit          `json:"rate_limits"`
+	Rulesets       []Ruleset            `json:"rulesets"`
+	WorkerRoutes   []WorkerRoute        `json:"worker_routes"`
+	Lockdowns      []ZoneLockdown       `json:"lockdowns"`
+	UserAgentRules []UserAgentRule      `json:"user_agent_rules"`
+}
+
+// Resources compared by DiffZoneSnapshots.
+const (
+	ZoneSnapshotSetting       = "setting"
+	ZoneSnapshotSSLMode       = "ssl_mode"
+	ZoneSnapshotDNSSEC        = "dnssec"
+	ZoneSnapshotPageRule      = "page_rule"
+	ZoneSnapshotFilter        = "filter"
+	ZoneSnapshotFirewallRule  = "firewall_rule"
+	ZoneSnapshotRateLimit     = "rate_limit"
+	ZoneSnapshotRuleset       = "ruleset"
+	ZoneSnapshotWorkerRoute   = "worker_route"
+	ZoneSnapshotLockdown      = "lockdown"
+	ZoneSnapshotUserAgentRule = "user_agent_rule"
+)
+
+// Kinds of ZoneSnapshotChange.
+const (
+	ZoneSnapshotAdded    = "added"
+	ZoneSnapshotRemoved  = "removed"
+	ZoneSnapshotModified = "modified"
+)
+
+// ZoneSnapshotChange is a single difference between two snapshots. Before is
+// nil for added resources and After is nil for removed ones.
+type ZoneSnapshotChange struct {
+	Resource string      `json:"resource"`
+	ID       string      `json:"id"`
+	Change   string      `json:"change"`
+	Before   interface{} `json:"before,omitempty"`
+	After    interface{} `json:"after,omitempty"`
+}
+
+// ZoneSnapshot captures the current configuration of a zone.
+func (api *API) ZoneSnapshot(ctx context.Context, zoneID string) (*ZoneSnapshot, error) {
+	zone, err := api.ZoneDetails(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ZoneSnapshot{
+		Version:  ZoneSnapshotVersion,
+		ZoneID:   zone.ID,
+		ZoneName: zone.Name,
+		TakenAt:  time.Now().UTC(),
+	}
+
+	settings, err := api.ZoneSettings(ctx, zoneID)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch zone settings")
+	}
+	others := make([]ZoneSetting, 0, len(settings.Result))
+	for _, setting := range settings.Result {
+		if setting.ID == "ssl" {
+			s.SSLMode, _ = setting.Value.(string)
+			continue
+		}
+		others = append(others, setting)
+	}
+	s.Settings = NewZoneSettingsDocument(others)
+
+	dnssec, err := api.ZoneDNSSECSetting(ctx, zoneID)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch DNSSEC status")
+	}
+	s.DNSSEC = dnssec.Status
+
+	if s.PageRules, err = api.ListPageRules(ctx, zoneID); err != nil {
+		return nil, errors.Wrap(err, "could not fetch page rules")
+	}
+	for i := range s.PageRules {
+		s.PageRules[i].CreatedOn = time.Time{}
+		s.PageRules[i].ModifiedOn = time.Time{}
+	}
+
+	if s.Filters, err = api.allFilters(ctx, zoneID); err != nil {
+		return nil, errors.Wrap(err, "could not fetch filters")
+	}
+
+	if s.FirewallRules, err = api.allFirewallRules(ctx, zoneID); err != nil {
+		return nil, errors.Wrap(err, "could not fetch firewall rules")
+	}
+	for i := range s.FirewallRules {
+		s.FirewallRules[i].CreatedOn = time.Time{}
+		s.FirewallRules[i].ModifiedOn = time.Time{}
+	}
+
+	if s.RateLimits, err = api.ListAllRateLimits(ctx, zoneID); err != nil {
+		return nil, errors.Wrap(err, "could not fetch rate limits")
+	}
+
+	if s.Rulesets, err = api.zoneEntrypointRulesets(ctx, zoneID); err != nil {
+		return nil, errors.Wrap(err, "could not fetch rulesets")
+	}
+
+	routes, err := api.ListWorkerRoutes(ctx, zoneID)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch Workers routes")
+	}
+	s.WorkerRoutes = routes.Routes
+
+	if s.Lockdowns, err = api.allZoneLockdowns(ctx, zoneID); err != nil {
+		return nil, errors.Wrap(err, "could not fetch zone lockdowns")
+	}
+
+	if s.UserAgentRules, err = api.allUserAgentRules(ctx, zoneID); err != nil {
+		return nil, errors.Wrap(err, "could not fetch user agent rules")
+	}
+
+	return s, nil
+}
+
+// ZoneDrift compares a previously taken snapshot with the live configuration
+// of the same zone and returns what changed since.
+func (api *API) ZoneDrift(ctx context.Context, snapshot *ZoneSnapshot) ([]ZoneSnapshotChange, error) {
+	live, err := api.ZoneSnapshot(ctx, snapshot.ZoneID)
+	if err != nil {
+		return nil, err
+	}
+	return DiffZoneSnapshots(snapshot, live)
+}
+
+// DiffZoneSnapshots lists the differences between two snapshots, ordered by
+// resource and ID. Resources are matched by ID, rulesets by phase.
+func DiffZoneSnapshots(before, after *ZoneSnapshot) ([]ZoneSnapshotChange, error) {
+	if before.Version != ZoneSnapshotVersion || after.Version != ZoneSnapshotVersion {
+		return nil, errors.Errorf("unsupported zone snapshot version: %d and %d (expected %d)", before.Version, after.Version, ZoneSnapshotVersion)
+	}
+
+	beforeSettings, err := snapshotSettings(before)
+	if err != nil {
+		return nil, err
+	}
+	afterSettings, err := snapshotSettings(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []ZoneSnapshotChange
+	changes = append(changes, diffSnapshotResources(ZoneSnapshotSetting, beforeSettings, afterSettings)...)
+	changes = append(changes, diffSnapshotResources(ZoneSnapshotSSLMode, singletonSnapshotResource(before.SSLMode), singletonSnapshotResource(after.SSLMode))...)
+	changes = append(changes, diffSnapshotResources(ZoneSnapshotDNSSEC, singletonSnapshotResource(before.DNSSEC), singletonSnapshotResource(after.DNSSEC))...)
+
+	// Lists of resources, keyed by the named field.
+	lists := []struct {
+		resource      string
+		key           string
+		before, after interface{}
+	}{
+		{ZoneSnapshotPageRule, "ID", before.PageRules, after.PageRules},
+		{ZoneSnapshotFilter, "ID", before.Filters, after.Filters},
+		{ZoneSnapshotFirewallRule, "ID", before.FirewallRules, after.FirewallRules},
+		{ZoneSnapshotRateLimit, "ID", before.RateLimits, after.RateLimits},
+		{ZoneSnapshotRuleset, "Phase", before.Rulesets, after.Rulesets},
+		{ZoneSnapshotWorkerRoute, "ID", before.WorkerRoutes, after.WorkerRoutes},
+		{ZoneSnapshotLockdown, "ID", before.Lockdowns, after.Lockdowns},
+		{ZoneSnapshotUserAgentRule, "ID", before.UserAgentRules, after.UserAgentRules},
+	}
+	for _, l := range lists {
+		b := keyedSnapshotResources(l.before, l.key)
+		a := keyedSnapshotResources(l.after, l.key)
+		changes = append(changes, diffSnapshotResources(l.resource, b, a)...)
+	}
+
+	return changes, nil
+}
+
+// snapshotSettings returns the settings of a snapshot keyed by setting ID,
+// with the values the API uses.
+func snapshotSettings(s *ZoneSnapshot) (map[string]interface{}, error) {
+	settings, err := s.Settings.ZoneSettings()
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid settings in snapshot of %s", s.ZoneName)
+	}
+	m := make(map[string]interface{}, len(settings))
+	for _, setting := range settings {
+		m[setting.ID] = setting.Value
+	}
+	return m, nil
+}
+
+// keyedSnapshotResources returns the elements of list, a slice of structs,
+// keyed by the string field named key.
+func keyedSnapshotResources(list interface{}, key string) map[string]interface{} {
+	v := reflect.ValueOf(list)
+	m := make(map[string]interface{}, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		m[item.FieldByName(key).String()] = item.Interface()
+	}
+	return m
+}
+
+func singletonSnapshotResource(v string) map[string]interface{} {
+	if v == "" {
+		return nil
+	}
+	return map[string]interface{}{"": v}
+}
+
+func diffSnapshotResources(resource string, before, after map[string]interface{}) []ZoneSnapshotChange {
+	ids := make([]string, 0, len(before)+len(after))
+	for id := range before {
+		ids = append(ids, id)
+	}
+	for id := range after {
+		if _, ok := before[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var changes []ZoneSnapshotChange
+	for _, id := range ids {
+		b, inBefore := before[id]
+		a, inAfter := after[id]
+		switch {
+		case !inBefore:
+			changes = append(changes, ZoneSnapshotChange{Resource: resource, ID: id, Change: ZoneSnapshotAdded, After: a})
+		case !inAfter:
+			changes = append(changes, ZoneSnapshotChange{Resource: resource, ID: id, Change: ZoneSnapshotRemoved, Before: b})
+		case !jsonEqual(b, a):
+			changes = append(changes, ZoneSnapshotChange{Resource: resource, ID: id, Change: ZoneSnapshotModified, Before: b, After: a})
+		}
+	}
+	return changes
+}
+
+// zoneEntrypointRulesets returns the zone entry point rulesets, including
+// their rules, with revision details cleared.
+func (api *API) zoneEntrypointRulesets(ctx context.Context, zoneID string) ([]Ruleset, error) {
+	list, err := api.ListZoneRulesets(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rulesets []Ruleset
+	for _, r := range list {
+		if r.Kind != string(RulesetKindZone) {
+			continue
+		}
+		rs, err := api.GetZoneRuleset(ctx, zoneID, r.ID)
+		if err != nil {
+			return nil, err
+		}
+		rs.Version = ""
+		rs.LastUpdated = nil
+		for i := range rs.Rules {
+			rs.Rules[i].Version = ""
+			rs.Rules[i].LastUpdated = nil
+		}
+		rulesets = append(rulesets, rs)
+	}
+	return rulesets, nil
+}
+
+// allFilters pages through every filter of a zone.
+func (api *API) allFilters(ctx context.Context, zoneID string) ([]Filter, error) {
+	const perPage = 100
+	var filters []Filter
+	for page := 1; ; page++ {
+		res, err := api.Filters(ctx, zoneID, PaginationOptions{Page: page, PerPage: perPage})
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, res...)
+		if len(res) < perPage {
+			return filters, nil
+		}
+	}
+}
+
+// allZoneLockdowns pages through every zone lockdown rule of a zone.
+func (api *API) allZoneLockdowns(ctx context.Context, zoneID string) ([]ZoneLockdown, error) {
+	var lockdowns []ZoneLockdown
+	for page := 1; ; page++ {
+		res, err := api.ListZoneLockdowns(ctx, zoneID, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range res.Result {
+			l.CreatedOn = nil
+			l.ModifiedOn = nil
+			lockdowns = append(lockdowns, l)
+		}
+		if res.TotalPages <= page {
+			return lockdowns, nil
+		}
+	}
+}
+
+// allUserAgentRules pages through every user agent rule of a zone.
+func (api *API) allUserAgentRules(ctx context.Context, zoneID string) ([]UserAgentRule, error) {
+	var rules []UserAgentRule
+	for page := 1; ; page++ {
+		res, err := api.ListUserAgentRules(ctx, zoneID, page)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, res.Result...)
+		if res.TotalPages <= page {
+			return rules, nil
+		}
+	}
+}
+
+// String returns a one line summary of the change, such as
+// "modified setting always_online".
+func (c ZoneSnapshotChange) String() string {
+	if c.ID == "" {
+		return fmt.Sprintf("%s %s", c.Change, c.Resource)
+	}
+	return fmt.Sprintf("%s %s %s", c.Change, c.Resource, c.ID)
+}
@@ -0,0 +1,124 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZoneSnapshot(t *testing.T) {
+	setup()
+	defer teardown()
+
+	respond := func(result string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodGet, r.Method, "Expected method 'GET', got %s", r.Method)
+			w.Header().Set("content-type", "application/json")
+			fmt.Fprintf(w, `{"success": true, "errors": [], "messages": [], "result": %s, "result_info": {"page": 1, "total_pages": 1}}`, result)
+		}
+	}
+
+	prefix := "/zones/" + testZoneID
+	mux.HandleFunc(prefix, respond(`{"id": "`+testZoneID+`", "name": "example.com"}`))
+	mux.HandleFunc(prefix+"/settings", respond(`[
+		{"id": "ssl", "value": "full", "editable": true},
+		{"id": "always_online", "value": "on", "editable": true, "modified_on": "2014-01-01T05:20:00.12345Z"}
+	]`))
+	mux.HandleFunc(prefix+"/dnssec", respond(`{"status": "active"}`))
+	mux.HandleFunc(prefix+"/pagerules", respond(`[{"id": "pr1", "targets": [], "actions": [], "priority": 1, "status": "active", "modified_on": "2014-01-01T05:20:00Z"}]`))
+	mux.HandleFunc(prefix+"/filters", respond(`[{"id": "f1", "expression": "ip.src eq 192.0.2.1", "paused": false}]`))
+	mux.HandleFunc(prefix+"/firewall/rules", respond(`[{"id": "fr1", "action": "block", "filter": {"id": "f1"}, "created_on": "2014-01-01T05:20:00Z"}]`))
+	mux.HandleFunc(prefix+"/rate_limits", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"success": true, "errors": [], "messages": [], "result": [], "result_info": {"page": 1, "per_page": 100, "count": 0}}`)
+	})
+	mux.HandleFunc(prefix+"/rulesets", respond(`[
+		{"id": "rs1", "kind": "zone", "phase": "http_request_firewall_custom"},
+		{"id": "rs2", "kind": "managed", "phase": "http_request_firewall_managed"}
+	]`))
+	mux.HandleFunc(prefix+"/rulesets/rs1", respond(`{"id": "rs1", "kind": "zone", "phase": "http_request_firewall_custom", "version": "3", "last_updated": "2014-01-01T05:20:00Z", "rules": [{"id": "r1", "version": "2", "action": "block", "expression": "true"}]}`))
+	mux.HandleFunc(prefix+"/workers/routes", respond(`[{"id": "wr1", "pattern": "example.com/*", "script": "app"}]`))
+	mux.HandleFunc(prefix+"/firewall/lockdowns", respond(`[]`))
+	mux.HandleFunc(prefix+"/firewall/ua_rules", respond(`[]`))
+
+	client.AccountID = testAccountID
+	s, err := client.ZoneSnapshot(context.Background(), testZoneID)
+	require.NoError(t, err)
+
+	assert.Equal(t, ZoneSnapshotVersion, s.Version)
+	assert.Equal(t, "example.com", s.ZoneName)
+	assert.Equal(t, "full", s.SSLMode)
+	require.NotNil(t, s.Settings.AlwaysOnline)
+	assert.True(t, *s.Settings.AlwaysOnline)
+	assert.Nil(t, s.Settings.SSL)
+	assert.Equal(t, "active", s.DNSSEC)
+	require.Len(t, s.PageRules, 1)
+	assert.True(t, s.PageRules[0].ModifiedOn.IsZero())
+	require.Len(t, s.FirewallRules, 1)
+	assert.True(t, s.FirewallRules[0].CreatedOn.IsZero())
+	require.Len(t, s.Rulesets, 1)
+	assert.Equal(t, "", s.Rulesets[0].Version)
+	assert.Nil(t, s.Rulesets[0].LastUpdated)
+	assert.Equal(t, "", s.Rulesets[0].Rules[0].Version)
+	assert.Equal(t, []WorkerRoute{{ID: "wr1", Pattern: "example.com/*", Enabled: true, Script: "app"}}, s.WorkerRoutes)
+}
+
+func TestDiffZoneSnapshots(t *testing.T) {
+	on, off := true, false
+	before := &ZoneSnapshot{
+		Version:  ZoneSnapshotVersion,
+		TakenAt:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		Settings: ZoneSettingsDocument{AlwaysOnline: &on, Brotli: &on},
+		SSLMode:  "full",
+		DNSSEC:   "active",
+		FirewallRules: []FirewallRule{
+			{ID: "fr1", Action: "block", Filter: Filter{ID: "f1"}},
+			{ID: "fr2", Action: "allow", Filter: Filter{ID: "f2"}},
+		},
+		Rulesets:     []Ruleset{{ID: "rs1", Phase: "http_request_firewall_custom", Description: "old"}},
+		WorkerRoutes: []WorkerRoute{{ID: "wr1", Pattern: "example.com/*", Script: "app"}},
+	}
+	after := &ZoneSnapshot{
+		Version:  ZoneSnapshotVersion,
+		TakenAt:  time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+		Settings: ZoneSettingsDocument{AlwaysOnline: &off, Brotli: &on},
+		SSLMode:  "strict",
+		DNSSEC:   "active",
+		FirewallRules: []FirewallRule{
+			{ID: "fr1", Action: "block", Filter: Filter{ID: "f1"}},
+		},
+		Rulesets: []Ruleset{{ID: "rs1", Phase: "http_request_firewall_custom", Description: "new"}},
+		WorkerRoutes: []WorkerRoute{
+			{ID: "wr1", Pattern: "example.com/*", Script: "app"},
+			{ID: "wr2", Pattern: "example.com/api/*", Script: "api"},
+		},
+	}
+
+	changes, err := DiffZoneSnapshots(before, after)
+	require.NoError(t, err)
+	assert.Equal(t, []ZoneSnapshotChange{
+		{Resource: ZoneSnapshotSetting, ID: "always_online", Change: ZoneSnapshotModified, Before: "on", After: "off"},
+		{Resource: ZoneSnapshotSSLMode, Change: ZoneSnapshotModified, Before: "full", After: "strict"},
+		{Resource: ZoneSnapshotFirewallRule, ID: "fr2", Change: ZoneSnapshotRemoved, Before: FirewallRule{ID: "fr2", Action: "allow", Filter: Filter{ID: "f2"}}},
+		{
+			Resource: ZoneSnapshotRuleset, ID: "http_request_firewall_custom", Change: ZoneSnapshotModified,
+			Before: Ruleset{ID: "rs1", Phase: "http_request_firewall_custom", Description: "old"},
+			After:  Ruleset{ID: "rs1", Phase: "http_request_firewall_custom", Description: "new"},
+		},
+		{Resource: ZoneSnapshotWorkerRoute, ID: "wr2", Change: ZoneSnapshotAdded, After: WorkerRoute{ID: "wr2", Pattern: "example.com/api/*", Script: "api"}},
+	}, changes)
+	assert.Equal(t, "modified setting always_online", changes[0].String())
+	assert.Equal(t, "modified ssl_mode", changes[1].String())
+
+	unchanged, err := DiffZoneSnapshots(before, before)
+	require.NoError(t, err)
+	assert.Empty(t, unchanged)
+
+	_, err = DiffZoneSnapshots(before, &ZoneSnapshot{Version: 99})
+	assert.EqualError(t, err, "unsupported zone snapshot version: 1 and 99 (expected 1)")
+}